@@ -6,8 +6,10 @@ import (
 	"errors"
 	"image"
 	"image/color"
+	"strings"
 
 	"gioui.org/io/event"
+	"gioui.org/io/input"
 	"gioui.org/io/key"
 	"gioui.org/op"
 
@@ -187,10 +189,12 @@ type driver interface {
 	ShowTextInput(show bool)
 	SetInputHint(mode key.InputHint)
 	NewContext() (context, error)
-	// ReadClipboard requests the clipboard content.
-	ReadClipboard()
-	// WriteClipboard requests a clipboard write.
-	WriteClipboard(mime string, s []byte)
+	// ReadClipboard requests the clipboard content in the first of types
+	// that is available.
+	ReadClipboard(types []string)
+	// WriteClipboard requests a clipboard write of data in one or more
+	// formats, starting with the primary format.
+	WriteClipboard(data []input.ClipboardData)
 	// Configure the window.
 	Configure([]Option)
 	// SetCursor updates the current cursor to name.
@@ -207,6 +211,15 @@ type driver interface {
 	ProcessEvent(e event.Event)
 }
 
+// primaryDriver is implemented by drivers for platforms with a primary
+// selection.
+type primaryDriver interface {
+	// ReadPrimary is like ReadClipboard, for the primary selection.
+	ReadPrimary(types []string)
+	// WritePrimary is like WriteClipboard, for the primary selection.
+	WritePrimary(data []input.ClipboardData)
+}
+
 type windowRendezvous struct {
 	in      chan windowAndConfig
 	out     chan windowAndConfig
@@ -352,6 +365,23 @@ func (e *eventLoop) Wakeup() {
 	}
 }
 
+// isTextType reports whether mime is a MIME type of plain text.
+func isTextType(mime string) bool {
+	return mime == "application/text" || mime == "text/plain" || strings.HasPrefix(mime, "text/plain;")
+}
+
+// clipboardText returns the plain text format of clipboard data, or the
+// primary format if there is no plain text, for platforms that only
+// transfer text.
+func clipboardText(data []input.ClipboardData) []byte {
+	for _, d := range data {
+		if isTextType(d.Type) {
+			return d.Data
+		}
+	}
+	return data[0].Data
+}
+
 func walkActions(actions system.Action, do func(system.Action)) {
 	for a := system.Action(1); actions != 0; a <<= 1 {
 		if actions&a != 0 {
@@ -285,6 +285,8 @@ var android struct {
 		androidWidgetRadioButton C.jstring
 		// "android.widget.Switch"
 		androidWidgetSwitch C.jstring
+		// "android.widget.TabWidget"
+		androidWidgetTabWidget C.jstring
 	}
 }
 
@@ -445,6 +447,7 @@ func initJVM(env *C.JNIEnv, gio C.jclass, ctx C.jobject) {
 	android.strings.androidWidgetEditText = intern("android.widget.EditText")
 	android.strings.androidWidgetRadioButton = intern("android.widget.RadioButton")
 	android.strings.androidWidgetSwitch = intern("android.widget.Switch")
+	android.strings.androidWidgetTabWidget = intern("android.widget.TabWidget")
 }
 
 // JavaVM returns the global JNI JavaVM.
@@ -766,6 +769,10 @@ func (w *window) initAccessibilityNodeInfo(env *C.JNIEnv, sem input.SemanticNode
 	case semantic.Switch:
 		checkable = true
 		clsName = android.strings.androidWidgetSwitch
+	case semantic.Tab:
+		selectMethod = android.accessibilityNodeInfo.setSelected
+	case semantic.TabList:
+		clsName = android.strings.androidWidgetTabWidget
 	}
 	if err := callVoidMethod(env, info, android.accessibilityNodeInfo.setClassName, jvalue(clsName)); err != nil {
 		panic(err)
@@ -1336,7 +1343,8 @@ func newWindow(window *callbacks, options []Option) {
 	<-mainWindow.windows
 }
 
-func (w *window) WriteClipboard(mime string, s []byte) {
+func (w *window) WriteClipboard(data []input.ClipboardData) {
+	s := clipboardText(data)
 	runInJVM(javaVM(), func(env *C.JNIEnv) {
 		jstr := javaString(env, string(s))
 		callStaticVoidMethod(env, android.gioCls, android.mwriteClipboard,
@@ -1344,7 +1352,7 @@ func (w *window) WriteClipboard(mime string, s []byte) {
 	})
 }
 
-func (w *window) ReadClipboard() {
+func (w *window) ReadClipboard(types []string) {
 	runInJVM(javaVM(), func(env *C.JNIEnv) {
 		c, err := callStaticObjectMethod(env, android.gioCls, android.mreadClipboard,
 			jvalue(android.appCtx))
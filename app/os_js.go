@@ -18,6 +18,7 @@ import (
 
 	"gioui.org/f32"
 	"gioui.org/io/event"
+	"gioui.org/io/input"
 	"gioui.org/io/key"
 	"gioui.org/io/pointer"
 	"gioui.org/io/system"
@@ -531,7 +532,7 @@ func (w *window) SetAnimating(anim bool) {
 	}
 }
 
-func (w *window) ReadClipboard() {
+func (w *window) ReadClipboard(types []string) {
 	if w.clipboard.IsUndefined() {
 		return
 	}
@@ -541,7 +542,8 @@ func (w *window) ReadClipboard() {
 	w.clipboard.Call("readText", w.clipboard).Call("then", w.clipboardCallback)
 }
 
-func (w *window) WriteClipboard(mime string, s []byte) {
+func (w *window) WriteClipboard(data []input.ClipboardData) {
+	s := clipboardText(data)
 	if w.clipboard.IsUndefined() {
 		return
 	}
@@ -19,6 +19,7 @@ import (
 
 	"gioui.org/internal/f32"
 	"gioui.org/io/event"
+	"gioui.org/io/input"
 	"gioui.org/io/key"
 	"gioui.org/io/pointer"
 	"gioui.org/io/system"
@@ -400,7 +401,7 @@ func (w *window) contextView() C.CFTypeRef {
 	return w.view
 }
 
-func (w *window) ReadClipboard() {
+func (w *window) ReadClipboard(types []string) {
 	cstr := C.readClipboard()
 	if cstr != 0 {
 		defer C.CFRelease(cstr)
@@ -414,7 +415,8 @@ func (w *window) ReadClipboard() {
 	})
 }
 
-func (w *window) WriteClipboard(mime string, s []byte) {
+func (w *window) WriteClipboard(data []input.ClipboardData) {
+	s := clipboardText(data)
 	cstr := stringToNSString(string(s))
 	defer C.CFRelease(cstr)
 	C.writeClipboard(cstr)
@@ -8,6 +8,7 @@
 #include "wayland_xdg_shell.h"
 #include "wayland_xdg_decoration.h"
 #include "wayland_text_input.h"
+#include "wayland_primary_selection.h"
 #include "_cgo_export.h"
 
 const struct wl_registry_listener gio_registry_listener = {
@@ -122,3 +123,18 @@ const struct wl_data_source_listener gio_data_source_listener = {
 	.dnd_finished = gio_onDataSourceDNDFinished,
 	.action = gio_onDataSourceAction,
 };
+
+const struct zwp_primary_selection_device_v1_listener gio_primary_selection_device_listener = {
+	.data_offer = gio_onPrimarySelectionDeviceDataOffer,
+	.selection = gio_onPrimarySelectionDeviceSelection,
+};
+
+const struct zwp_primary_selection_offer_v1_listener gio_primary_selection_offer_listener = {
+	// Cast away const parameter.
+	.offer = (void (*)(void *, struct zwp_primary_selection_offer_v1 *, const char *))gio_onPrimarySelectionOfferOffer,
+};
+
+const struct zwp_primary_selection_source_v1_listener gio_primary_selection_source_listener = {
+	.send = (void (*)(void *, struct zwp_primary_selection_source_v1 *, const char *, int32_t))gio_onPrimarySelectionSourceSend,
+	.cancelled = gio_onPrimarySelectionSourceCancelled,
+};
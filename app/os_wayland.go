@@ -16,6 +16,7 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
@@ -27,6 +28,7 @@ import (
 	"gioui.org/f32"
 	"gioui.org/internal/fling"
 	"gioui.org/io/event"
+	"gioui.org/io/input"
 	"gioui.org/io/key"
 	"gioui.org/io/pointer"
 	"gioui.org/io/system"
@@ -45,9 +47,13 @@ import (
 //go:generate wayland-scanner client-header /usr/share/wayland-protocols/unstable/xdg-decoration/xdg-decoration-unstable-v1.xml wayland_xdg_decoration.h
 //go:generate wayland-scanner private-code /usr/share/wayland-protocols/unstable/xdg-decoration/xdg-decoration-unstable-v1.xml wayland_xdg_decoration.c
 
+//go:generate wayland-scanner client-header /usr/share/wayland-protocols/unstable/primary-selection/primary-selection-unstable-v1.xml wayland_primary_selection.h
+//go:generate wayland-scanner private-code /usr/share/wayland-protocols/unstable/primary-selection/primary-selection-unstable-v1.xml wayland_primary_selection.c
+
 //go:generate sed -i "1s;^;//go:build ((linux \\&\\& !android) || freebsd) \\&\\& !nowayland\\n// +build linux,!android freebsd\\n// +build !nowayland\\n\\n;" wayland_xdg_shell.c
 //go:generate sed -i "1s;^;//go:build ((linux \\&\\& !android) || freebsd) \\&\\& !nowayland\\n// +build linux,!android freebsd\\n// +build !nowayland\\n\\n;" wayland_xdg_decoration.c
 //go:generate sed -i "1s;^;//go:build ((linux \\&\\& !android) || freebsd) \\&\\& !nowayland\\n// +build linux,!android freebsd\\n// +build !nowayland\\n\\n;" wayland_text_input.c
+//go:generate sed -i "1s;^;//go:build ((linux \\&\\& !android) || freebsd) \\&\\& !nowayland\\n// +build linux,!android freebsd\\n// +build !nowayland\\n\\n;" wayland_primary_selection.c
 
 /*
 #cgo linux pkg-config: wayland-client wayland-cursor
@@ -59,6 +65,7 @@ import (
 #include <wayland-client.h>
 #include <wayland-cursor.h>
 #include "wayland_text_input.h"
+#include "wayland_primary_selection.h"
 #include "wayland_xdg_shell.h"
 #include "wayland_xdg_decoration.h"
 
@@ -78,6 +85,9 @@ extern const struct zwp_text_input_v3_listener gio_zwp_text_input_v3_listener;
 extern const struct wl_data_device_listener gio_data_device_listener;
 extern const struct wl_data_offer_listener gio_data_offer_listener;
 extern const struct wl_data_source_listener gio_data_source_listener;
+extern const struct zwp_primary_selection_device_v1_listener gio_primary_selection_device_listener;
+extern const struct zwp_primary_selection_offer_v1_listener gio_primary_selection_offer_listener;
+extern const struct zwp_primary_selection_source_v1_listener gio_primary_selection_source_listener;
 */
 import "C"
 
@@ -89,6 +99,7 @@ type wlDisplay struct {
 	imm               *C.struct_zwp_text_input_manager_v3
 	shm               *C.struct_wl_shm
 	dataDeviceManager *C.struct_wl_data_device_manager
+	primaryManager    *C.struct_zwp_primary_selection_device_manager_v1
 	decor             *C.struct_zxdg_decoration_manager_v1
 	seat              *wlSeat
 	xkb               *xkb.Context
@@ -103,6 +114,8 @@ type wlDisplay struct {
 	repeat        repeatState
 	poller        poller
 	readClipClose chan struct{}
+	// readPrimaryClose is like readClipClose, for the primary selection.
+	readPrimaryClose chan struct{}
 }
 
 type wlSeat struct {
@@ -130,13 +143,18 @@ type wlSeat struct {
 	offers map[*C.struct_wl_data_offer][]string
 	// clipboard is the wl_data_offer for the clipboard.
 	clipboard *C.struct_wl_data_offer
-	// mimeType is the chosen mime type of clipboard.
-	mimeType string
 	// source represents the clipboard content of the most recent
 	// clipboard write, if any.
 	source *C.struct_wl_data_source
-	// content is the data belonging to source.
-	content []byte
+	// content maps the mime types of source to their data.
+	content map[string][]byte
+
+	// Primary selection support, like clipboard support.
+	primaryDev     *C.struct_zwp_primary_selection_device_v1
+	primaryOffers  map[*C.struct_zwp_primary_selection_offer_v1][]string
+	primary        *C.struct_zwp_primary_selection_offer_v1
+	primarySource  *C.struct_zwp_primary_selection_source_v1
+	primaryContent map[string][]byte
 }
 
 type repeatState struct {
@@ -280,7 +298,7 @@ func newWLWindow(callbacks *callbacks, options []Option) error {
 	return nil
 }
 
-func (d *wlDisplay) writeClipboard(content []byte) error {
+func (d *wlDisplay) writeClipboard(data []input.ClipboardData) error {
 	s := d.seat
 	if s == nil {
 		return nil
@@ -294,35 +312,138 @@ func (d *wlDisplay) writeClipboard(content []byte) error {
 	if d.dataDeviceManager == nil || s.dataDev == nil {
 		return nil
 	}
-	s.content = content
+	var mimes []string
+	s.content, mimes = clipboardOffer(data)
 	s.source = C.wl_data_device_manager_create_data_source(d.dataDeviceManager)
 	C.wl_data_source_add_listener(s.source, &C.gio_data_source_listener, unsafe.Pointer(s.seat))
-	for _, mime := range clipboardMimeTypes {
-		C.wl_data_source_offer(s.source, C.CString(mime))
+	for _, mime := range mimes {
+		cmime := C.CString(mime)
+		C.wl_data_source_offer(s.source, cmime)
+		C.free(unsafe.Pointer(cmime))
 	}
 	C.wl_data_device_set_selection(s.dataDev, s.source, s.serial)
 	return nil
 }
 
-func (d *wlDisplay) readClipboard() (io.ReadCloser, error) {
+func (d *wlDisplay) writePrimary(data []input.ClipboardData) {
+	s := d.seat
+	if s == nil {
+		return
+	}
+	// Clear old offer.
+	if s.primarySource != nil {
+		C.zwp_primary_selection_source_v1_destroy(s.primarySource)
+		s.primarySource = nil
+		s.primaryContent = nil
+	}
+	if d.primaryManager == nil || s.primaryDev == nil {
+		return
+	}
+	var mimes []string
+	s.primaryContent, mimes = clipboardOffer(data)
+	s.primarySource = C.zwp_primary_selection_device_manager_v1_create_source(d.primaryManager)
+	C.zwp_primary_selection_source_v1_add_listener(s.primarySource, &C.gio_primary_selection_source_listener, unsafe.Pointer(s.seat))
+	for _, mime := range mimes {
+		cmime := C.CString(mime)
+		C.zwp_primary_selection_source_v1_offer(s.primarySource, cmime)
+		C.free(unsafe.Pointer(cmime))
+	}
+	C.zwp_primary_selection_device_v1_set_selection(s.primaryDev, s.primarySource, s.serial)
+}
+
+// clipboardOffer maps the mime types to offer for clipboard data to their
+// content, and returns the mime types in order of preference.
+func clipboardOffer(data []input.ClipboardData) (map[string][]byte, []string) {
+	content := make(map[string][]byte)
+	var mimes []string
+	for _, d := range data {
+		types := []string{d.Type}
+		if isTextType(d.Type) {
+			types = clipboardMimeTypes
+		}
+		for _, mime := range types {
+			if _, exists := content[mime]; !exists {
+				content[mime] = d.Data
+				mimes = append(mimes, mime)
+			}
+		}
+	}
+	return content, mimes
+}
+
+// readClipboard reads the first of the requested types offered by the
+// clipboard. It returns the type read and the types offered.
+func (d *wlDisplay) readClipboard(types []string) (io.ReadCloser, string, []string, error) {
 	s := d.seat
 	if s == nil {
-		return nil, nil
+		return nil, "", nil, nil
 	}
 	if s.clipboard == nil {
-		return nil, nil
+		return nil, "", nil, nil
+	}
+	return receiveOffer(s.offers[s.clipboard], types, func(mime *C.char, fd C.int) {
+		C.wl_data_offer_receive(s.clipboard, mime, fd)
+	})
+}
+
+// readPrimary is like readClipboard, for the primary selection.
+func (d *wlDisplay) readPrimary(types []string) (io.ReadCloser, string, []string, error) {
+	s := d.seat
+	if s == nil {
+		return nil, "", nil, nil
+	}
+	if s.primary == nil {
+		return nil, "", nil, nil
+	}
+	return receiveOffer(s.primaryOffers[s.primary], types, func(mime *C.char, fd C.int) {
+		C.zwp_primary_selection_offer_v1_receive(s.primary, mime, fd)
+	})
+}
+
+// receiveOffer receives the first of the requested types among the
+// offered mime types, through a pipe passed to receive. It returns the type
+// received and the types offered.
+func receiveOffer(offered, types []string, receive func(mime *C.char, fd C.int)) (io.ReadCloser, string, []string, error) {
+	var available []string
+	for _, mime := range offered {
+		if isTextType(mime) || slices.Contains(clipboardMimeTypes, mime) {
+			mime = "application/text"
+		}
+		if !slices.Contains(available, mime) {
+			available = append(available, mime)
+		}
+	}
+	var typ, mimeType string
+loop:
+	for _, want := range types {
+		if want != "application/text" {
+			if slices.Contains(offered, want) {
+				typ, mimeType = want, want
+				break
+			}
+			continue
+		}
+		for _, text := range clipboardMimeTypes {
+			if slices.Contains(offered, text) {
+				typ, mimeType = want, text
+				break loop
+			}
+		}
+	}
+	if mimeType == "" {
+		return nil, "", nil, nil
 	}
 	r, w, err := os.Pipe()
 	if err != nil {
-		return nil, err
+		return nil, "", nil, err
 	}
-	// wl_data_offer_receive performs and implicit dup(2) of the write end
+	// The receive request performs an implicit dup(2) of the write end
 	// of the pipe. Close our version.
 	defer w.Close()
-	cmimeType := C.CString(s.mimeType)
+	cmimeType := C.CString(mimeType)
 	defer C.free(unsafe.Pointer(cmimeType))
-	C.wl_data_offer_receive(s.clipboard, cmimeType, C.int(w.Fd()))
-	return r, nil
+	receive(cmimeType, C.int(w.Fd()))
+	return r, typ, available, nil
 }
 
 func (d *wlDisplay) createNativeWindow(options []Option) (*window, error) {
@@ -479,11 +600,27 @@ func (s *wlSeat) flushOffers() {
 	}
 }
 
+// flushPrimaryOffers is like flushOffers, for the primary selection.
+func (s *wlSeat) flushPrimaryOffers() {
+	for o := range s.primaryOffers {
+		if o == s.primary {
+			continue
+		}
+		delete(s.primaryOffers, o)
+		callbackDelete(unsafe.Pointer(o))
+		C.zwp_primary_selection_offer_v1_destroy(o)
+	}
+}
+
 func (s *wlSeat) destroy() {
 	if s.source != nil {
 		C.wl_data_source_destroy(s.source)
 		s.source = nil
 	}
+	if s.primarySource != nil {
+		C.zwp_primary_selection_source_v1_destroy(s.primarySource)
+		s.primarySource = nil
+	}
 	if s.im != nil {
 		C.zwp_text_input_v3_destroy(s.im)
 		s.im = nil
@@ -502,6 +639,12 @@ func (s *wlSeat) destroy() {
 	if s.dataDev != nil {
 		C.wl_data_device_release(s.dataDev)
 	}
+	s.primary = nil
+	s.flushPrimaryOffers()
+	if s.primaryDev != nil {
+		callbackDelete(unsafe.Pointer(s.primaryDev))
+		C.zwp_primary_selection_device_v1_destroy(s.primaryDev)
+	}
 	if s.seat != nil {
 		callbackDelete(unsafe.Pointer(s.seat))
 		C.wl_seat_release(s.seat)
@@ -686,10 +829,13 @@ func gio_onRegistryGlobal(data unsafe.Pointer, reg *C.struct_wl_registry, name C
 			seat:      s,
 			offers:    make(map[*C.struct_wl_data_offer][]string),
 			touchFoci: make(map[C.int32_t]*window),
+
+			primaryOffers: make(map[*C.struct_zwp_primary_selection_offer_v1][]string),
 		}
 		callbackStore(unsafe.Pointer(s), d.seat)
 		C.wl_seat_add_listener(s, &C.gio_seat_listener, unsafe.Pointer(s))
 		d.bindDataDevice()
+		d.bindPrimaryDevice()
 	case "wl_shm":
 		d.shm = (*C.struct_wl_shm)(C.wl_registry_bind(reg, name, &C.wl_shm_interface, 1))
 	case "xdg_wm_base":
@@ -702,6 +848,9 @@ func gio_onRegistryGlobal(data unsafe.Pointer, reg *C.struct_wl_registry, name C
 	case "wl_data_device_manager":
 		d.dataDeviceManager = (*C.struct_wl_data_device_manager)(C.wl_registry_bind(reg, name, &C.wl_data_device_manager_interface, 3))
 		d.bindDataDevice()
+	case "zwp_primary_selection_device_manager_v1":
+		d.primaryManager = (*C.struct_zwp_primary_selection_device_manager_v1)(C.wl_registry_bind(reg, name, &C.zwp_primary_selection_device_manager_v1_interface, 1))
+		d.bindPrimaryDevice()
 	}
 }
 
@@ -751,16 +900,32 @@ func gio_onDataDeviceSelection(data unsafe.Pointer, dataDev *C.struct_wl_data_de
 	s := callbackLoad(data).(*wlSeat)
 	defer s.flushOffers()
 	s.clipboard = nil
-loop:
-	for _, want := range clipboardMimeTypes {
-		for _, got := range s.offers[id] {
-			if want != got {
-				continue
-			}
-			s.clipboard = id
-			s.mimeType = got
-			break loop
-		}
+	if id != nil && len(s.offers[id]) > 0 {
+		s.clipboard = id
+	}
+}
+
+//export gio_onPrimarySelectionOfferOffer
+func gio_onPrimarySelectionOfferOffer(data unsafe.Pointer, offer *C.struct_zwp_primary_selection_offer_v1, mime *C.char) {
+	s := callbackLoad(data).(*wlSeat)
+	s.primaryOffers[offer] = append(s.primaryOffers[offer], C.GoString(mime))
+}
+
+//export gio_onPrimarySelectionDeviceDataOffer
+func gio_onPrimarySelectionDeviceDataOffer(data unsafe.Pointer, dev *C.struct_zwp_primary_selection_device_v1, id *C.struct_zwp_primary_selection_offer_v1) {
+	s := callbackLoad(data).(*wlSeat)
+	callbackStore(unsafe.Pointer(id), s)
+	C.zwp_primary_selection_offer_v1_add_listener(id, &C.gio_primary_selection_offer_listener, unsafe.Pointer(id))
+	s.primaryOffers[id] = nil
+}
+
+//export gio_onPrimarySelectionDeviceSelection
+func gio_onPrimarySelectionDeviceSelection(data unsafe.Pointer, dev *C.struct_zwp_primary_selection_device_v1, id *C.struct_zwp_primary_selection_offer_v1) {
+	s := callbackLoad(data).(*wlSeat)
+	defer s.flushPrimaryOffers()
+	s.primary = nil
+	if id != nil && len(s.primaryOffers[id]) > 0 {
+		s.primary = id
 	}
 }
 
@@ -1018,37 +1183,56 @@ func gio_onPointerAxisDiscrete(data unsafe.Pointer, p *C.struct_wl_pointer, axis
 	}
 }
 
-func (w *window) ReadClipboard() {
+func (w *window) ReadClipboard(types []string) {
 	if w.disp.readClipClose != nil {
 		return
 	}
+	r, typ, available, err := w.disp.readClipboard(types)
+	if r == nil || err != nil {
+		return
+	}
 	w.disp.readClipClose = make(chan struct{})
-	r, err := w.disp.readClipboard()
+	w.readOffer(r, transfer.DataEvent{Type: typ, Types: available}, w.disp.readClipClose)
+}
+
+func (w *window) WriteClipboard(data []input.ClipboardData) {
+	w.disp.writeClipboard(data)
+}
+
+func (w *window) ReadPrimary(types []string) {
+	if w.disp.readPrimaryClose != nil {
+		return
+	}
+	r, typ, available, err := w.disp.readPrimary(types)
 	if r == nil || err != nil {
 		return
 	}
+	w.disp.readPrimaryClose = make(chan struct{})
+	w.readOffer(r, transfer.DataEvent{Type: typ, Types: available, Primary: true}, w.disp.readPrimaryClose)
+}
+
+func (w *window) WritePrimary(data []input.ClipboardData) {
+	w.disp.writePrimary(data)
+}
+
+// readOffer reads the content of a clipboard offer from r and delivers
+// it in e, unless closed is closed first.
+func (w *window) readOffer(r io.ReadCloser, e transfer.DataEvent, closed <-chan struct{}) {
 	// Don't let slow clipboard transfers block event loop.
 	go func() {
 		defer r.Close()
 		data, _ := io.ReadAll(r)
-		e := transfer.DataEvent{
-			Type: "application/text",
-			Open: func() io.ReadCloser {
-				return io.NopCloser(bytes.NewReader(data))
-			},
+		e.Open = func() io.ReadCloser {
+			return io.NopCloser(bytes.NewReader(data))
 		}
 		select {
 		case w.clipReads <- e:
 			w.disp.wakeup()
-		case <-w.disp.readClipClose:
+		case <-closed:
 		}
 	}()
 }
 
-func (w *window) WriteClipboard(mime string, s []byte) {
-	w.disp.writeClipboard(s)
-}
-
 func (w *window) Configure(options []Option) {
 	_, cfg := w.getConfig()
 	prev := w.config
@@ -1387,7 +1571,11 @@ func (w *window) dispatch() {
 	}
 	select {
 	case e := <-w.clipReads:
-		w.disp.readClipClose = nil
+		if e.Primary {
+			w.disp.readPrimaryClose = nil
+		} else {
+			w.disp.readClipClose = nil
+		}
 		w.ProcessEvent(e)
 	case <-w.wakeups:
 		w.w.Invalidate()
@@ -1440,6 +1628,19 @@ func (d *wlDisplay) bindDataDevice() {
 	}
 }
 
+// bindPrimaryDevice initializes the primaryDev field if and only if both
+// the seat and primaryManager fields are initialized.
+func (d *wlDisplay) bindPrimaryDevice() {
+	if d.seat != nil && d.primaryManager != nil {
+		d.seat.primaryDev = C.zwp_primary_selection_device_manager_v1_get_device(d.primaryManager, d.seat.seat)
+		if d.seat.primaryDev == nil {
+			return
+		}
+		callbackStore(unsafe.Pointer(d.seat.primaryDev), d.seat)
+		C.zwp_primary_selection_device_v1_add_listener(d.seat.primaryDev, &C.gio_primary_selection_device_listener, unsafe.Pointer(d.seat.primaryDev))
+	}
+}
+
 func (d *wlDisplay) dispatch() error {
 	// wl_display_prepare_read records the current thread for
 	// use in wl_display_read_events or wl_display_cancel_events.
@@ -1590,13 +1791,33 @@ func gio_onDataSourceTarget(data unsafe.Pointer, source *C.struct_wl_data_source
 //export gio_onDataSourceSend
 func gio_onDataSourceSend(data unsafe.Pointer, source *C.struct_wl_data_source, mime *C.char, fd C.int32_t) {
 	s := callbackLoad(data).(*wlSeat)
-	content := s.content
+	content := s.content[C.GoString(mime)]
 	go func() {
 		defer syscall.Close(int(fd))
 		syscall.Write(int(fd), content)
 	}()
 }
 
+//export gio_onPrimarySelectionSourceSend
+func gio_onPrimarySelectionSourceSend(data unsafe.Pointer, source *C.struct_zwp_primary_selection_source_v1, mime *C.char, fd C.int32_t) {
+	s := callbackLoad(data).(*wlSeat)
+	content := s.primaryContent[C.GoString(mime)]
+	go func() {
+		defer syscall.Close(int(fd))
+		syscall.Write(int(fd), content)
+	}()
+}
+
+//export gio_onPrimarySelectionSourceCancelled
+func gio_onPrimarySelectionSourceCancelled(data unsafe.Pointer, source *C.struct_zwp_primary_selection_source_v1) {
+	s := callbackLoad(data).(*wlSeat)
+	if s.primarySource == source {
+		s.primaryContent = nil
+		s.primarySource = nil
+	}
+	C.zwp_primary_selection_source_v1_destroy(source)
+}
+
 //export gio_onDataSourceCancelled
 func gio_onDataSourceCancelled(data unsafe.Pointer, source *C.struct_wl_data_source) {
 	s := callbackLoad(data).(*wlSeat)
@@ -1884,6 +2105,10 @@ func (d *wlDisplay) destroy() {
 		close(d.readClipClose)
 		d.readClipClose = nil
 	}
+	if d.readPrimaryClose != nil {
+		close(d.readPrimaryClose)
+		d.readPrimaryClose = nil
+	}
 	if d.notify.write != 0 {
 		syscall.Close(d.notify.write)
 		d.notify.write = 0
@@ -1907,6 +2132,9 @@ func (d *wlDisplay) destroy() {
 	if d.decor != nil {
 		C.zxdg_decoration_manager_v1_destroy(d.decor)
 	}
+	if d.primaryManager != nil {
+		C.zwp_primary_selection_device_manager_v1_destroy(d.primaryManager)
+	}
 	if d.shm != nil {
 		C.wl_shm_destroy(d.shm)
 	}
@@ -5,6 +5,7 @@ package app
 import (
 	"errors"
 	"fmt"
+	"gioui.org/io/input"
 	"gioui.org/io/transfer"
 	syscall "golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/registry"
@@ -686,7 +687,7 @@ func (w *window) NewContext() (context, error) {
 	return nil, errors.New("NewContext: no available GPU drivers")
 }
 
-func (w *window) ReadClipboard() {
+func (w *window) ReadClipboard(types []string) {
 	w.readClipboard()
 }
 
@@ -788,7 +789,8 @@ func (w *window) Configure(options []Option) {
 	windows.ShowWindow(w.hwnd, showMode)
 }
 
-func (w *window) WriteClipboard(mime string, s []byte) {
+func (w *window) WriteClipboard(data []input.ClipboardData) {
+	s := clipboardText(data)
 	w.writeClipboard(string(s))
 }
 
@@ -28,10 +28,12 @@ package app
 import "C"
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"image"
 	"io"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -40,6 +42,7 @@ import (
 
 	"gioui.org/f32"
 	"gioui.org/io/event"
+	"gioui.org/io/input"
 	"gioui.org/io/key"
 	"gioui.org/io/pointer"
 	"gioui.org/io/system"
@@ -77,6 +80,10 @@ type x11Window struct {
 		primary C.Atom
 		// "CLIPBOARD_CONTENT", the clipboard destination property.
 		clipboardContent C.Atom
+		// "PRIMARY_CONTENT", the primary selection destination property.
+		primaryContent C.Atom
+		// "INCR", the type of incremental transfers.
+		incr C.Atom
 		// "WM_DELETE_WINDOW"
 		evDelWindow C.Atom
 		// "ATOM"
@@ -105,11 +112,10 @@ type x11Window struct {
 
 	pointerBtns pointer.Buttons
 
-	clipboard struct {
-		content []byte
-	}
-	cursor pointer.Cursor
-	config Config
+	clipboard x11Selection
+	primary   x11Selection
+	cursor    pointer.Cursor
+	config    Config
 
 	wakeups chan struct{}
 	handler x11EventHandler
@@ -147,19 +153,190 @@ func (w *x11Window) NewContext() (context, error) {
 	return nil, errors.New("x11: no available GPU backends")
 }
 
+// x11Selection is the state of the CLIPBOARD or PRIMARY selection.
+type x11Selection struct {
+	// selection is the selection atom, and property the property that
+	// receives its content.
+	selection, property C.Atom
+	// content maps the selection targets of the content to their data,
+	// and targets lists them in order of preference.
+	content map[C.Atom][]byte
+	targets []C.Atom
+	// types are the MIME types requested by the pending read, and
+	// available the MIME types of the content being read.
+	types     []string
+	available []string
+	// incr is the content received so far by an incremental transfer of
+	// the incrTarget target, and reading whether a transfer is ongoing.
+	incr       []byte
+	incrTarget C.Atom
+	reading    bool
+}
+
 func (w *x11Window) SetAnimating(anim bool) {
 	w.animating = anim
 }
 
-func (w *x11Window) ReadClipboard() {
-	C.XDeleteProperty(w.x, w.xw, w.atoms.clipboardContent)
-	C.XConvertSelection(w.x, w.atoms.clipboard, w.atoms.utf8string, w.atoms.clipboardContent, w.xw, C.CurrentTime)
+func (w *x11Window) ReadClipboard(types []string) {
+	w.readSelection(&w.clipboard, types)
+}
+
+func (w *x11Window) WriteClipboard(data []input.ClipboardData) {
+	w.writeSelection(&w.clipboard, data)
+}
+
+func (w *x11Window) ReadPrimary(types []string) {
+	w.readSelection(&w.primary, types)
+}
+
+func (w *x11Window) WritePrimary(data []input.ClipboardData) {
+	w.writeSelection(&w.primary, data)
+}
+
+func (w *x11Window) readSelection(s *x11Selection, types []string) {
+	s.types = types
+	s.available = nil
+	s.reading = false
+	s.incr = nil
+	// Request the targets of the content, to choose among types.
+	C.XDeleteProperty(w.x, w.xw, s.property)
+	C.XConvertSelection(w.x, s.selection, w.atoms.targets, s.property, w.xw, C.CurrentTime)
+}
+
+func (w *x11Window) writeSelection(s *x11Selection, data []input.ClipboardData) {
+	s.content = make(map[C.Atom][]byte)
+	s.targets = s.targets[:0]
+	for _, d := range data {
+		for _, t := range w.clipboardTargets(d.Type) {
+			if _, exists := s.content[t]; !exists {
+				s.content[t] = d.Data
+				s.targets = append(s.targets, t)
+			}
+		}
+	}
+	C.XSetSelectionOwner(w.x, s.selection, w.xw, C.CurrentTime)
+}
+
+// selection returns the state of a selection atom, or nil if the
+// selection is not supported.
+func (w *x11Window) selection(atom C.Atom) *x11Selection {
+	switch atom {
+	case w.atoms.clipboard:
+		return &w.clipboard
+	case w.atoms.primary:
+		return &w.primary
+	}
+	return nil
 }
 
-func (w *x11Window) WriteClipboard(mime string, s []byte) {
-	w.clipboard.content = s
-	C.XSetSelectionOwner(w.x, w.atoms.clipboard, w.xw, C.CurrentTime)
-	C.XSetSelectionOwner(w.x, w.atoms.primary, w.xw, C.CurrentTime)
+// clipboardTargets returns the selection targets for clipboard content of
+// a MIME type.
+func (w *x11Window) clipboardTargets(mime string) []C.Atom {
+	if isTextType(mime) {
+		// GTK clients need GTK_TEXT_BUFFER_CONTENTS.
+		return []C.Atom{w.atoms.utf8string, w.atoms.plaintext, w.atoms.gtk_text_buffer_contents}
+	}
+	return []C.Atom{w.atom(mime, false)}
+}
+
+// clipboardType returns the MIME type of a selection target, or the empty
+// string if the target is not a MIME type.
+func (w *x11Window) clipboardType(target C.Atom) string {
+	switch target {
+	case w.atoms.utf8string, w.atoms.plaintext, w.atoms.gtk_text_buffer_contents:
+		return "application/text"
+	}
+	cname := C.XGetAtomName(w.x, target)
+	if cname == nil {
+		return ""
+	}
+	defer C.XFree(unsafe.Pointer(cname))
+	mime := C.GoString(cname)
+	switch {
+	case !strings.Contains(mime, "/"):
+		return ""
+	case isTextType(mime):
+		// Only UTF-8 text is supported.
+		return ""
+	}
+	return mime
+}
+
+// clipboardTarget returns the target of the first requested MIME type
+// among the targets of the content of a selection.
+func (w *x11Window) clipboardTarget(s *x11Selection, targets []C.Atom) (C.Atom, bool) {
+	types := make([]string, len(targets))
+	for i, t := range targets {
+		types[i] = w.clipboardType(t)
+		if m := types[i]; m != "" && !slices.Contains(s.available, m) {
+			s.available = append(s.available, m)
+		}
+	}
+	for _, want := range s.types {
+		if want == "application/text" && slices.Contains(targets, w.atoms.utf8string) {
+			return w.atoms.utf8string, true
+		}
+		if i := slices.Index(types, want); i != -1 {
+			return targets[i], true
+		}
+	}
+	return 0, false
+}
+
+// readProperty returns the value and type of a property of the window, and
+// whether the value is complete. Values in the 32-bit format are arrays of
+// C.long.
+func (w *x11Window) readProperty(prop C.Atom) ([]byte, C.Atom, bool) {
+	var (
+		typ           C.Atom
+		format        C.int
+		nitems, after C.ulong
+		value         *C.uchar
+	)
+	// Read up to 1<<30 32-bit units, which covers any property that
+	// fits in memory.
+	st := C.XGetWindowProperty(w.x, w.xw, prop, 0, 1<<30, C.False, C.AnyPropertyType,
+		&typ, &format, &nitems, &after, &value)
+	if st != C.Success || value == nil {
+		return nil, typ, false
+	}
+	defer C.XFree(unsafe.Pointer(value))
+	size := int(nitems)
+	switch format {
+	case 16:
+		size *= int(unsafe.Sizeof(C.short(0)))
+	case 32:
+		size *= int(unsafe.Sizeof(C.long(0)))
+	}
+	return C.GoBytes(unsafe.Pointer(value), C.int(size)), typ, after == 0
+}
+
+// selectionProperty returns the state of the selection that receives its
+// content in a property, or nil if there is none.
+func (w *x11Window) selectionProperty(prop C.Atom) *x11Selection {
+	switch prop {
+	case w.clipboard.property:
+		return &w.clipboard
+	case w.primary.property:
+		return &w.primary
+	}
+	return nil
+}
+
+// deliverSelection processes the content of a selection in a target.
+func (w *x11Window) deliverSelection(s *x11Selection, target C.Atom, data []byte) {
+	mime := w.clipboardType(target)
+	if mime == "" {
+		return
+	}
+	w.ProcessEvent(transfer.DataEvent{
+		Type:    mime,
+		Types:   s.available,
+		Primary: s == &w.primary,
+		Open: func() io.ReadCloser {
+			return io.NopCloser(bytes.NewReader(data))
+		},
+	})
 }
 
 func (w *x11Window) Configure(options []Option) {
@@ -668,32 +845,70 @@ func (h *x11EventHandler) handleEvents() bool {
 			// redraw will be done by a later expose event
 		case C.SelectionNotify:
 			cevt := (*C.XSelectionEvent)(unsafe.Pointer(xev))
-			prop := w.atoms.clipboardContent
+			s := w.selection(cevt.selection)
+			if s == nil {
+				break
+			}
+			prop := s.property
+			if cevt.target == w.atoms.targets {
+				// Fall back to text for owners that don't report their
+				// targets.
+				target := w.atoms.utf8string
+				if cevt.property == prop {
+					data, _, _ := w.readProperty(prop)
+					targets := unsafe.Slice((*C.Atom)(unsafe.Pointer(unsafe.SliceData(data))), len(data)/int(unsafe.Sizeof(C.Atom(0))))
+					t, ok := w.clipboardTarget(s, targets)
+					if !ok {
+						break
+					}
+					target = t
+				}
+				C.XDeleteProperty(w.x, w.xw, prop)
+				C.XConvertSelection(w.x, s.selection, target, prop, w.xw, C.CurrentTime)
+				break
+			}
 			if cevt.property != prop {
 				break
 			}
-			if cevt.selection != w.atoms.clipboard {
+			data, typ, ok := w.readProperty(prop)
+			if typ == w.atoms.incr {
+				// The owner sends large content in chunks, each written
+				// to the property after the previous one is deleted. An
+				// empty chunk ends the transfer.
+				s.reading = true
+				s.incr = s.incr[:0]
+				s.incrTarget = cevt.target
+				C.XDeleteProperty(w.x, w.xw, prop)
 				break
 			}
-			var text C.XTextProperty
-			if st := C.XGetTextProperty(w.x, w.xw, &text, prop); st == 0 {
-				// Failed; ignore.
+			if !ok {
 				break
 			}
-			if text.format != 8 || text.encoding != w.atoms.utf8string {
-				// Ignore non-utf-8 encoded strings.
+			w.deliverSelection(s, cevt.target, data)
+		case C.PropertyNotify:
+			pevt := (*C.XPropertyEvent)(unsafe.Pointer(xev))
+			s := w.selectionProperty(pevt.atom)
+			if s == nil || !s.reading || pevt.state != C.PropertyNewValue {
 				break
 			}
-			str := C.GoStringN((*C.char)(unsafe.Pointer(text.value)), C.int(text.nitems))
-			w.ProcessEvent(transfer.DataEvent{
-				Type: "application/text",
-				Open: func() io.ReadCloser {
-					return io.NopCloser(strings.NewReader(str))
-				},
-			})
+			data, _, ok := w.readProperty(s.property)
+			C.XDeleteProperty(w.x, w.xw, s.property)
+			if !ok {
+				s.reading = false
+				s.incr = nil
+				break
+			}
+			if len(data) > 0 {
+				s.incr = append(s.incr, data...)
+				break
+			}
+			data, s.incr = s.incr, nil
+			s.reading = false
+			w.deliverSelection(s, s.incrTarget, data)
 		case C.SelectionRequest:
 			cevt := (*C.XSelectionRequestEvent)(unsafe.Pointer(xev))
-			if (cevt.selection != w.atoms.clipboard && cevt.selection != w.atoms.primary) || cevt.property == C.None {
+			s := w.selection(cevt.selection)
+			if s == nil || cevt.property == C.None {
 				// Unsupported clipboard or obsolete requestor.
 				break
 			}
@@ -711,25 +926,21 @@ func (h *x11EventHandler) handleEvents() bool {
 				}
 				C.XSendEvent(w.x, cevt.requestor, 0, 0, &xev)
 			}
-			switch cevt.target {
-			case w.atoms.targets:
+			switch content, ok := s.content[cevt.target]; {
+			case cevt.target == w.atoms.targets:
 				// The requestor wants the supported clipboard
 				// formats. First write the targets...
-				formats := [...]C.long{
-					C.long(w.atoms.targets),
-					C.long(w.atoms.utf8string),
-					C.long(w.atoms.plaintext),
-					// GTK clients need this.
-					C.long(w.atoms.gtk_text_buffer_contents),
+				formats := []C.long{C.long(w.atoms.targets)}
+				for _, t := range s.targets {
+					formats = append(formats, C.long(t))
 				}
 				C.XChangeProperty(w.x, cevt.requestor, cevt.property, w.atoms.atom,
 					32 /* bitwidth of formats */, C.PropModeReplace,
-					(*C.uchar)(unsafe.Pointer(&formats)), C.int(len(formats)),
+					(*C.uchar)(unsafe.Pointer(&formats[0])), C.int(len(formats)),
 				)
 				// ...then notify the requestor.
 				notify()
-			case w.atoms.plaintext, w.atoms.utf8string, w.atoms.gtk_text_buffer_contents:
-				content := w.clipboard.content
+			case ok:
 				var ptr *C.uchar
 				if len(content) > 0 {
 					ptr = (*C.uchar)(unsafe.Pointer(&content[0]))
@@ -807,7 +1018,8 @@ func newX11Window(gioWin *callbacks, options []Option) error {
 			C.KeyPressMask | C.KeyReleaseMask | // keyboard
 			C.ButtonPressMask | C.ButtonReleaseMask | // mouse clicks
 			C.PointerMotionMask | // mouse movement
-			C.StructureNotifyMask, // resize
+			C.StructureNotifyMask | // resize
+			C.PropertyChangeMask, // incremental selection transfers
 		background_pixmap: C.None,
 		override_redirect: C.False,
 	}
@@ -851,6 +1063,8 @@ func newX11Window(gioWin *callbacks, options []Option) error {
 	w.atoms.clipboard = w.atom("CLIPBOARD", false)
 	w.atoms.primary = w.atom("PRIMARY", false)
 	w.atoms.clipboardContent = w.atom("CLIPBOARD_CONTENT", false)
+	w.atoms.primaryContent = w.atom("PRIMARY_CONTENT", false)
+	w.atoms.incr = w.atom("INCR", false)
 	w.atoms.atom = w.atom("ATOM", false)
 	w.atoms.targets = w.atom("TARGETS", false)
 	w.atoms.wmName = w.atom("_NET_WM_NAME", false)
@@ -859,6 +1073,8 @@ func newX11Window(gioWin *callbacks, options []Option) error {
 	w.atoms.wmActiveWindow = w.atom("_NET_ACTIVE_WINDOW", false)
 	w.atoms.wmStateMaximizedHorz = w.atom("_NET_WM_STATE_MAXIMIZED_HORZ", false)
 	w.atoms.wmStateMaximizedVert = w.atom("_NET_WM_STATE_MAXIMIZED_VERT", false)
+	w.clipboard.selection, w.clipboard.property = w.atoms.clipboard, w.atoms.clipboardContent
+	w.primary.selection, w.primary.property = w.atoms.primary, w.atoms.primaryContent
 
 	// extensions
 	C.XSetWMProtocols(dpy, win, &w.atoms.evDelWindow, 1)
@@ -253,11 +253,19 @@ func (w *Window) updateState() {
 	if hint, ok := q.TextInputHint(); ok {
 		w.driver.SetInputHint(hint)
 	}
-	if mime, txt, ok := q.WriteClipboard(); ok {
-		w.driver.WriteClipboard(mime, txt)
+	if data, ok := q.WriteClipboardData(); ok {
+		w.driver.WriteClipboard(data)
 	}
 	if q.ClipboardRequested() {
-		w.driver.ReadClipboard()
+		w.driver.ReadClipboard(q.ClipboardTypes())
+	}
+	if p, ok := w.driver.(primaryDriver); ok {
+		if data, ok := q.WritePrimary(); ok {
+			p.WritePrimary(data)
+		}
+		if q.PrimaryRequested() {
+			p.ReadPrimary(q.PrimaryTypes())
+		}
 	}
 	oldState := w.imeState
 	newState := oldState
@@ -394,6 +402,8 @@ func (c *callbacks) SetDriver(d driver) {
 	c.w.invMu.Lock()
 	defer c.w.invMu.Unlock()
 	c.w.driver = d
+	_, primary := d.(primaryDriver)
+	c.w.queue.SetPrimarySupported(primary)
 }
 
 func (c *callbacks) ProcessFrame(frame *op.Ops, ack chan<- struct{}) {
@@ -16,16 +16,28 @@ import (
 	_ "image/png"
 
 	giofont "gioui.org/font"
+	"gioui.org/internal/colr"
 	fontapi "github.com/go-text/typesetting/font"
 	"github.com/go-text/typesetting/font/opentype"
 )
 
+func init() {
+	colr.FaceGlyphs = func(f giofont.Face) *colr.Font {
+		if f, ok := f.(Face); ok {
+			return f.colr
+		}
+		return nil
+	}
+}
+
 // Face is a thread-safe representation of a loaded font. For efficiency, applications
 // should construct a face for any given font file once, reusing it across different
 // text shapers.
 type Face struct {
 	face *fontapi.Font
 	font giofont.Font
+	// colr holds the color glyphs of the font, if any.
+	colr *colr.Font
 }
 
 // Parse constructs a Face from source bytes.
@@ -34,13 +46,14 @@ func Parse(src []byte) (Face, error) {
 	if err != nil {
 		return Face{}, err
 	}
-	font, md, err := parseLoader(ld)
+	font, md, c, err := parseLoader(ld)
 	if err != nil {
 		return Face{}, fmt.Errorf("failed parsing truetype font: %w", err)
 	}
 	return Face{
 		face: font,
 		font: md,
+		colr: c,
 	}, nil
 }
 
@@ -57,13 +70,14 @@ func ParseCollection(src []byte) ([]giofont.FontFace, error) {
 	}
 	out := make([]giofont.FontFace, len(lds))
 	for i, ld := range lds {
-		face, md, err := parseLoader(ld)
+		face, md, c, err := parseLoader(ld)
 		if err != nil {
 			return nil, fmt.Errorf("reading font %d of collection: %s", i, err)
 		}
 		ff := Face{
 			face: face,
 			font: md,
+			colr: c,
 		}
 		out[i] = giofont.FontFace{
 			Face: ff,
@@ -92,14 +106,20 @@ func FontToDescription(font giofont.Font) fontapi.Description {
 	}
 }
 
-// parseLoader parses the contents of the loader into a face and its font.
-func parseLoader(ld *opentype.Loader) (*fontapi.Font, giofont.Font, error) {
+// parseLoader parses the contents of the loader into a face, its font and
+// its color glyphs.
+func parseLoader(ld *opentype.Loader) (*fontapi.Font, giofont.Font, *colr.Font, error) {
 	ft, err := fontapi.NewFont(ld)
 	if err != nil {
-		return nil, giofont.Font{}, err
+		return nil, giofont.Font{}, nil, err
+	}
+	// Malformed color tables leave the glyphs in their outline form.
+	c, err := colr.Load(ld)
+	if err != nil {
+		c = nil
 	}
 	data := DescriptionToFont(ft.Describe())
-	return ft, data, nil
+	return ft, data, c, nil
 }
 
 // Face returns a thread-unsafe wrapper for this Face suitable for use by a single shaper.
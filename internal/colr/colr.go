@@ -0,0 +1,476 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+// Package colr reads the color glyphs of the COLR and CPAL font tables.
+//
+// Color glyphs of COLR version 0 are stacks of outline glyphs filled with
+// palette colors. Version 1 describes color glyphs by graphs of paints,
+// which are flattened into layers. Linear gradients keep only their outer
+// color stops, radial and sweep gradients are reduced to a solid color,
+// composite modes other than clear, source and destination are drawn as
+// source over destination, and font variations are ignored.
+//
+// Layers painted with the text color are part of the glyph outline rather
+// than painted separately, so they are clipped by their innermost outline
+// only and the alpha of their paint is ignored.
+package colr
+
+import (
+	"errors"
+	"image/color"
+	"math"
+
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/font/opentype"
+
+	"gioui.org/f32"
+	giofont "gioui.org/font"
+)
+
+// Font holds the color glyphs of a font.
+type Font struct {
+	// palette is the first palette of the font.
+	palette []color.NRGBA
+	// baseGlyphs and layers are the version 0 records.
+	baseGlyphs, layers []byte
+	// baseList and layerList are the version 1 paint lists, if any.
+	baseList, layerList []byte
+}
+
+// Layer is a layer of a color glyph.
+type Layer struct {
+	// Clips are the outlines whose intersection bounds the layer.
+	Clips []Clip
+	// Paint fills the layer.
+	Paint Paint
+}
+
+// Clip is an outline glyph, transformed in font units.
+type Clip struct {
+	Glyph     font.GID
+	Transform f32.Affine2D
+}
+
+// Paint describes the fill of a layer.
+type Paint struct {
+	// Foreground is set for a fill with the text color. The alpha of
+	// Color applies to the text color.
+	Foreground bool
+	// Color is the fill color, or the first color of a gradient.
+	Color color.NRGBA
+	// Gradient is set for a linear gradient from Color at Stop1 to
+	// Color2 at Stop2.
+	Gradient     bool
+	Stop1, Stop2 f32.Point
+	Color2       color.NRGBA
+	// Transform maps the gradient stops to font units.
+	Transform f32.Affine2D
+}
+
+// foregroundIndex is the palette index of the text color.
+const foregroundIndex = 0xffff
+
+// maxDepth limits the nesting of paints, which may be cyclic in invalid
+// fonts.
+const maxDepth = 64
+
+var errInvalid = errors.New("colr: invalid table")
+
+// FaceGlyphs returns the color glyphs of f, or nil. Package opentype sets
+// it to reach the color glyphs of its faces without exporting them.
+var FaceGlyphs = func(f giofont.Face) *Font { return nil }
+
+// Load reads the color glyphs of the font of ld. It returns nil if the font
+// has no color glyphs.
+func Load(ld *opentype.Loader) (*Font, error) {
+	colrTable, err := ld.RawTable(opentype.MustNewTag("COLR"))
+	if err != nil {
+		return nil, nil
+	}
+	cpalTable, err := ld.RawTable(opentype.MustNewTag("CPAL"))
+	if err != nil {
+		cpalTable = nil
+	}
+	return Parse(colrTable, cpalTable)
+}
+
+// Parse reads the COLR and CPAL tables of a font.
+func Parse(colr, cpal []byte) (*Font, error) {
+	if len(colr) < 14 {
+		return nil, errInvalid
+	}
+	f := new(Font)
+	version := u16(colr, 0)
+	var ok bool
+	f.baseGlyphs, ok = sub(colr, u32(colr, 4), int(u16(colr, 2))*6)
+	if !ok {
+		return nil, errInvalid
+	}
+	f.layers, ok = sub(colr, u32(colr, 8), int(u16(colr, 12))*4)
+	if !ok {
+		return nil, errInvalid
+	}
+	if version >= 1 {
+		if len(colr) < 22 {
+			return nil, errInvalid
+		}
+		if off := u32(colr, 14); off != 0 {
+			if _, ok := sub(colr, off, 4); !ok {
+				return nil, errInvalid
+			}
+			f.baseList = colr[off:]
+		}
+		if off := u32(colr, 18); off != 0 {
+			if _, ok := sub(colr, off, 4); !ok {
+				return nil, errInvalid
+			}
+			f.layerList = colr[off:]
+		}
+	}
+	if len(cpal) >= 14 {
+		entries := int(u16(cpal, 2))
+		records := u32(cpal, 8)
+		first := uint32(u16(cpal, 12))
+		colors, ok := sub(cpal, records+first*4, entries*4)
+		if !ok {
+			return nil, errInvalid
+		}
+		f.palette = make([]color.NRGBA, entries)
+		for i := range f.palette {
+			c := colors[i*4:]
+			f.palette[i] = color.NRGBA{B: c[0], G: c[1], R: c[2], A: c[3]}
+		}
+	}
+	return f, nil
+}
+
+// Layers appends the layers of the color glyph gid to layers, from bottom
+// to top. It appends nothing if gid is not a color glyph.
+func (f *Font) Layers(gid font.GID, layers []Layer) []Layer {
+	if off, ok := f.basePaint(gid); ok {
+		w := walker{f: f, layers: layers}
+		w.paint(f.baseList, off, nil, f32.Affine2D{}, 0)
+		return w.layers
+	}
+	rec, ok := search(f.baseGlyphs, 6, gid)
+	if !ok {
+		return layers
+	}
+	first, n := int(u16(rec, 2)), int(u16(rec, 4))
+	for i := first; i < first+n && (i+1)*4 <= len(f.layers); i++ {
+		l := f.layers[i*4:]
+		layers = append(layers, Layer{
+			Clips: []Clip{{Glyph: font.GID(u16(l, 0))}},
+			Paint: f.solid(u16(l, 2), 1),
+		})
+	}
+	return layers
+}
+
+// basePaint returns the offset of the version 1 paint of gid in baseList.
+func (f *Font) basePaint(gid font.GID) (uint32, bool) {
+	if len(f.baseList) < 4 {
+		return 0, false
+	}
+	n := int(u32(f.baseList, 0))
+	recs, ok := sub(f.baseList, 4, n*6)
+	if !ok {
+		return 0, false
+	}
+	rec, ok := search(recs, 6, gid)
+	if !ok {
+		return 0, false
+	}
+	return u32(rec, 2), true
+}
+
+// solid returns the paint of a palette color with the given alpha.
+func (f *Font) solid(idx uint16, alpha float32) Paint {
+	c := f.color(idx, alpha)
+	return Paint{Foreground: idx == foregroundIndex, Color: c}
+}
+
+// color returns the palette color idx with its alpha multiplied by alpha.
+// The text color is represented by black.
+func (f *Font) color(idx uint16, alpha float32) color.NRGBA {
+	c := color.NRGBA{A: 0xff}
+	if int(idx) < len(f.palette) {
+		c = f.palette[idx]
+	}
+	alpha = max(0, min(1, alpha))
+	c.A = uint8(float32(c.A)*alpha + .5)
+	return c
+}
+
+// walker flattens a graph of version 1 paints into layers.
+type walker struct {
+	f      *Font
+	layers []Layer
+}
+
+// paint flattens the paint at offset off of table t, clipped by clips and
+// transformed by tr.
+func (w *walker) paint(t []byte, off uint32, clips []Clip, tr f32.Affine2D, depth int) {
+	if _, ok := sub(t, off, 1); !ok || depth > maxDepth {
+		return
+	}
+	p := t[off:]
+	depth++
+	// child returns the offset of the paint at the 24-bit offset at i.
+	child := func(i int) uint32 {
+		return off + u24(p, i)
+	}
+	// transform flattens the child paint with the transform m.
+	transform := func(m f32.Affine2D) {
+		w.paint(t, child(1), clips, tr.Mul(m), depth)
+	}
+	// around returns m applied around the center at i.
+	around := func(m f32.Affine2D, i int) f32.Affine2D {
+		c := f32.Pt(float32(i16(p, i)), float32(i16(p, i+2)))
+		return f32.Affine2D{}.Offset(c).Mul(m).Mul(f32.Affine2D{}.Offset(c.Mul(-1)))
+	}
+	switch format := p[0]; format {
+	case 1: // PaintColrLayers.
+		if len(p) < 6 || len(w.f.layerList) < 4 {
+			return
+		}
+		first, n := u32(p, 2), uint32(p[1])
+		for i := first; i < first+n && i < u32(w.f.layerList, 0); i++ {
+			if o, ok := sub(w.f.layerList, 4+i*4, 4); ok {
+				w.paint(w.f.layerList, u32(o, 0), clips, tr, depth)
+			}
+		}
+	case 2, 3: // PaintSolid.
+		if len(p) < 5 {
+			return
+		}
+		w.fill(clips, w.f.solid(u16(p, 1), f2dot14(p, 3)), tr)
+	case 4, 5: // PaintLinearGradient.
+		if len(p) < 16 {
+			return
+		}
+		first, last, ok := w.colorLine(t, child(1), format == 5)
+		if !ok {
+			return
+		}
+		p0 := f32.Pt(float32(i16(p, 4)), float32(i16(p, 6)))
+		p1 := f32.Pt(float32(i16(p, 8)), float32(i16(p, 10)))
+		p2 := f32.Pt(float32(i16(p, 12)), float32(i16(p, 14)))
+		// The gradient runs along the normal of p0p2 by the projection
+		// of p0p1.
+		d, n := p1.Sub(p0), p2.Sub(p0)
+		n = f32.Pt(-n.Y, n.X)
+		if nn := n.X*n.X + n.Y*n.Y; nn != 0 {
+			d = n.Mul((d.X*n.X + d.Y*n.Y) / nn)
+		}
+		paint := Paint{
+			Color:    first.color,
+			Color2:   last.color,
+			Gradient: first.offset != last.offset,
+			Stop1:    p0.Add(d.Mul(first.offset)),
+			Stop2:    p0.Add(d.Mul(last.offset)),
+		}
+		w.fill(clips, paint, tr)
+	case 6, 7, 8, 9: // PaintRadialGradient and PaintSweepGradient.
+		if len(p) < 4 {
+			return
+		}
+		first, last, ok := w.colorLine(t, child(1), format == 7 || format == 9)
+		if !ok {
+			return
+		}
+		w.fill(clips, Paint{Color: mix(first.color, last.color)}, tr)
+	case 10: // PaintGlyph.
+		if len(p) < 6 {
+			return
+		}
+		clips = append(clips[:len(clips):len(clips)], Clip{Glyph: font.GID(u16(p, 4)), Transform: tr})
+		w.paint(t, child(1), clips, tr, depth)
+	case 11: // PaintColrGlyph.
+		if len(p) < 3 {
+			return
+		}
+		if o, ok := w.f.basePaint(font.GID(u16(p, 1))); ok {
+			w.paint(w.f.baseList, o, clips, tr, depth)
+		}
+	case 12, 13: // PaintTransform.
+		if len(p) < 7 {
+			return
+		}
+		m, ok := sub(t, child(4), 24)
+		if !ok {
+			return
+		}
+		transform(f32.NewAffine2D(
+			fixed(m, 0), fixed(m, 8), fixed(m, 16),
+			fixed(m, 4), fixed(m, 12), fixed(m, 20),
+		))
+	case 14, 15: // PaintTranslate.
+		if len(p) < 8 {
+			return
+		}
+		transform(f32.Affine2D{}.Offset(f32.Pt(float32(i16(p, 4)), float32(i16(p, 6)))))
+	case 16, 17, 18, 19: // PaintScale and PaintScaleAroundCenter.
+		if len(p) < 8 || format >= 18 && len(p) < 12 {
+			return
+		}
+		m := f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(f2dot14(p, 4), f2dot14(p, 6)))
+		if format >= 18 {
+			m = around(m, 8)
+		}
+		transform(m)
+	case 20, 21, 22, 23: // PaintScaleUniform and PaintScaleUniformAroundCenter.
+		if len(p) < 6 || format >= 22 && len(p) < 10 {
+			return
+		}
+		s := f2dot14(p, 4)
+		m := f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(s, s))
+		if format >= 22 {
+			m = around(m, 6)
+		}
+		transform(m)
+	case 24, 25, 26, 27: // PaintRotate and PaintRotateAroundCenter.
+		if len(p) < 6 || format >= 26 && len(p) < 10 {
+			return
+		}
+		a := f2dot14(p, 4) * math.Pi
+		sin, cos := float32(math.Sin(float64(a))), float32(math.Cos(float64(a)))
+		m := f32.NewAffine2D(cos, -sin, 0, sin, cos, 0)
+		if format >= 26 {
+			m = around(m, 6)
+		}
+		transform(m)
+	case 28, 29, 30, 31: // PaintSkew and PaintSkewAroundCenter.
+		if len(p) < 8 || format >= 30 && len(p) < 12 {
+			return
+		}
+		tanX := float32(math.Tan(float64(-f2dot14(p, 4) * math.Pi)))
+		tanY := float32(math.Tan(float64(f2dot14(p, 6) * math.Pi)))
+		m := f32.NewAffine2D(1, tanX, 0, tanY, 1, 0)
+		if format >= 30 {
+			m = around(m, 8)
+		}
+		transform(m)
+	case 32: // PaintComposite.
+		if len(p) < 8 {
+			return
+		}
+		const (
+			clear = 0
+			src   = 1
+			dest  = 2
+		)
+		mode := p[4]
+		if mode == clear {
+			return
+		}
+		if mode != src {
+			w.paint(t, off+u24(p, 5), clips, tr, depth)
+		}
+		if mode != dest {
+			w.paint(t, child(1), clips, tr, depth)
+		}
+	}
+}
+
+// fill adds a layer of paint clipped by clips.
+func (w *walker) fill(clips []Clip, paint Paint, tr f32.Affine2D) {
+	if len(clips) == 0 {
+		return
+	}
+	paint.Transform = tr
+	w.layers = append(w.layers, Layer{Clips: clips, Paint: paint})
+}
+
+// colorStop is a stop of a gradient.
+type colorStop struct {
+	offset float32
+	color  color.NRGBA
+}
+
+// colorLine returns the first and last stops of the color line at offset
+// off of t.
+func (w *walker) colorLine(t []byte, off uint32, variable bool) (first, last colorStop, ok bool) {
+	line, ok := sub(t, off, 3)
+	if !ok {
+		return first, last, false
+	}
+	stride := 6
+	if variable {
+		stride = 10
+	}
+	n := int(u16(line, 1))
+	stops, ok := sub(t, off+3, n*stride)
+	if !ok || n == 0 {
+		return first, last, false
+	}
+	for i := range n {
+		s := stops[i*stride:]
+		stop := colorStop{offset: f2dot14(s, 0), color: w.f.color(u16(s, 2), f2dot14(s, 4))}
+		if i == 0 || stop.offset < first.offset {
+			first = stop
+		}
+		if i == 0 || stop.offset >= last.offset {
+			last = stop
+		}
+	}
+	return first, last, true
+}
+
+// mix returns the average of two colors.
+func mix(c1, c2 color.NRGBA) color.NRGBA {
+	avg := func(a, b uint8) uint8 { return uint8((uint16(a) + uint16(b) + 1) / 2) }
+	return color.NRGBA{R: avg(c1.R, c2.R), G: avg(c1.G, c2.G), B: avg(c1.B, c2.B), A: avg(c1.A, c2.A)}
+}
+
+// search returns the record for gid of the records of size n sorted by
+// glyph ID in recs.
+func search(recs []byte, n int, gid font.GID) ([]byte, bool) {
+	lo, hi := 0, len(recs)/n
+	for lo < hi {
+		mid := (lo + hi) / 2
+		rec := recs[mid*n:]
+		switch g := font.GID(u16(rec, 0)); {
+		case g < gid:
+			lo = mid + 1
+		case g > gid:
+			hi = mid
+		default:
+			return rec[:n], true
+		}
+	}
+	return nil, false
+}
+
+// sub returns the n bytes at offset off of b.
+func sub(b []byte, off uint32, n int) ([]byte, bool) {
+	if uint64(off)+uint64(n) > uint64(len(b)) {
+		return nil, false
+	}
+	return b[off : int(off)+n], true
+}
+
+func u16(b []byte, i int) uint16 {
+	return uint16(b[i])<<8 | uint16(b[i+1])
+}
+
+func i16(b []byte, i int) int16 {
+	return int16(u16(b, i))
+}
+
+func u24(b []byte, i int) uint32 {
+	return uint32(b[i])<<16 | uint32(b[i+1])<<8 | uint32(b[i+2])
+}
+
+func u32(b []byte, i int) uint32 {
+	return uint32(u16(b, i))<<16 | uint32(u16(b, i+2))
+}
+
+// f2dot14 reads a signed 2.14 fixed point number.
+func f2dot14(b []byte, i int) float32 {
+	return float32(i16(b, i)) / (1 << 14)
+}
+
+// fixed reads a signed 16.16 fixed point number.
+func fixed(b []byte, i int) float32 {
+	return float32(int32(u32(b, i))) / (1 << 16)
+}
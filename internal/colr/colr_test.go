@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package colr
+
+import (
+	"image/color"
+	"testing"
+
+	"gioui.org/f32"
+)
+
+var red = color.NRGBA{R: 0xff, A: 0xff}
+
+// testCPAL is a palette of a single red color.
+var testCPAL = []byte{
+	0, 0, // version
+	0, 1, // numPaletteEntries
+	0, 1, // numPalettes
+	0, 1, // numColorRecords
+	0, 0, 0, 14, // colorRecordsArrayOffset
+	0, 0, // colorRecordIndices[0]
+	0, 0, 0xff, 0xff, // BGRA
+}
+
+func TestLayersV0(t *testing.T) {
+	colr := []byte{
+		0, 0, // version
+		0, 1, // numBaseGlyphRecords
+		0, 0, 0, 14, // baseGlyphRecordsOffset
+		0, 0, 0, 20, // layerRecordsOffset
+		0, 2, // numLayerRecords
+		// BaseGlyph record.
+		0, 5, 0, 0, 0, 2,
+		// Layer records.
+		0, 7, 0, 0,
+		0, 8, 0xff, 0xff,
+	}
+	f, err := Parse(colr, testCPAL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := f.Layers(6, nil); len(l) != 0 {
+		t.Errorf("plain glyph has %d layers", len(l))
+	}
+	layers := f.Layers(5, nil)
+	if len(layers) != 2 {
+		t.Fatalf("got %d layers, expected 2", len(layers))
+	}
+	if c := layers[0].Clips; len(c) != 1 || c[0].Glyph != 7 {
+		t.Errorf("layer 0 clips: %v", c)
+	}
+	if p := layers[0].Paint; p.Foreground || p.Color != red {
+		t.Errorf("layer 0 paint: %+v", p)
+	}
+	if c := layers[1].Clips; len(c) != 1 || c[0].Glyph != 8 {
+		t.Errorf("layer 1 clips: %v", c)
+	}
+	if p := layers[1].Paint; !p.Foreground {
+		t.Errorf("layer 1 is not painted with the text color")
+	}
+}
+
+func TestLayersV1(t *testing.T) {
+	colr := []byte{
+		0, 1, // version
+		0, 0, // numBaseGlyphRecords
+		0, 0, 0, 0, // baseGlyphRecordsOffset
+		0, 0, 0, 0, // layerRecordsOffset
+		0, 0, // numLayerRecords
+		0, 0, 0, 34, // baseGlyphListOffset
+		0, 0, 0, 0, // layerListOffset
+		0, 0, 0, 0, // clipListOffset
+		0, 0, 0, 0, // varIndexMapOffset
+		0, 0, 0, 0, // itemVariationStoreOffset
+		// BaseGlyphList.
+		0, 0, 0, 1,
+		0, 5, 0, 0, 0, 10,
+		// PaintTranslate by (100, 0).
+		14, 0, 0, 8, 0, 100, 0, 0,
+		// PaintGlyph of glyph 7.
+		10, 0, 0, 6, 0, 7,
+		// PaintLinearGradient from (0, 0) to (100, 0).
+		4, 0, 0, 16,
+		0, 0, 0, 0,
+		0, 100, 0, 0,
+		0, 0, 0, 100,
+		// ColorLine from red to the text color.
+		0, 0, 2,
+		0, 0, 0, 0, 0x40, 0,
+		0x40, 0, 0xff, 0xff, 0x40, 0,
+	}
+	f, err := Parse(colr, testCPAL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers := f.Layers(5, nil)
+	if len(layers) != 1 {
+		t.Fatalf("got %d layers, expected 1", len(layers))
+	}
+	tr := f32.Affine2D{}.Offset(f32.Pt(100, 0))
+	l := layers[0]
+	if len(l.Clips) != 1 || l.Clips[0].Glyph != 7 || l.Clips[0].Transform != tr {
+		t.Errorf("clips: %v", l.Clips)
+	}
+	p := l.Paint
+	if !p.Gradient || p.Stop1 != f32.Pt(0, 0) || p.Stop2 != f32.Pt(100, 0) || p.Transform != tr {
+		t.Errorf("gradient: %+v", p)
+	}
+	if p.Color != red || p.Color2 != (color.NRGBA{A: 0xff}) {
+		t.Errorf("gradient colors: %v, %v", p.Color, p.Color2)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	colr := []byte{
+		0, 0, // version
+		0, 1, // numBaseGlyphRecords
+		0, 0, 0, 14, // baseGlyphRecordsOffset
+		0, 0, 0, 20, // layerRecordsOffset
+		0, 2, // numLayerRecords
+	}
+	if _, err := Parse(colr, nil); err == nil {
+		t.Error("truncated table parsed without error")
+	}
+}
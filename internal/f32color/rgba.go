@@ -189,3 +189,67 @@ func approxLuminance(c color.NRGBA) byte {
 	)
 	return byte((r*int(c.R) + g*int(c.G) + b*int(c.B)) / t)
 }
+
+// HSVToNRGBA converts a color in the HSV model of the sRGB color space to
+// NRGBA. Hue, saturation and value are in the [0; 1] range.
+//
+// HSV is a transform of the gamma encoded sRGB channels, so unlike
+// LinearFromSRGB and NRGBAToLinearRGBA the HSV conversions involve no
+// linearization.
+func HSVToNRGBA(h, s, v float32, alpha uint8) color.NRGBA {
+	h = (h - float32(math.Floor(float64(h)))) * 6
+	i := int(h)
+	f := h - float32(i)
+	p := v * (1 - s)
+	q := v * (1 - s*f)
+	t := v * (1 - s*(1-f))
+	var r, g, b float32
+	switch i {
+	case 0:
+		r, g, b = v, t, p
+	case 1:
+		r, g, b = q, v, p
+	case 2:
+		r, g, b = p, v, t
+	case 3:
+		r, g, b = p, q, v
+	case 4:
+		r, g, b = t, p, v
+	default:
+		r, g, b = v, p, q
+	}
+	return color.NRGBA{
+		R: uint8(r*255 + .5),
+		G: uint8(g*255 + .5),
+		B: uint8(b*255 + .5),
+		A: alpha,
+	}
+}
+
+// NRGBAToHSV converts the color channels of col to the HSV model. Hue,
+// saturation and value are in the [0; 1] range. The hue of gray colors is 0.
+func NRGBAToHSV(col color.NRGBA) (h, s, v float32) {
+	r, g, b := float32(col.R)/0xff, float32(col.G)/0xff, float32(col.B)/0xff
+	mx := max(r, g, b)
+	mn := min(r, g, b)
+	d := mx - mn
+	v = mx
+	if mx > 0 {
+		s = d / mx
+	}
+	if d == 0 {
+		return 0, s, v
+	}
+	switch mx {
+	case r:
+		h = (g - b) / d
+		if h < 0 {
+			h += 6
+		}
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return h / 6, s, v
+}
@@ -56,3 +56,22 @@ func BenchmarkLinearFromSRGB(b *testing.B) {
 		}
 	})
 }
+
+func TestHSVRoundtrip(t *testing.T) {
+	for _, want := range []color.NRGBA{
+		{R: 0xff, A: 0xff},
+		{G: 0x80, B: 0x40, A: 0x80},
+		{R: 0x12, G: 0x34, B: 0x56, A: 0xff},
+		{R: 0xfe, G: 0xfe, B: 0xfe, A: 0x01},
+		{R: 0xff, B: 0xff, A: 0xff},
+		{A: 0xff},
+	} {
+		h, s, v := NRGBAToHSV(want)
+		if got := HSVToNRGBA(h, s, v, want.A); got != want {
+			t.Errorf("HSV(%v, %v, %v): got %v, want %v", h, s, v, got, want)
+		}
+	}
+	if got, want := HSVToNRGBA(1./3, 1, 1, 0xff), (color.NRGBA{G: 0xff, A: 0xff}); got != want {
+		t.Errorf("HSV green: got %v, want %v", got, want)
+	}
+}
@@ -1,5 +1,16 @@
 // SPDX-License-Identifier: Unlicense OR MIT
 
+// Package clipboard contains the commands for reading and writing the
+// clipboard.
+//
+// Clipboard content is identified by MIME types. Plain text has the type
+// "application/text"; platforms that support more than text also transfer
+// types such as "text/html", "image/png" and "text/uri-list".
+//
+// On X11 and Wayland, commands may also target the primary selection,
+// which holds the most recently selected text and is pasted by a middle
+// click. Commands for the primary selection are ignored on platforms
+// without one.
 package clipboard
 
 import (
@@ -8,16 +19,38 @@ import (
 	"gioui.org/io/event"
 )
 
-// WriteCmd copies Text to the clipboard.
+// WriteCmd copies Data to the clipboard.
 type WriteCmd struct {
+	// Type is the MIME type of Data.
 	Type string
 	Data io.ReadCloser
+	// Formats are the content in other MIME types, such as a plain text
+	// version of HTML content. Readers of the clipboard choose among Type
+	// and the types of Formats.
+	Formats []Format
+	// Primary writes to the primary selection instead of the clipboard.
+	Primary bool
 }
 
-// ReadCmd requests the text of the clipboard, delivered to
-// the handler through an [io/transfer.DataEvent].
+// Format is clipboard content in a MIME type.
+type Format struct {
+	Type string
+	Data io.ReadCloser
+}
+
+// ReadCmd requests the content of the clipboard, delivered to
+// the handler through an [io/transfer.DataEvent]. The event
+// lists the MIME types that the clipboard content is available in.
+//
+// The content is read into memory in full before it is delivered, so its
+// size is limited by the available memory.
 type ReadCmd struct {
 	Tag event.Tag
+	// Types are the accepted MIME types in order of preference. If
+	// empty, plain text is requested.
+	Types []string
+	// Primary reads the primary selection instead of the clipboard.
+	Primary bool
 }
 
 func (WriteCmd) ImplementsCommand() {}
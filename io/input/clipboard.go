@@ -8,11 +8,19 @@ import (
 
 	"gioui.org/io/clipboard"
 	"gioui.org/io/event"
+	"gioui.org/io/transfer"
 )
 
 // clipboardState contains the state for clipboard event routing.
 type clipboardState struct {
 	receivers []event.Tag
+	// types are the MIME types requested by the receivers, in order of
+	// preference.
+	types []string
+	// primaryReceivers and primaryTypes are the receivers and types of
+	// reads of the primary selection.
+	primaryReceivers []event.Tag
+	primaryTypes     []string
 }
 
 type clipboardQueue struct {
@@ -20,8 +28,24 @@ type clipboardQueue struct {
 	requested bool
 	mime      string
 	text      []byte
+	// formats are the other formats of text.
+	formats []ClipboardData
+	// primaryRequested is like requested, for the primary selection.
+	primaryRequested bool
+	// primary is the content of the most recent write to the primary
+	// selection, if any.
+	primary []ClipboardData
 }
 
+// ClipboardData is clipboard content in a MIME type.
+type ClipboardData struct {
+	Type string
+	Data []byte
+}
+
+// textType is the MIME type of plain text.
+const textType = "application/text"
+
 // WriteClipboard returns the most recent data to be copied
 // to the clipboard, if any.
 func (q *clipboardQueue) WriteClipboard() (mime string, content []byte, ok bool) {
@@ -30,9 +54,28 @@ func (q *clipboardQueue) WriteClipboard() (mime string, content []byte, ok bool)
 	}
 	content = q.text
 	q.text = nil
+	q.formats = nil
 	return q.mime, content, true
 }
 
+// WriteClipboardData is like WriteClipboard, but returns the data in
+// every format, starting with the primary format.
+func (q *clipboardQueue) WriteClipboardData() ([]ClipboardData, bool) {
+	formats := q.formats
+	mime, content, ok := q.WriteClipboard()
+	if !ok {
+		return nil, false
+	}
+	return append([]ClipboardData{{Type: mime, Data: content}}, formats...), true
+}
+
+// WritePrimary is like WriteClipboardData, for the primary selection.
+func (q *clipboardQueue) WritePrimary() ([]ClipboardData, bool) {
+	data := q.primary
+	q.primary = nil
+	return data, data != nil
+}
+
 // ClipboardRequested reports if any new handler is waiting
 // to read the clipboard.
 func (q *clipboardQueue) ClipboardRequested(state clipboardState) bool {
@@ -41,31 +84,85 @@ func (q *clipboardQueue) ClipboardRequested(state clipboardState) bool {
 	return req
 }
 
+// PrimaryRequested is like ClipboardRequested, for the primary selection.
+func (q *clipboardQueue) PrimaryRequested(state clipboardState) bool {
+	req := len(state.primaryReceivers) > 0 && q.primaryRequested
+	q.primaryRequested = false
+	return req
+}
+
 func (q *clipboardQueue) Push(state clipboardState, e event.Event) (clipboardState, []taggedEvent) {
+	receivers := state.receivers
+	if e, ok := e.(transfer.DataEvent); ok && e.Primary {
+		receivers = state.primaryReceivers
+		state.primaryReceivers = nil
+		state.primaryTypes = nil
+	} else {
+		state.receivers = nil
+		state.types = nil
+	}
 	var evts []taggedEvent
-	for _, r := range state.receivers {
+	for _, r := range receivers {
 		evts = append(evts, taggedEvent{tag: r, event: e})
 	}
-	state.receivers = nil
 	return state, evts
 }
 
 func (q *clipboardQueue) ProcessWriteClipboard(req clipboard.WriteCmd) {
 	defer req.Data.Close()
+	var formats []ClipboardData
+	for _, f := range req.Formats {
+		content, err := io.ReadAll(f.Data)
+		f.Data.Close()
+		if err != nil {
+			continue
+		}
+		formats = append(formats, ClipboardData{Type: f.Type, Data: content})
+	}
 	content, err := io.ReadAll(req.Data)
 	if err != nil {
 		return
 	}
+	if req.Primary {
+		q.primary = append([]ClipboardData{{Type: req.Type, Data: content}}, formats...)
+		return
+	}
 	q.mime = req.Type
 	q.text = content
+	q.formats = formats
 }
 
-func (q *clipboardQueue) ProcessReadClipboard(state clipboardState, tag event.Tag) clipboardState {
-	if slices.Contains(state.receivers, tag) {
+func (q *clipboardQueue) ProcessReadClipboard(state clipboardState, req clipboard.ReadCmd) clipboardState {
+	if req.Primary {
+		var added bool
+		state.primaryReceivers, state.primaryTypes, added = addClipboardReader(state.primaryReceivers, state.primaryTypes, req)
+		q.primaryRequested = q.primaryRequested || added
 		return state
 	}
-	n := len(state.receivers)
-	state.receivers = append(state.receivers[:n:n], tag)
-	q.requested = true
+	var added bool
+	state.receivers, state.types, added = addClipboardReader(state.receivers, state.types, req)
+	q.requested = q.requested || added
 	return state
 }
+
+// addClipboardReader adds the tag and types of a read to receivers and
+// types, without modifying their backing arrays. It reports whether the
+// tag was added.
+func addClipboardReader(receivers []event.Tag, types []string, req clipboard.ReadCmd) ([]event.Tag, []string, bool) {
+	reqTypes := req.Types
+	if len(reqTypes) == 0 {
+		reqTypes = []string{textType}
+	}
+	n := len(types)
+	for _, t := range reqTypes {
+		if !slices.Contains(types, t) {
+			types = append(types[:n:n], t)
+			n++
+		}
+	}
+	if slices.Contains(receivers, req.Tag) {
+		return receivers, types, false
+	}
+	n = len(receivers)
+	return append(receivers[:n:n], req.Tag), types, true
+}
@@ -4,6 +4,8 @@ package input
 
 import (
 	"io"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 
@@ -87,6 +89,123 @@ func TestQueueProcessWriteClipboard(t *testing.T) {
 	assertClipboardWriteCmd(t, r, mime, "Write 2")
 }
 
+func TestQueueProcessWriteClipboardFormats(t *testing.T) {
+	r := new(Router)
+
+	r.Source().Execute(clipboard.WriteCmd{
+		Type: "text/html",
+		Data: io.NopCloser(strings.NewReader("<b>Bold</b>")),
+		Formats: []clipboard.Format{
+			{Type: "application/text", Data: io.NopCloser(strings.NewReader("Bold"))},
+			{Type: "image/png", Data: io.NopCloser(strings.NewReader("\x89PNG"))},
+		},
+	})
+	data, ok := r.WriteClipboardData()
+	if !ok {
+		t.Fatal("missing clipboard write")
+	}
+	want := []ClipboardData{
+		{Type: "text/html", Data: []byte("<b>Bold</b>")},
+		{Type: "application/text", Data: []byte("Bold")},
+		{Type: "image/png", Data: []byte("\x89PNG")},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("got clipboard data %q, want %q", data, want)
+	}
+	if _, ok := r.WriteClipboardData(); ok {
+		t.Error("duplicated clipboard write")
+	}
+
+	// A plain write replaces the formats of the previous write.
+	r.Source().Execute(clipboard.WriteCmd{
+		Type:    "text/html",
+		Data:    io.NopCloser(strings.NewReader("<i>1</i>")),
+		Formats: []clipboard.Format{{Type: "application/text", Data: io.NopCloser(strings.NewReader("1"))}},
+	})
+	r.Source().Execute(clipboard.WriteCmd{Type: "application/text", Data: io.NopCloser(strings.NewReader("2"))})
+	data, _ = r.WriteClipboardData()
+	if want := []ClipboardData{{Type: "application/text", Data: []byte("2")}}; !reflect.DeepEqual(data, want) {
+		t.Errorf("got clipboard data %q, want %q", data, want)
+	}
+}
+
+func TestClipboardReadTypes(t *testing.T) {
+	r, handlers := new(Router), make([]int, 2)
+
+	r.Source().Execute(clipboard.ReadCmd{Tag: &handlers[0], Types: []string{"image/png", "text/uri-list"}})
+	r.Source().Execute(clipboard.ReadCmd{Tag: &handlers[1]})
+	assertClipboardReadCmd(t, r, 2)
+	want := []string{"image/png", "text/uri-list", "application/text"}
+	if got := r.ClipboardTypes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got clipboard types %v, want %v", got, want)
+	}
+
+	// The content is delivered in the first available type, and lists the
+	// other types.
+	r.Queue(transfer.DataEvent{
+		Type:  "text/uri-list",
+		Types: []string{"text/uri-list", "application/text"},
+		Open: func() io.ReadCloser {
+			return io.NopCloser(strings.NewReader("file:///tmp/a.txt"))
+		},
+	})
+	evts := events(r, -1, transfer.TargetFilter{Target: &handlers[0], Type: "text/uri-list"})
+	assertEventTypeSequence(t, evts, transfer.DataEvent{})
+	if e := evts[0].(transfer.DataEvent); !slices.Equal(e.Types, []string{"text/uri-list", "application/text"}) {
+		t.Errorf("got available types %v", e.Types)
+	}
+	assertClipboardReadCmd(t, r, 0)
+	if got := r.ClipboardTypes(); len(got) != 0 {
+		t.Errorf("got clipboard types %v after delivery, want none", got)
+	}
+}
+
+func TestClipboardPrimary(t *testing.T) {
+	r, handlers := new(Router), make([]int, 2)
+
+	r.Source().Execute(clipboard.WriteCmd{Primary: true, Type: "application/text", Data: io.NopCloser(strings.NewReader("selected"))})
+	if _, ok := r.WriteClipboardData(); ok {
+		t.Error("primary selection written to the clipboard")
+	}
+	data, ok := r.WritePrimary()
+	if want := []ClipboardData{{Type: "application/text", Data: []byte("selected")}}; !ok || !reflect.DeepEqual(data, want) {
+		t.Errorf("got primary selection %q, want %q", data, want)
+	}
+	if _, ok := r.WritePrimary(); ok {
+		t.Error("duplicated primary selection write")
+	}
+
+	r.Source().Execute(clipboard.ReadCmd{Tag: &handlers[0], Primary: true})
+	r.Source().Execute(clipboard.ReadCmd{Tag: &handlers[1]})
+	if !r.PrimaryRequested() {
+		t.Error("missing primary selection request")
+	}
+	if r.PrimaryRequested() {
+		t.Error("duplicated primary selection request")
+	}
+	if got := r.PrimaryTypes(); !slices.Equal(got, []string{"application/text"}) {
+		t.Errorf("got primary selection types %v", got)
+	}
+	assertClipboardReadCmd(t, r, 1)
+
+	// Primary selection content is delivered only to its readers.
+	r.Queue(transfer.DataEvent{
+		Type:    "application/text",
+		Primary: true,
+		Open: func() io.ReadCloser {
+			return io.NopCloser(strings.NewReader("selected"))
+		},
+	})
+	assertEventTypeSequence(t, events(r, -1, transfer.TargetFilter{Target: &handlers[0], Type: "application/text"}), transfer.DataEvent{})
+	assertEventTypeSequence(t, events(r, -1, transfer.TargetFilter{Target: &handlers[1], Type: "application/text"}))
+	if got := len(r.state().primaryReceivers); got != 0 {
+		t.Errorf("got %d primary selection receivers after delivery", got)
+	}
+	if got := len(r.state().receivers); got != 1 {
+		t.Errorf("got %d clipboard receivers, want 1", got)
+	}
+}
+
 func assertClipboardReadCmd(t *testing.T, router *Router, expected int) {
 	t.Helper()
 	if got := len(router.state().receivers); got != expected {
@@ -58,6 +58,8 @@ type Router struct {
 	deferring bool
 	// scratchFilters is for garbage-free construction of ephemeral filters.
 	scratchFilters []taggedFilter
+	// primarySupported is set if the platform has a primary selection.
+	primarySupported bool
 }
 
 // Source implements the interface between a Router and user interface widgets.
@@ -201,6 +203,12 @@ func (s Source) Focused(tag event.Tag) bool {
 	return s.r.state().keyState.focus == tag
 }
 
+// PrimarySupported reports whether the platform has a primary selection
+// that [clipboard.ReadCmd] and [clipboard.WriteCmd] can target.
+func (s Source) PrimarySupported() bool {
+	return s.Enabled() && s.r.primarySupported
+}
+
 // Event returns the next event that matches at least one of filters.
 // If the source is disabled, no events will be reported.
 func (s Source) Event(filters ...event.Filter) (event.Event, bool) {
@@ -558,7 +566,7 @@ func (q *Router) executeCommand(c Command) stateChange {
 	case clipboard.WriteCmd:
 		q.cqueue.ProcessWriteClipboard(req)
 	case clipboard.ReadCmd:
-		state.clipboardState = q.cqueue.ProcessReadClipboard(state.clipboardState, req.Tag)
+		state.clipboardState = q.cqueue.ProcessReadClipboard(state.clipboardState, req)
 	case pointer.GrabCmd:
 		state.pointerState, evts = q.pointer.queue.grab(state.pointerState, req)
 	case op.InvalidateCmd:
@@ -743,12 +751,48 @@ func (q *Router) WriteClipboard() (mime string, content []byte, ok bool) {
 	return q.cqueue.WriteClipboard()
 }
 
+// WriteClipboardData is like WriteClipboard, but returns the content in
+// every format of the most recent write, starting with the primary format.
+func (q *Router) WriteClipboardData() ([]ClipboardData, bool) {
+	return q.cqueue.WriteClipboardData()
+}
+
+// WritePrimary returns the content in every format of the most recent
+// write to the primary selection, if any.
+func (q *Router) WritePrimary() ([]ClipboardData, bool) {
+	return q.cqueue.WritePrimary()
+}
+
+// SetPrimarySupported records whether the platform has a primary
+// selection, as reported by [Source.PrimarySupported].
+func (q *Router) SetPrimarySupported(supported bool) {
+	q.primarySupported = supported
+}
+
+// PrimaryRequested reports if any new handler is waiting
+// to read the primary selection.
+func (q *Router) PrimaryRequested() bool {
+	return q.cqueue.PrimaryRequested(q.lastState().clipboardState)
+}
+
+// PrimaryTypes is like ClipboardTypes, for the primary selection.
+func (q *Router) PrimaryTypes() []string {
+	return q.lastState().primaryTypes
+}
+
 // ClipboardRequested reports if any new handler is waiting
 // to read the clipboard.
 func (q *Router) ClipboardRequested() bool {
 	return q.cqueue.ClipboardRequested(q.lastState().clipboardState)
 }
 
+// ClipboardTypes returns the MIME types requested by the handlers waiting
+// to read the clipboard, in order of preference. The clipboard content
+// should be delivered in the first type that is available.
+func (q *Router) ClipboardTypes() []string {
+	return q.lastState().types
+}
+
 // Cursor returns the last cursor set.
 func (q *Router) Cursor() pointer.Cursor {
 	return q.state().cursor
@@ -30,6 +30,12 @@ const (
 	Editor
 	RadioButton
 	Switch
+	// Tab is a page selector in a row of tabs.
+	Tab
+	// Link is an interactive span of text, such as a hyperlink.
+	Link
+	// TabList is a row of Tab components.
+	TabList
 )
 
 // SelectedOp describes the selected state for components that have
@@ -85,6 +91,12 @@ func (c ClassOp) String() string {
 		return "RadioButton"
 	case Switch:
 		return "Switch"
+	case Tab:
+		return "Tab"
+	case Link:
+		return "Link"
+	case TabList:
+		return "TabList"
 	default:
 		panic("invalid ClassOp")
 	}
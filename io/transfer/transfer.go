@@ -96,6 +96,13 @@ type DataEvent struct {
 	// Open returns the transfer data. It is only valid to call Open in the frame
 	// the DataEvent is received. The caller must close the return value after use.
 	Open func() io.ReadCloser
+	// Types lists the MIME types that the data is available in, if known,
+	// such as for the content of the clipboard. A target that prefers
+	// another type may request the data again in that type.
+	Types []string
+	// Primary reports whether the data is the content of the primary
+	// selection rather than the clipboard.
+	Primary bool
 }
 
 func (DataEvent) ImplementsEvent() {}
@@ -8,8 +8,11 @@ import (
 	"image"
 	"io"
 	"log"
+	"math"
 	"os"
 	"slices"
+	"strings"
+	"unicode"
 
 	"github.com/go-text/typesetting/di"
 	"github.com/go-text/typesetting/font"
@@ -17,17 +20,20 @@ import (
 	"github.com/go-text/typesetting/fontscan"
 	"github.com/go-text/typesetting/language"
 	"github.com/go-text/typesetting/shaping"
+	"github.com/go-text/typesetting/unicodedata"
 	"golang.org/x/image/math/fixed"
 	"golang.org/x/text/unicode/bidi"
 
 	"gioui.org/f32"
 	giofont "gioui.org/font"
 	"gioui.org/font/opentype"
+	"gioui.org/internal/colr"
 	"gioui.org/internal/debug"
 	"gioui.org/io/system"
 	"gioui.org/op"
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
+	"gioui.org/text/hyphen"
 )
 
 // document holds a collection of shaped lines and alignment information for
@@ -91,6 +97,9 @@ type line struct {
 	direction system.TextDirection
 	// runeCount is the number of text runes represented by this line's runs.
 	runeCount int
+	// spanBase is the index of the first span of the paragraph containing
+	// this line. It is added to the span of each run.
+	spanBase int
 
 	yOffset int
 }
@@ -204,36 +213,131 @@ type runLayout struct {
 	// truncator indicates that this run is a text truncator standing in for remaining
 	// text.
 	truncator bool
+	// span is the index of the span within the paragraph that this run was
+	// shaped from.
+	span int
+}
+
+// spanStyle is the style of a range of runes in a paragraph of rich text.
+type spanStyle struct {
+	font giofont.Font
+	ppem fixed.Int26_6
+	// runes is the number of runes in the range.
+	runes int
+}
+
+// spanAt returns the index of the span containing the rune at offset.
+func spanAt(spans []spanStyle, offset int) int {
+	end := 0
+	for i, sp := range spans {
+		end += sp.runes
+		if offset < end {
+			return i
+		}
+	}
+	return max(len(spans)-1, 0)
 }
 
 // shaperImpl implements the shaping and line-wrapping of opentype fonts.
 type shaperImpl struct {
 	// Fields for tracking fonts/faces.
-	fontMap      *fontscan.FontMap
-	faces        []*font.Face
-	faceToIndex  map[*font.Font]int
-	faceMeta     []giofont.Font
+	fontMap     *fontscan.FontMap
+	faces       []*font.Face
+	faceToIndex map[*font.Font]int
+	faceMeta    []giofont.Font
+	// colorFonts holds the color glyphs of the faces, by face index.
+	colorFonts   []*colr.Font
 	defaultFaces []string
 	logger       interface {
 		Printf(format string, args ...any)
 	}
 	parser parser
+	// systemFonts and fontCacheDir are set if the shaper uses system fonts.
+	systemFonts  bool
+	fontCacheDir string
+	// fallbacks are the fallback typefaces by script and language.
+	fallbacks []fallback
+	// query is the font map query of the current text font.
+	query fontscan.Query
+	// lang is the language of the text being shaped.
+	lang language.Language
+	// variedFaces maps faces with font variations to their index.
+	variedFaces map[*font.Face]int
+	// variedFaceCache maps variable fonts and their variations to faces. It
+	// holds at most maxVariedFaces faces, and the least recently used face
+	// is evicted to make room for a new one, which takes over its index.
+	variedFaceCache map[variedFaceKey]*variedFace
+	// variedFaceUses counts the uses of varied faces, to order them by
+	// their last use.
+	variedFaceUses uint64
+	// staticFonts records the fonts that are not variable.
+	staticFonts map[*font.Font]bool
+	// evictedFaces is set when a varied face is evicted, which invalidates
+	// the glyph IDs referring to it.
+	evictedFaces bool
 
 	// Shaping and wrapping state.
 	shaper        shaping.HarfbuzzShaper
+	features      []shaping.FontFeature
+	variations    []font.Variation
+	variationsKey FontVariations
 	wrapper       shaping.LineWrapper
 	bidiParagraph bidi.Paragraph
+	hyphens       hyphenation
+	// upright lists the rune ranges of the text last shaped by shapeText
+	// that are shaped upright.
+	upright []Range
 
 	// Scratch buffers used to avoid re-allocating slices during routine internal
 	// shaping operations.
 	splitScratch1, splitScratch2 []shaping.Input
 	outScratchBuf                []shaping.Output
 	scratchRunes                 []rune
+	scratchFamilies              []string
+	colorLayers                  []colr.Layer
 
 	// bitmapGlyphCache caches extracted bitmap glyph images.
 	bitmapGlyphCache bitmapCache
 }
 
+// fallback is a parsed Fallback.
+type fallback struct {
+	// script is the script matched, or 0 for all scripts.
+	script   language.Script
+	lang     language.Language
+	families []string
+}
+
+// matches reports whether the fallback applies to runes of script in text
+// of lang.
+func (f fallback) matches(script language.Script, lang language.Language) bool {
+	if f.script != 0 && f.script != script {
+		return false
+	}
+	return f.lang == "" || lang == f.lang || strings.HasPrefix(string(lang), string(f.lang)+"-")
+}
+
+// variedFaceKey identifies a font with a set of variations.
+type variedFaceKey struct {
+	font       *font.Font
+	variations FontVariations
+}
+
+// variedFace is a face of a variable font with a set of variations.
+type variedFace struct {
+	key  variedFaceKey
+	face *font.Face
+	// idx is the index of the face.
+	idx int
+	// lastUse is the value of variedFaceUses when the face was last used.
+	lastUse uint64
+}
+
+// maxVariedFaces limits the number of faces with font variations, whose
+// indexes must fit the 16 bits of a GlyphID face index along with the
+// other faces.
+const maxVariedFaces = 256
+
 // debugLogger only logs messages if debug.Text is true.
 type debugLogger struct {
 	*log.Logger
@@ -254,12 +358,17 @@ func newShaperImpl(systemFonts bool, collection []FontFace) *shaperImpl {
 	shaper.logger = newDebugLogger()
 	shaper.fontMap = fontscan.NewFontMap(shaper.logger)
 	shaper.faceToIndex = make(map[*font.Font]int)
+	shaper.variedFaces = make(map[*font.Face]int)
+	shaper.variedFaceCache = make(map[variedFaceKey]*variedFace)
+	shaper.staticFonts = make(map[*font.Font]bool)
 	if systemFonts {
 		str, err := os.UserCacheDir()
 		if err != nil {
 			shaper.logger.Printf("failed resolving font cache dir: %v", err)
 			shaper.logger.Printf("skipping system font load")
 		}
+		shaper.systemFonts = true
+		shaper.fontCacheDir = str
 		if err := shaper.fontMap.UseSystemFonts(str); err != nil {
 			shaper.logger.Printf("failed loading system fonts: %v", err)
 		}
@@ -278,10 +387,10 @@ func newShaperImpl(systemFonts bool, collection []FontFace) *shaperImpl {
 func (s *shaperImpl) Load(f FontFace) {
 	desc := opentype.FontToDescription(f.Font)
 	s.fontMap.AddFace(f.Face.Face(), fontscan.Location{File: fmt.Sprint(desc)}, desc)
-	s.addFace(f.Face.Face(), f.Font)
+	s.addFace(f.Face.Face(), f.Font, colr.FaceGlyphs(f.Face))
 }
 
-func (s *shaperImpl) addFace(f *font.Face, md giofont.Font) {
+func (s *shaperImpl) addFace(f *font.Face, md giofont.Font, c *colr.Font) {
 	if _, ok := s.faceToIndex[f.Font]; ok {
 		return
 	}
@@ -290,6 +399,140 @@ func (s *shaperImpl) addFace(f *font.Face, md giofont.Font) {
 	s.faceToIndex[f.Font] = idx
 	s.faces = append(s.faces, f)
 	s.faceMeta = append(s.faceMeta, md)
+	s.colorFonts = append(s.colorFonts, c)
+}
+
+// setFallbacks configures the fallback typefaces. Fallbacks with invalid
+// scripts are ignored.
+func (s *shaperImpl) setFallbacks(fallbacks []Fallback) {
+	s.fallbacks = s.fallbacks[:0]
+	for _, f := range fallbacks {
+		var fb fallback
+		if f.Script != "" {
+			script, err := language.ParseScript(f.Script)
+			if err != nil {
+				s.logger.Printf("unknown fallback script %q", f.Script)
+				continue
+			}
+			fb.script = script
+		}
+		if f.Language != "" {
+			fb.lang = language.NewLanguage(f.Language)
+		}
+		families, err := s.parser.parse(string(f.Typeface))
+		if err != nil {
+			s.logger.Printf("Unable to parse typeface %q: %v", f.Typeface, err)
+			continue
+		}
+		fb.families = slices.Clone(families)
+		s.fallbacks = append(s.fallbacks, fb)
+	}
+}
+
+// systemFontList returns the fonts found on the system.
+func (s *shaperImpl) systemFontList() []SystemFont {
+	if !s.systemFonts {
+		return nil
+	}
+	fps, err := fontscan.SystemFonts(s.logger, s.fontCacheDir)
+	if err != nil {
+		s.logger.Printf("failed loading system fonts: %v", err)
+		return nil
+	}
+	fonts := make([]SystemFont, 0, len(fps))
+	for _, fp := range fps {
+		fonts = append(fonts, SystemFont{
+			Font: opentype.DescriptionToFont(font.Description{
+				Family: fp.Family,
+				Aspect: fp.Aspect,
+			}),
+			File:  fp.Location.File,
+			Index: int(fp.Location.Index),
+		})
+	}
+	return fonts
+}
+
+// faceIndex returns the index of the face f.
+func (s *shaperImpl) faceIndex(f *font.Face) int {
+	if f == nil {
+		return 0
+	}
+	if idx, ok := s.variedFaces[f]; ok {
+		return idx
+	}
+	return s.faceToIndex[f.Font]
+}
+
+// setFontSettings configures the OpenType features and font variations for
+// subsequent shaping.
+func (s *shaperImpl) setFontSettings(features FontFeatures, variations FontVariations) {
+	s.features = s.features[:0]
+	for i := range features.Len() {
+		f := features.At(i)
+		s.features = append(s.features, shaping.FontFeature{
+			Tag:   gotextot.MustNewTag(f.Tag),
+			Value: f.Value,
+		})
+	}
+	s.variations = s.variations[:0]
+	s.variationsKey = variations
+	for i := range variations.Len() {
+		v := variations.At(i)
+		s.variations = append(s.variations, font.Variation{
+			Tag:   gotextot.MustNewTag(v.Tag),
+			Value: v.Value,
+		})
+	}
+}
+
+// variedFace returns the face with the current font variations applied to
+// f, registering it with an index if f is a variable font.
+func (s *shaperImpl) variedFace(f *font.Face) *font.Face {
+	if len(s.variations) == 0 || f == nil || s.staticFonts[f.Font] {
+		return f
+	}
+	s.variedFaceUses++
+	key := variedFaceKey{font: f.Font, variations: s.variationsKey}
+	if varied, ok := s.variedFaceCache[key]; ok {
+		varied.lastUse = s.variedFaceUses
+		return varied.face
+	}
+	// The HarfbuzzShaper caches its fonts by *font.Font, ignoring the
+	// variations of the face. Give every varied face a copy of the font.
+	fnt := *f.Font
+	face := font.NewFace(&fnt)
+	face.SetVariations(s.variations)
+	if len(face.Coords()) == 0 {
+		s.staticFonts[f.Font] = true
+		return f
+	}
+	varied := &variedFace{key: key, face: face, lastUse: s.variedFaceUses}
+	base := s.faceIndex(f)
+	meta, c := s.faceMeta[base], s.colorFonts[base]
+	if len(s.variedFaceCache) < maxVariedFaces {
+		varied.idx = len(s.faces)
+		s.faces = append(s.faces, face)
+		s.faceMeta = append(s.faceMeta, meta)
+		s.colorFonts = append(s.colorFonts, c)
+	} else {
+		var oldest *variedFace
+		for _, v := range s.variedFaceCache {
+			if oldest == nil || v.lastUse < oldest.lastUse {
+				oldest = v
+			}
+		}
+		delete(s.variedFaceCache, oldest.key)
+		delete(s.variedFaces, oldest.face)
+		s.evictedFaces = true
+		varied.idx = oldest.idx
+		s.faces[varied.idx] = face
+		s.faceMeta[varied.idx] = meta
+		s.colorFonts[varied.idx] = c
+	}
+	s.variedFaces[face] = varied.idx
+	s.variedFaceCache[key] = varied
+	return face
 }
 
 // splitByScript divides the inputs into new, smaller inputs on script boundaries
@@ -342,6 +585,92 @@ func splitByScript(inputs []shaping.Input, documentDir di.Direction, buf []shapi
 	return splitInputs
 }
 
+// splitByOrientation divides the inputs of vertical text into upright and
+// sideways inputs according to the vertical orientation of their runes, and
+// sets the direction of the upright inputs. It will use buf as the backing
+// memory for the returned slice.
+func splitByOrientation(inputs []shaping.Input, buf []shaping.Input) []shaping.Input {
+	splitInputs := buf
+	for _, input := range inputs {
+		vo := unicodedata.LookupVerticalOrientation(input.Script)
+		currentInput := input
+		sideways := true
+		for i := input.RunStart; i < input.RunEnd; i++ {
+			s := vo.Orientation(input.Text[i])
+			if i == input.RunStart {
+				sideways = s
+				continue
+			}
+			if s != sideways {
+				currentInput.RunEnd = i
+				splitInputs = append(splitInputs, orient(currentInput, sideways))
+				currentInput = input
+				currentInput.RunStart = i
+				sideways = s
+			}
+		}
+		currentInput.RunEnd = input.RunEnd
+		splitInputs = append(splitInputs, orient(currentInput, sideways))
+	}
+	return splitInputs
+}
+
+// orient returns input shaped top-to-bottom if it is not sideways.
+func orient(input shaping.Input, sideways bool) shaping.Input {
+	if !sideways {
+		input.Direction = di.DirectionTTB
+		input.Direction.SetSideways(false)
+	}
+	return input
+}
+
+// uprightDirection marks runs of vertical text shaped upright. It is
+// horizontal, because vertical text is laid out horizontally and rotated
+// for display.
+var uprightDirection = func() di.Direction {
+	d := di.DirectionTTB
+	d.SetSideways(false)
+	return d.SwitchAxis()
+}()
+
+// uprightOutput converts the glyphs of out, shaped top-to-bottom, to the
+// horizontal layout of vertical text, where the glyphs are rotated
+// counter-clockwise. The glyphs are centered on the middle of the line
+// bounds of the horizontal font metrics, so that they align with sideways
+// glyphs.
+func uprightOutput(out *shaping.Output) {
+	ext, _ := out.Face.FontHExtents()
+	asc, desc := out.FromFontUnit(ext.Ascender), out.FromFontUnit(ext.Descender)
+	mid := (asc + desc) / 2
+	for i, g := range out.Glyphs {
+		// The offsets are subtracted from the dot when drawing. Unlike for
+		// horizontal text, they are significant and are included in the
+		// bounds.
+		xOff, yOff := g.YOffset, g.XOffset+mid
+		out.Glyphs[i] = shaping.Glyph{
+			XAdvance:     -g.YAdvance,
+			XOffset:      xOff,
+			YOffset:      yOff,
+			XBearing:     -g.YBearing - xOff,
+			YBearing:     g.XBearing + g.Width + yOff,
+			Width:        -g.Height,
+			Height:       -g.Width,
+			ClusterIndex: g.ClusterIndex,
+			RuneCount:    g.RuneCount,
+			GlyphCount:   g.GlyphCount,
+			GlyphID:      g.GlyphID,
+			Mask:         g.Mask,
+		}
+	}
+	out.Direction = di.DirectionLTR
+	out.LineBounds = shaping.Bounds{
+		Ascent:  asc,
+		Descent: desc,
+		Gap:     out.FromFontUnit(ext.LineGap),
+	}
+	out.RecalculateAll()
+}
+
 func (s *shaperImpl) splitBidi(input shaping.Input) []shaping.Input {
 	var splitInputs []shaping.Input
 	if input.Direction.Axis() != di.Horizontal || input.RunStart == input.RunEnd {
@@ -377,19 +706,91 @@ func (s *shaperImpl) splitBidi(input shaping.Input) []shaping.Input {
 // field and ensuring that any faces loaded as part of the search are registered with
 // ids so that they can be referred to by a GlyphID.
 func (s *shaperImpl) ResolveFace(r rune) *font.Face {
-	face := s.fontMap.ResolveFace(r)
+	face := s.resolveFace(r)
 	if face != nil {
-		family, aspect := s.fontMap.FontMetadata(face.Font)
-		md := opentype.DescriptionToFont(font.Description{
-			Family: family,
-			Aspect: aspect,
-		})
-		s.addFace(face, md)
+		if _, ok := s.faceToIndex[face.Font]; !ok {
+			family, aspect := s.fontMap.FontMetadata(face.Font)
+			md := opentype.DescriptionToFont(font.Description{
+				Family: family,
+				Aspect: aspect,
+			})
+			s.addFace(face, md, s.loadColorGlyphs(face.Font))
+		}
 		return face
 	}
 	return nil
 }
 
+// resolveFace resolves the face for r, trying the typefaces of the text font
+// first and then the fallback typefaces for the script of r and the text
+// language.
+func (s *shaperImpl) resolveFace(r rune) *font.Face {
+	families := append(s.scratchFamilies[:0], s.query.Families...)
+	script := language.LookupScript(r)
+	for _, f := range s.fallbacks {
+		if f.matches(script, s.lang) {
+			families = append(families, f.families...)
+		}
+	}
+	s.scratchFamilies = families
+	if len(families) == len(s.query.Families) {
+		return s.fontMap.ResolveFace(r)
+	}
+	q := s.query
+	q.Families = families
+	s.fontMap.SetQuery(q)
+	face := s.fontMap.ResolveFace(r)
+	s.fontMap.SetQuery(s.query)
+	return face
+}
+
+// loadColorGlyphs loads the color glyphs of a system font. Fonts parsed by
+// package opentype carry their color glyphs.
+func (s *shaperImpl) loadColorGlyphs(f *font.Font) *colr.Font {
+	loc := s.fontMap.FontLocation(f)
+	file, err := os.Open(loc.File)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	lds, err := gotextot.NewLoaders(file)
+	if err != nil || int(loc.Index) >= len(lds) {
+		return nil
+	}
+	c, err := colr.Load(lds[loc.Index])
+	if err != nil {
+		s.logger.Printf("failed loading color glyphs of %s: %v", loc.File, err)
+		return nil
+	}
+	return c
+}
+
+// splitBySpans divides the inputs on span boundaries, applies the size of each
+// span and divides the result by font coverage in the faces matching the
+// span font. It will use the slice provided in buf as the backing storage of
+// the returned slice if buf is non-nil.
+func (s *shaperImpl) splitBySpans(inputs []shaping.Input, spans []spanStyle, buf []shaping.Input) []shaping.Input {
+	split := buf
+	start := 0
+	for _, sp := range spans {
+		end := start + sp.runes
+		if end > start {
+			s.setQuery(sp.font)
+		}
+		for _, input := range inputs {
+			input.RunStart = max(input.RunStart, start)
+			input.RunEnd = min(input.RunEnd, end)
+			if input.RunStart >= input.RunEnd {
+				continue
+			}
+			input.Size = sp.ppem
+			split = append(split, shaping.SplitByFace(input, s)...)
+		}
+		start = end
+	}
+	return split
+}
+
 // splitByFaces divides the inputs by font coverage in the provided faces. It will use the slice provided in buf
 // as the backing storage of the returned slice if buf is non-nil.
 func (s *shaperImpl) splitByFaces(inputs []shaping.Input, buf []shaping.Input) []shaping.Input {
@@ -406,12 +807,19 @@ func (s *shaperImpl) splitByFaces(inputs []shaping.Input, buf []shaping.Input) [
 }
 
 // shapeText invokes the text shaper and returns the raw text data in the shaper's native
-// format. It does not wrap lines.
-func (s *shaperImpl) shapeText(ppem fixed.Int26_6, lc system.Locale, txt []rune) []shaping.Output {
+// format. It does not wrap lines. If spans is non-empty, the text is shaped with
+// the font and size of each span. Otherwise, it is shaped with ppem and the faces
+// of the current font map query.
+func (s *shaperImpl) shapeText(ppem fixed.Int26_6, lc system.Locale, txt []rune, spans []spanStyle) []shaping.Output {
 	lcfg := langConfig{
 		Language:  language.NewLanguage(lc.Language),
 		Direction: mapDirection(lc.Direction),
 	}
+	s.lang = lcfg.Language
+	if len(spans) > 0 {
+		// Empty text takes the size of the first span.
+		ppem = spans[0].ppem
+	}
 	// Create an initial input.
 	input := toInput(nil, ppem, lcfg, txt)
 	if input.RunStart == input.RunEnd && len(s.faces) > 0 {
@@ -422,16 +830,33 @@ func (s *shaperImpl) shapeText(ppem fixed.Int26_6, lc system.Locale, txt []rune)
 	}
 	// Break input on font glyph coverage.
 	inputs := s.splitBidi(input)
-	inputs = s.splitByFaces(inputs, s.splitScratch1[:0])
+	if len(spans) > 0 && len(txt) > 0 {
+		inputs = s.splitBySpans(inputs, spans, s.splitScratch1[:0])
+	} else {
+		inputs = s.splitByFaces(inputs, s.splitScratch1[:0])
+	}
 	inputs = splitByScript(inputs, lcfg.Direction, s.splitScratch2[:0])
+	if lc.Direction.Axis() == system.Vertical {
+		inputs = splitByOrientation(inputs, s.splitScratch1[:0])
+	}
+	for i := range inputs {
+		inputs[i].Face = s.variedFace(inputs[i].Face)
+		inputs[i].FontFeatures = s.features
+	}
 	// Shape all inputs.
 	if needed := len(inputs) - len(s.outScratchBuf); needed > 0 {
 		s.outScratchBuf = slices.Grow(s.outScratchBuf, needed)
 	}
 	s.outScratchBuf = s.outScratchBuf[:0]
+	s.upright = s.upright[:0]
 	for _, input := range inputs {
 		if input.Face != nil {
-			s.outScratchBuf = append(s.outScratchBuf, s.shaper.Shape(input))
+			out := s.shaper.Shape(input)
+			if out.Direction.IsVertical() {
+				uprightOutput(&out)
+				s.upright = append(s.upright, Range{Offset: input.RunStart, Count: input.RunEnd - input.RunStart})
+			}
+			s.outScratchBuf = append(s.outScratchBuf, out)
 		} else {
 			s.outScratchBuf = append(s.outScratchBuf, shaping.Output{
 				// Use the text size as the advance of the entire fake run so that
@@ -489,36 +914,258 @@ func wrapPolicyToGoText(p WrapPolicy) shaping.LineBreakPolicy {
 
 // shapeAndWrapText invokes the text shaper and returns wrapped lines in the shaper's native format.
 func (s *shaperImpl) shapeAndWrapText(params Parameters, txt []rune) (_ []shaping.Line, truncated int) {
-	wc := shaping.WrapConfig{
-		Direction:                     mapDirection(params.Locale.Direction),
-		TruncateAfterLines:            params.MaxLines,
-		TextContinues:                 params.forceTruncate,
-		BreakPolicy:                   wrapPolicyToGoText(params.WrapPolicy),
-		DisableTrailingWhitespaceTrim: params.DisableSpaceTrim,
-	}
+	return s.shapeAndWrapSpans(params, txt, nil)
+}
+
+// setQuery configures the font map to resolve faces matching f.
+func (s *shaperImpl) setQuery(f giofont.Font) {
 	families := s.defaultFaces
-	if params.Font.Typeface != "" {
-		parsed, err := s.parser.parse(string(params.Font.Typeface))
+	if f.Typeface != "" {
+		parsed, err := s.parser.parse(string(f.Typeface))
 		if err != nil {
-			s.logger.Printf("Unable to parse typeface %q: %v", params.Font.Typeface, err)
+			s.logger.Printf("Unable to parse typeface %q: %v", f.Typeface, err)
 		} else {
 			families = parsed
 		}
 	}
-	s.fontMap.SetQuery(fontscan.Query{
+	s.query = fontscan.Query{
 		Families: families,
-		Aspect:   opentype.FontToDescription(params.Font).Aspect,
-	})
+		Aspect:   opentype.FontToDescription(f).Aspect,
+	}
+	s.fontMap.SetQuery(s.query)
+}
+
+// shapeAndWrapSpans is like shapeAndWrapText, but shapes the text with the
+// styles of spans, if any.
+func (s *shaperImpl) shapeAndWrapSpans(params Parameters, txt []rune, spans []spanStyle) (_ []shaping.Line, truncated int) {
+	wc := shaping.WrapConfig{
+		Direction:                     mapDirection(params.Locale.Direction),
+		TruncateAfterLines:            params.MaxLines,
+		TextContinues:                 params.forceTruncate,
+		BreakPolicy:                   wrapPolicyToGoText(params.WrapPolicy),
+		DisableTrailingWhitespaceTrim: params.DisableSpaceTrim,
+	}
+	s.setQuery(params.Font)
+	s.setFontSettings(params.Features, params.Variations)
+	truncatorUpright := false
 	if wc.TruncateAfterLines > 0 {
 		if len(params.Truncator) == 0 {
 			params.Truncator = "…"
 		}
 		// We only permit a single run as the truncator, regardless of whether more were generated.
 		// Just use the first one.
-		wc.Truncator = s.shapeText(params.PxPerEm, params.Locale, []rune(params.Truncator))[0]
+		wc.Truncator = s.shapeText(params.PxPerEm, params.Locale, []rune(params.Truncator), nil)[0]
+		truncatorUpright = len(s.upright) > 0 && s.upright[0].Offset == 0
+	}
+	// Break words at hyphenation points by inserting soft hyphens.
+	origTxt := txt
+	hyphenated := false
+	if params.WrapPolicy == WrapHyphenate {
+		if p := hyphen.Lookup(params.Locale.Language); p != nil {
+			spans, hyphenated = s.hyphens.hyphenate(p, txt, spans)
+		}
+	}
+	if hyphenated {
+		txt = s.hyphens.txt
+	}
+	outs := s.shapeText(params.PxPerEm, params.Locale, txt, spans)
+	if params.LetterSpacing != 0 || params.WordSpacing != 0 {
+		shaping.AddSpacing(outs, txt, params.WordSpacing, params.LetterSpacing)
+	}
+	rtl := wc.Direction.Progression() == di.TowardTopLeft
+	if params.hasTabStops() {
+		// Position the tabs as if the paragraph fits a single line, which
+		// is exact for the first line. The logical order of the runs
+		// approximates their order from the start of the line.
+		runs := make([]*shaping.Output, len(outs))
+		for i := range outs {
+			runs[i] = &outs[i]
+		}
+		positionTabs(params, txt, runs, rtl, true)
 	}
 	// Wrap outputs into lines.
-	return s.wrapper.WrapParagraph(wc, params.MaxWidth, txt, shaping.NewSliceIterator(s.shapeText(params.PxPerEm, params.Locale, txt)))
+	var lines []shaping.Line
+	if hyphenated {
+		lines, truncated = s.hyphens.wrap(&s.wrapper, wc, params.MaxWidth, outs)
+	} else {
+		lines, truncated = s.wrapper.WrapParagraph(wc, params.MaxWidth, txt, shaping.NewSliceIterator(outs))
+	}
+	// The last run of the final line is the truncator, if any.
+	hasTruncator := truncated > 0 || params.forceTruncate && params.MaxLines == len(lines)
+	if params.Locale.Direction.Axis() == system.Vertical {
+		s.markUpright(lines, hasTruncator, truncatorUpright)
+	}
+	if params.hasTabStops() && len(lines) > 1 {
+		// Tab stops are measured from the start of each wrapped line.
+		var runs []*shaping.Output
+		for _, l := range lines[1:] {
+			runs = slices.Grow(runs[:0], len(l))[:len(l)]
+			for i := range l {
+				idx := int(l[i].VisualIndex)
+				if rtl {
+					idx = len(l) - 1 - idx
+				}
+				runs[idx] = &l[i]
+			}
+			positionTabs(params, txt, runs, rtl, false)
+		}
+	}
+	if hyphenated {
+		truncated = s.hyphens.finish(lines, truncated, hasTruncator)
+		txt = origTxt
+	}
+	if params.Alignment == Justify && len(lines) > 1 {
+		// The last line of the paragraph is not justified.
+		for _, l := range lines[:len(lines)-1] {
+			justifyLine(l, txt, fixed.I(params.MaxWidth))
+		}
+	}
+	return lines, truncated
+}
+
+// markUpright sets the direction of the wrapped runs of vertical text that
+// were shaped upright.
+func (s *shaperImpl) markUpright(lines []shaping.Line, hasTruncator, truncatorUpright bool) {
+	for i, l := range lines {
+		for j := range l {
+			run := &l[j]
+			upright := false
+			if hasTruncator && i == len(lines)-1 && j == len(l)-1 {
+				upright = truncatorUpright
+			} else {
+				for _, r := range s.upright {
+					if r.Offset <= run.Runes.Offset && run.Runes.Offset < r.Offset+r.Count {
+						upright = true
+						break
+					}
+				}
+			}
+			if upright {
+				run.Direction = uprightDirection
+			}
+		}
+	}
+}
+
+// justifyLine widens the word separators of l to stretch it to width. If
+// the line has no word separators, the space is added between its clusters
+// instead. Trailing whitespace is not widened.
+func justifyLine(l shaping.Line, txt []rune, width fixed.Int26_6) {
+	start, end := len(txt), 0
+	for _, run := range l {
+		start = min(start, run.Runes.Offset)
+		end = max(end, run.Runes.Offset+run.Runes.Count)
+	}
+	trailing := end
+	for trailing > start && unicode.IsSpace(txt[trailing-1]) {
+		trailing--
+	}
+	// Padding widens the space after a glyph, so the visually last cluster
+	// is not padded when the space goes between clusters.
+	last, lastRun := -1, -1
+	for _, run := range l {
+		if int(run.VisualIndex) < lastRun {
+			continue
+		}
+		for _, g := range run.Glyphs {
+			if g.ClusterIndex < trailing {
+				last, lastRun = g.ClusterIndex, int(run.VisualIndex)
+			}
+		}
+	}
+	var lineWidth fixed.Int26_6
+	words, clusters := 0, 0
+	for _, run := range l {
+		for j, g := range run.Glyphs {
+			if g.ClusterIndex >= trailing {
+				continue
+			}
+			lineWidth += g.XAdvance
+			// Glyphs of a cluster are adjacent.
+			if j > 0 && run.Glyphs[j-1].ClusterIndex == g.ClusterIndex {
+				continue
+			}
+			if isWordSeparator(txt[g.ClusterIndex]) {
+				words++
+			}
+			if g.ClusterIndex != last {
+				clusters++
+			}
+		}
+	}
+	n := words
+	if n == 0 {
+		n = clusters
+	}
+	extra := width - lineWidth
+	if n == 0 || extra <= 0 {
+		return
+	}
+	// Distribute the remainder of the division over the first gaps.
+	gap, rem := extra/fixed.Int26_6(n), extra%fixed.Int26_6(n)
+	for i := range l {
+		run := &l[i]
+		for j, g := range run.Glyphs {
+			if g.ClusterIndex >= trailing || j > 0 && run.Glyphs[j-1].ClusterIndex == g.ClusterIndex {
+				continue
+			}
+			if words > 0 && !isWordSeparator(txt[g.ClusterIndex]) || words == 0 && g.ClusterIndex == last {
+				continue
+			}
+			run.Glyphs[j].XAdvance += gap
+			if rem > 0 {
+				run.Glyphs[j].XAdvance++
+				rem--
+			}
+		}
+		run.RecomputeAdvance()
+	}
+}
+
+// isWordSeparator reports whether r separates words.
+func isWordSeparator(r rune) bool {
+	switch r {
+	case ' ', '\u00A0', '\u1361', '\U00010100', '\U00010101', '\U0001039F', '\U0001091F':
+		return true
+	}
+	return false
+}
+
+// positionTabs extends the tab glyphs of the runs to the next tab stop of
+// params, measured from the start of the line. The runs are in the order
+// from the start of the line, and their glyphs are iterated in reverse for
+// right-to-left lines. If replace is set, the tab glyphs are also replaced by
+// the invisible space glyph of their font.
+func positionTabs(params Parameters, txt []rune, runs []*shaping.Output, rtl bool, replace bool) {
+	var pen fixed.Int26_6
+	for _, run := range runs {
+		changed := false
+		for j := range run.Glyphs {
+			if rtl {
+				j = len(run.Glyphs) - 1 - j
+			}
+			g := &run.Glyphs[j]
+			isTab := g.RuneCount == 1 && g.GlyphCount == 1 && txt[g.ClusterIndex] == '\t'
+			// Skip tabs trimmed from the end of a line.
+			if isTab && (replace || g.XAdvance > 0) {
+				if replace && run.Face != nil {
+					if gid, ok := run.Face.NominalGlyph(' '); ok {
+						g.GlyphID = gid
+					}
+					g.Width, g.Height, g.XBearing, g.YBearing = 0, 0, 0, 0
+					g.XOffset, g.YOffset = 0, 0
+				}
+				if stop, ok := params.tabStop(pen); ok {
+					g.XAdvance = stop - pen
+					changed = true
+				}
+			}
+			pen += g.XAdvance
+		}
+		if changed {
+			run.RecomputeAdvance()
+		}
+	}
 }
 
 // replaceControlCharacters replaces problematic unicode
@@ -577,6 +1224,12 @@ func calculateYOffsets(lines []line) {
 
 // LayoutRunes shapes and wraps the text, and returns the result in Gio's shaped text format.
 func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
+	return s.LayoutSpans(params, txt, nil)
+}
+
+// LayoutSpans is like LayoutRunes, but shapes the runes of each span with
+// its font and size. The runes of spans must add up to the length of txt.
+func (s *shaperImpl) LayoutSpans(params Parameters, txt []rune, spans []spanStyle) document {
 	hasNewline := len(txt) > 0 && txt[len(txt)-1] == '\n'
 	var ls []shaping.Line
 	var truncated int
@@ -588,7 +1241,7 @@ func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
 		// on the final line (if we hit the limit).
 		params.forceTruncate = true
 	}
-	ls, truncated = s.shapeAndWrapText(params, replaceControlCharacters(txt))
+	ls, truncated = s.shapeAndWrapSpans(params, replaceControlCharacters(txt), spans)
 
 	hasTruncator := truncated > 0 || (params.forceTruncate && params.MaxLines == len(ls))
 	if hasTruncator && hasNewline {
@@ -601,8 +1254,15 @@ func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
 	// Convert to Lines.
 	textLines := make([]line, len(ls))
 	maxHeight := fixed.Int26_6(0)
+	// runeOffset is the offset of the first rune of each line.
+	runeOffset := 0
 	for i := range ls {
-		otLine := toLine(s.faceToIndex, ls[i], params.Locale.Direction)
+		otLine := toLine(s.faceIndex, ls[i], params.Locale.Direction)
+		if len(spans) > 0 {
+			for j := range otLine.runs {
+				otLine.runs[j].span = spanAt(spans, ls[i][j].Runes.Offset)
+			}
+		}
 		if otLine.lineHeight > maxHeight {
 			maxHeight = otLine.lineHeight
 		}
@@ -612,8 +1272,18 @@ func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
 			}
 			if hasTruncator {
 				otLine.setTruncatedCount(truncated)
+				if len(spans) > 0 {
+					// The truncator takes the style of the first truncated rune.
+					last := len(otLine.runs) - 1
+					offset := runeOffset
+					for _, r := range otLine.runs[:last] {
+						offset += r.Runes.Count
+					}
+					otLine.runs[last].span = spanAt(spans, offset)
+				}
 			}
 		}
+		runeOffset += otLine.runeCount
 		textLines[i] = otLine
 	}
 	if params.LineHeight != 0 {
@@ -662,15 +1332,26 @@ func (s *shaperImpl) Shape(pathOps *op.Ops, gs []Glyph) clip.PathSpec {
 			continue
 		}
 		scaleFactor := fixedToFloat(ppem) / float32(face.Upem())
+		if layers := s.layers(faceIdx, gid); len(layers) > 0 {
+			// The path of a color glyph covers its layers painted with
+			// the text color. A path can't intersect outlines, so the
+			// innermost clip bounds those layers and their alpha is
+			// ignored. Bitmaps paints the other layers.
+			origin := glyphOrigin(g, x)
+			for _, l := range layers {
+				if l.Paint.Foreground {
+					colorOutline(&builder, face, l.Clips[len(l.Clips)-1], origin, scaleFactor, g.Flags&FlagUpright != 0)
+				}
+			}
+			lastPos = builder.Pos()
+			continue
+		}
 		glyphData := face.GlyphData(gid)
 		switch glyphData := glyphData.(type) {
 		case font.GlyphOutline:
 			outline := glyphData
 			// Move to glyph position.
-			pos := f32.Point{
-				X: fixedToFloat((g.X - x) - g.Offset.X),
-				Y: -fixedToFloat(g.Offset.Y),
-			}
+			pos := glyphOrigin(g, x)
 			builder.Move(pos.Sub(lastPos))
 			lastPos = pos
 			var lastArg f32.Point
@@ -686,10 +1367,7 @@ func (s *shaperImpl) Shape(pathOps *op.Ops, gs []Glyph) clip.PathSpec {
 				}
 				var args [3]f32.Point
 				for i := range nargs {
-					a := f32.Point{
-						X: fseg.Args[i].X * scaleFactor,
-						Y: -fseg.Args[i].Y * scaleFactor,
-					}
+					a := outlinePoint(fseg.Args[i], scaleFactor, g.Flags&FlagUpright != 0)
 					args[i] = a.Sub(lastArg)
 					if i == nargs-1 {
 						lastArg = a
@@ -714,6 +1392,304 @@ func (s *shaperImpl) Shape(pathOps *op.Ops, gs []Glyph) clip.PathSpec {
 	return builder.End()
 }
 
+// glyphOrigin returns the origin of g in the coordinates of Shape for a line
+// starting at x.
+func glyphOrigin(g Glyph, x fixed.Int26_6) f32.Point {
+	return f32.Point{
+		X: fixedToFloat((g.X - x) - g.Offset.X),
+		Y: -fixedToFloat(g.Offset.Y),
+	}
+}
+
+// layers returns the layers of gid if it is a color glyph of the face with
+// index faceIdx. The returned slice is valid until the next call.
+func (s *shaperImpl) layers(faceIdx int, gid font.GID) []colr.Layer {
+	c := s.colorFonts[faceIdx]
+	if c == nil {
+		return nil
+	}
+	s.colorLayers = c.Layers(gid, s.colorLayers[:0])
+	return s.colorLayers
+}
+
+// colorOutline adds the outline of a clip of a color glyph layer at origin
+// to builder.
+func colorOutline(builder *clip.Path, face *font.Face, c colr.Clip, origin f32.Point, scale float32, upright bool) {
+	outline, ok := face.GlyphData(c.Glyph).(font.GlyphOutline)
+	if !ok {
+		return
+	}
+	for _, seg := range outline.Segments {
+		var args [3]f32.Point
+		for i, a := range seg.ArgsSlice() {
+			args[i] = origin.Add(colorPoint(c.Transform, f32.Pt(a.X, a.Y), scale, upright))
+		}
+		switch seg.Op {
+		case gotextot.SegmentOpMoveTo:
+			builder.MoveTo(args[0])
+		case gotextot.SegmentOpLineTo:
+			builder.LineTo(args[0])
+		case gotextot.SegmentOpQuadTo:
+			builder.QuadTo(args[0], args[1])
+		case gotextot.SegmentOpCubeTo:
+			builder.CubeTo(args[0], args[1], args[2])
+		}
+	}
+}
+
+// colorPoint converts a point of a color glyph layer in font units to the
+// coordinates of Shape relative to the glyph origin.
+func colorPoint(tr f32.Affine2D, p f32.Point, scale float32, upright bool) f32.Point {
+	p = tr.Transform(p)
+	return outlinePoint(gotextot.SegmentPoint{X: p.X, Y: p.Y}, scale, upright)
+}
+
+// outlinePoint converts a point of a glyph outline in font units to the
+// coordinates of Shape relative to the glyph origin. Upright glyphs of
+// vertical text are rotated counter-clockwise.
+func outlinePoint(p gotextot.SegmentPoint, scale float32, upright bool) f32.Point {
+	if upright {
+		return f32.Point{X: -p.Y * scale, Y: -p.X * scale}
+	}
+	return f32.Point{X: p.X * scale, Y: -p.Y * scale}
+}
+
+// decorationRect is the extent of a part of a decoration line, in the
+// coordinates of Shape.
+type decorationRect struct {
+	x0, x1 float32
+	y0, y1 float32
+}
+
+// Decorations converts the decorations d of the provided glyphs into a path
+// that aligns with the path returned by Shape. The position and thickness of
+// the lines are taken from the font of each glyph. Underlines are interrupted
+// where glyph outlines cross them.
+func (s *shaperImpl) Decorations(pathOps *op.Ops, gs []Glyph, d Decoration) clip.PathSpec {
+	var builder clip.Path
+	builder.Begin(pathOps)
+	for _, r := range s.decorationRects(gs, d) {
+		builder.MoveTo(f32.Pt(r.x0, r.y0))
+		builder.LineTo(f32.Pt(r.x1, r.y0))
+		builder.LineTo(f32.Pt(r.x1, r.y1))
+		builder.LineTo(f32.Pt(r.x0, r.y1))
+		builder.Close()
+	}
+	return builder.End()
+}
+
+// decorationRects returns the rectangles making up the decorations d of gs.
+func (s *shaperImpl) decorationRects(gs []Glyph, d Decoration) []decorationRect {
+	var rects []decorationRect
+	for _, kind := range []Decoration{Underline, Strikethrough} {
+		if d&kind == 0 || len(gs) == 0 {
+			continue
+		}
+		start := len(rects)
+		x := gs[0].X
+		for _, g := range gs {
+			if g.Advance == 0 {
+				continue
+			}
+			ppem, faceIdx, _ := splitGlyphID(g.ID)
+			pos, thickness := s.decorationMetrics(faceIdx, ppem, kind)
+			r := decorationRect{
+				x0: fixedToFloat(g.X - x),
+				y0: -pos,
+				y1: -pos + thickness,
+			}
+			r.x1 = r.x0 + fixedToFloat(g.Advance)
+			// Merge with the previous rectangle if they are adjacent, in
+			// either direction to cover right-to-left runs.
+			if n := len(rects); n > start {
+				prev := &rects[n-1]
+				if prev.y0 == r.y0 && prev.y1 == r.y1 {
+					if prev.x1 == r.x0 {
+						prev.x1 = r.x1
+						continue
+					}
+					if prev.x0 == r.x1 {
+						prev.x0 = r.x0
+						continue
+					}
+				}
+			}
+			rects = append(rects, r)
+		}
+		if kind == Underline {
+			rects = append(rects[:start], s.skipInk(gs, rects[start:])...)
+		}
+	}
+	return rects
+}
+
+// decorationMetrics returns the distance from the baseline up to the top of
+// the decoration line and its thickness, in pixels. Sensible defaults are
+// returned for faces without the metrics.
+func (s *shaperImpl) decorationMetrics(faceIdx int, ppem fixed.Int26_6, kind Decoration) (pos, thickness float32) {
+	size := fixedToFloat(ppem)
+	switch kind {
+	case Underline:
+		pos, thickness = -size*.1, size/16
+	case Strikethrough:
+		pos, thickness = size*.3, size/16
+	}
+	if faceIdx >= len(s.faces) || s.faces[faceIdx] == nil {
+		return pos, thickness
+	}
+	face := s.faces[faceIdx]
+	scale := size / float32(face.Upem())
+	posMetric, thicknessMetric := font.UnderlinePosition, font.UnderlineThickness
+	if kind == Strikethrough {
+		posMetric, thicknessMetric = font.StrikethroughPosition, font.StrikethroughThickness
+	}
+	if t := face.LineMetric(thicknessMetric) * scale; t > 0 {
+		thickness = t
+		pos = face.LineMetric(posMetric) * scale
+	}
+	return pos, thickness
+}
+
+// skipInk splits the underline rectangles of gs around the glyph outlines
+// crossing them, leaving a gap of the underline thickness on either side.
+func (s *shaperImpl) skipInk(gs []Glyph, rects []decorationRect) []decorationRect {
+	if len(rects) == 0 {
+		return rects
+	}
+	top, bottom, gap := rects[0].y0, rects[0].y1, float32(0)
+	for _, r := range rects {
+		top = min(top, r.y0)
+		if r.y1 > bottom {
+			bottom = r.y1
+		}
+		if t := r.y1 - r.y0; t > gap {
+			gap = t
+		}
+	}
+	var ink [][2]float32
+	x := gs[0].X
+	for _, g := range gs {
+		if x0, x1, ok := s.inkExtent(g, x, top-gap, bottom+gap); ok {
+			ink = append(ink, [2]float32{x0 - gap, x1 + gap})
+		}
+	}
+	out := make([]decorationRect, 0, len(rects))
+	for _, r := range rects {
+		parts := []decorationRect{r}
+		for _, in := range ink {
+			var next []decorationRect
+			for _, p := range parts {
+				if in[1] <= p.x0 || in[0] >= p.x1 {
+					next = append(next, p)
+					continue
+				}
+				if in[0] > p.x0 {
+					left := p
+					left.x1 = in[0]
+					next = append(next, left)
+				}
+				if in[1] < p.x1 {
+					right := p
+					right.x0 = in[1]
+					next = append(next, right)
+				}
+			}
+			parts = next
+		}
+		out = append(out, parts...)
+	}
+	return out
+}
+
+// inkExtent returns the horizontal extent of the outline of g between the
+// vertical positions top and bottom, in the coordinates of Shape for a line
+// starting at x.
+func (s *shaperImpl) inkExtent(g Glyph, x fixed.Int26_6, top, bottom float32) (x0, x1 float32, ok bool) {
+	ppem, faceIdx, gid := splitGlyphID(g.ID)
+	if faceIdx >= len(s.faces) || s.faces[faceIdx] == nil {
+		return 0, 0, false
+	}
+	face := s.faces[faceIdx]
+	outline, isOutline := face.GlyphData(gid).(font.GlyphOutline)
+	if !isOutline {
+		return 0, 0, false
+	}
+	scale := fixedToFloat(ppem) / float32(face.Upem())
+	origin := f32.Point{
+		X: fixedToFloat((g.X - x) - g.Offset.X),
+		Y: -fixedToFloat(g.Offset.Y),
+	}
+	x0, x1 = float32(math.Inf(+1)), float32(math.Inf(-1))
+	// add extends the extent by the part of the segment from p to q
+	// between top and bottom.
+	add := func(p, q f32.Point) {
+		t0, t1 := float32(0), float32(1)
+		if dy := q.Y - p.Y; dy != 0 {
+			t0, t1 = (top-p.Y)/dy, (bottom-p.Y)/dy
+			if t0 > t1 {
+				t0, t1 = t1, t0
+			}
+			if t0 < 0 {
+				t0 = 0
+			}
+			t1 = min(t1, 1)
+			if t0 > t1 {
+				return
+			}
+		} else if p.Y < top || p.Y > bottom {
+			return
+		}
+		for _, t := range [2]float32{t0, t1} {
+			px := p.X + (q.X-p.X)*t
+			x0 = min(x0, px)
+			if px > x1 {
+				x1 = px
+			}
+		}
+	}
+	pt := func(p gotextot.SegmentPoint) f32.Point {
+		return origin.Add(outlinePoint(p, scale, g.Flags&FlagUpright != 0))
+	}
+	// Curves are flattened into a fixed number of lines, which is precise
+	// enough for the purpose of skipping ink.
+	const steps = 8
+	var start, cur f32.Point
+	for _, seg := range outline.Segments {
+		switch seg.Op {
+		case gotextot.SegmentOpMoveTo:
+			add(cur, start)
+			start, cur = pt(seg.Args[0]), pt(seg.Args[0])
+		case gotextot.SegmentOpLineTo:
+			p := pt(seg.Args[0])
+			add(cur, p)
+			cur = p
+		case gotextot.SegmentOpQuadTo:
+			c, e := pt(seg.Args[0]), pt(seg.Args[1])
+			for i := 1; i <= steps; i++ {
+				t := float32(i) / steps
+				u := 1 - t
+				p := cur.Mul(u * u).Add(c.Mul(2 * u * t)).Add(e.Mul(t * t))
+				add(cur, p)
+				cur = p
+			}
+			cur = e
+		case gotextot.SegmentOpCubeTo:
+			c1, c2, e := pt(seg.Args[0]), pt(seg.Args[1]), pt(seg.Args[2])
+			from := cur
+			for i := 1; i <= steps; i++ {
+				t := float32(i) / steps
+				u := 1 - t
+				p := from.Mul(u * u * u).Add(c1.Mul(3 * u * u * t)).Add(c2.Mul(3 * u * t * t)).Add(e.Mul(t * t * t))
+				add(cur, p)
+				cur = p
+			}
+			cur = e
+		}
+	}
+	add(cur, start)
+	return x0, x1, x0 <= x1
+}
+
 func fixedToFloat(i fixed.Int26_6) float32 {
 	return float32(i) / 64.0
 }
@@ -722,7 +1698,8 @@ func floatToFixed(f float32) fixed.Int26_6 {
 	return fixed.Int26_6(f * 64)
 }
 
-// Bitmaps returns an op.CallOp that will display all bitmap glyphs within gs.
+// Bitmaps returns an op.CallOp that will display all bitmap glyphs within gs,
+// and the layers of color glyphs not painted with the text color.
 // The positioning of the bitmaps uses the same logic as Shape(), so the returned
 // CallOp can be added at the same offset as the path data returned by Shape()
 // and will align correctly.
@@ -733,7 +1710,7 @@ func (s *shaperImpl) Bitmaps(ops *op.Ops, gs []Glyph) op.CallOp {
 		if i == 0 {
 			x = g.X
 		}
-		_, faceIdx, gid := splitGlyphID(g.ID)
+		ppem, faceIdx, gid := splitGlyphID(g.ID)
 		if faceIdx >= len(s.faces) {
 			continue
 		}
@@ -741,6 +1718,11 @@ func (s *shaperImpl) Bitmaps(ops *op.Ops, gs []Glyph) op.CallOp {
 		if face == nil {
 			continue
 		}
+		if layers := s.layers(faceIdx, gid); len(layers) > 0 {
+			scale := fixedToFloat(ppem) / float32(face.Upem())
+			paintLayers(ops, face, layers, glyphOrigin(g, x), scale, g.Flags&FlagUpright != 0)
+			continue
+		}
 		glyphData := face.GlyphData(gid)
 		switch glyphData := glyphData.(type) {
 		case font.GlyphBitmap:
@@ -767,12 +1749,6 @@ func (s *shaperImpl) Bitmaps(ops *op.Ops, gs []Glyph) op.CallOp {
 				imgOp = bitmapData.img
 				imgSize = bitmapData.size
 			}
-			off := op.Affine(f32.Affine2D{}.Offset(f32.Point{
-				X: fixedToFloat((g.X - x) - g.Offset.X),
-				Y: fixedToFloat(g.Offset.Y + g.Bounds.Min.Y),
-			})).Push(ops)
-			cl := clip.Rect{Max: imgSize}.Push(ops)
-
 			glyphSize := image.Rectangle{
 				Min: image.Point{
 					X: g.Bounds.Min.X.Round(),
@@ -783,6 +1759,21 @@ func (s *shaperImpl) Bitmaps(ops *op.Ops, gs []Glyph) op.CallOp {
 					Y: g.Bounds.Max.Y.Round(),
 				},
 			}.Size()
+			imgTrans := f32.Affine2D{}.Offset(f32.Point{
+				X: fixedToFloat((g.X - x) - g.Offset.X),
+				Y: fixedToFloat(g.Offset.Y + g.Bounds.Min.Y),
+			})
+			if g.Flags&FlagUpright != 0 {
+				// Rotate the image counter-clockwise, with its top left
+				// corner at the bottom left of the bounds.
+				imgTrans = f32.NewAffine2D(
+					0, 1, fixedToFloat((g.X-x)+g.Bounds.Min.X),
+					-1, 0, fixedToFloat(g.Bounds.Max.Y),
+				)
+				glyphSize.X, glyphSize.Y = glyphSize.Y, glyphSize.X
+			}
+			off := op.Affine(imgTrans).Push(ops)
+			cl := clip.Rect{Max: imgSize}.Push(ops)
 			aff := op.Affine(f32.Affine2D{}.Scale(f32.Point{}, f32.Point{
 				X: float32(glyphSize.X) / float32(imgSize.X),
 				Y: float32(glyphSize.Y) / float32(imgSize.Y),
@@ -797,6 +1788,38 @@ func (s *shaperImpl) Bitmaps(ops *op.Ops, gs []Glyph) op.CallOp {
 	return bitmapMacro.Stop()
 }
 
+// paintLayers paints the layers of a color glyph at origin, except the layers
+// painted with the text color.
+func paintLayers(ops *op.Ops, face *font.Face, layers []colr.Layer, origin f32.Point, scale float32, upright bool) {
+	var clips []clip.Stack
+	for _, l := range layers {
+		if l.Paint.Foreground {
+			continue
+		}
+		clips = clips[:0]
+		for _, c := range l.Clips {
+			var p clip.Path
+			p.Begin(ops)
+			colorOutline(&p, face, c, origin, scale, upright)
+			clips = append(clips, clip.Outline{Path: p.End()}.Op().Push(ops))
+		}
+		if pt := l.Paint; pt.Gradient {
+			paint.LinearGradientOp{
+				Stop1:  origin.Add(colorPoint(pt.Transform, pt.Stop1, scale, upright)),
+				Color1: pt.Color,
+				Stop2:  origin.Add(colorPoint(pt.Transform, pt.Stop2, scale, upright)),
+				Color2: pt.Color2,
+			}.Add(ops)
+		} else {
+			paint.ColorOp{Color: pt.Color}.Add(ops)
+		}
+		paint.PaintOp{}.Add(ops)
+		for i := len(clips) - 1; i >= 0; i-- {
+			clips[i].Pop()
+		}
+	}
+}
+
 // langConfig describes the language and writing system of a body of text.
 type langConfig struct {
 	// Language the text is written in.
@@ -827,6 +1850,9 @@ func mapDirection(d system.TextDirection) di.Direction {
 		return di.DirectionLTR
 	case system.RTL:
 		return di.DirectionRTL
+	case system.TTB:
+		// Vertical text is laid out horizontally and rotated for display.
+		return di.DirectionLTR
 	}
 	return di.DirectionLTR
 }
@@ -837,6 +1863,8 @@ func unmapDirection(d di.Direction) system.TextDirection {
 		return system.LTR
 	case di.DirectionRTL:
 		return system.RTL
+	case uprightDirection:
+		return system.TTB
 	}
 	return system.LTR
 }
@@ -868,7 +1896,7 @@ func toGioGlyphs(in []shaping.Glyph, ppem fixed.Int26_6, faceIdx int) []glyph {
 }
 
 // toLine converts the output into a Line with the provided dominant text direction.
-func toLine(faceToIndex map[*font.Font]int, o shaping.Line, dir system.TextDirection) line {
+func toLine(faceIndex func(*font.Face) int, o shaping.Line, dir system.TextDirection) line {
 	if len(o) < 1 {
 		return line{}
 	}
@@ -883,12 +1911,8 @@ func toLine(faceToIndex map[*font.Font]int, o shaping.Line, dir system.TextDirec
 		if run.Size > maxSize {
 			maxSize = run.Size
 		}
-		var font *font.Font
-		if run.Face != nil {
-			font = run.Face.Font
-		}
 		line.runs[i] = runLayout{
-			Glyphs: toGioGlyphs(run.Glyphs, run.Size, faceToIndex[font]),
+			Glyphs: toGioGlyphs(run.Glyphs, run.Size, faceIndex(run.Face)),
 			Runes: Range{
 				Count:  run.Runes.Count,
 				Offset: line.runeCount,
@@ -384,7 +384,7 @@ func TestToLine(t *testing.T) {
 					totalInputGlyphs += len(run.Glyphs)
 					totalInputRunes += run.Runes.Count
 				}
-				output := toLine(shaper.faceToIndex, input, tc.dir)
+				output := toLine(shaper.faceIndex, input, tc.dir)
 				if output.direction != tc.dir {
 					t.Errorf("line %d: expected direction %v, got %v", i, tc.dir, output.direction)
 				}
@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+// Package de registers hyphenation patterns for German in the reformed
+// spelling of 1996. Import it for its side effect:
+//
+//	import _ "gioui.org/text/hyphen/de"
+//
+// Unlike the code of the package, the patterns are distributed under the
+// licence of their authors, in the LICENSE file of the package directory.
+package de
+
+import (
+	_ "embed"
+
+	"gioui.org/text/hyphen"
+)
+
+//go:embed patterns.txt
+var patterns string
+
+func init() {
+	p, err := hyphen.Parse(patterns, "")
+	if err != nil {
+		panic(err)
+	}
+	hyphen.Register("de", p)
+}
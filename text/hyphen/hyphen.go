@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+// Package hyphen implements Liang's hyphenation algorithm for patterns
+// in the format of TeX, such as the patterns of the hyph-utf8 project.
+//
+// Patterns are registered by language, and the text shaper hyphenates
+// text of a registered language when wrapping with text.WrapHyphenate.
+// The subpackages of hyphen register the patterns of their language when
+// imported, such as
+//
+//	import _ "gioui.org/text/hyphen/de"
+package hyphen
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Patterns is a compiled set of hyphenation patterns and exceptions.
+type Patterns struct {
+	// LeftMin and RightMin are the minimum number of runes before the
+	// first and after the last hyphen of a word.
+	LeftMin, RightMin int
+
+	// levels maps the letters of each pattern to the levels between them.
+	levels map[string][]uint8
+	// maxLen is the length in runes of the longest pattern.
+	maxLen int
+	// exceptions maps words to their hyphenation points.
+	exceptions map[string][]int
+}
+
+var registry struct {
+	mu       sync.Mutex
+	patterns map[string]*Patterns
+}
+
+// Parse compiles patterns and exceptions in TeX format. The patterns are
+// whitespace separated letters interleaved with level digits, such as
+// "hy3ph" or ".ach4", where a period matches the start or end of a word.
+// The exceptions are whitespace separated words hyphenated with '-', such
+// as "ta-ble". Lines starting with '%' are comments.
+func Parse(patterns, exceptions string) (*Patterns, error) {
+	p := &Patterns{
+		LeftMin:    2,
+		RightMin:   2,
+		levels:     make(map[string][]uint8),
+		exceptions: make(map[string][]int),
+	}
+	err := fields(patterns, func(f string) error {
+		var letters []rune
+		levels := []uint8{0}
+		for _, r := range f {
+			if '0' <= r && r <= '9' {
+				levels[len(levels)-1] = uint8(r - '0')
+				continue
+			}
+			letters = append(letters, unicode.ToLower(r))
+			levels = append(levels, 0)
+		}
+		if len(letters) == 0 {
+			return fmt.Errorf("hyphen: invalid pattern %q", f)
+		}
+		p.levels[string(letters)] = levels
+		p.maxLen = max(p.maxLen, len(letters))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = fields(exceptions, func(f string) error {
+		var word []rune
+		var points []int
+		for _, r := range f {
+			if r == '-' {
+				points = append(points, len(word))
+				continue
+			}
+			word = append(word, unicode.ToLower(r))
+		}
+		p.exceptions[string(word)] = points
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// fields calls f for each whitespace separated field of s outside
+// comments.
+func fields(s string, f func(field string) error) error {
+	sc := bufio.NewScanner(strings.NewReader(s))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '%'); i >= 0 {
+			line = line[:i]
+		}
+		for _, field := range strings.Fields(line) {
+			if err := f(field); err != nil {
+				return err
+			}
+		}
+	}
+	return sc.Err()
+}
+
+// Hyphenate appends the hyphenation points of word to points and returns
+// the result. A hyphenation point is the index of the rune following a
+// hyphen.
+func (p *Patterns) Hyphenate(word []rune, points []int) []int {
+	n := len(word)
+	if n < p.LeftMin+p.RightMin {
+		return points
+	}
+	key := make([]rune, 0, n+2)
+	key = append(key, '.')
+	for _, r := range word {
+		key = append(key, unicode.ToLower(r))
+	}
+	key = append(key, '.')
+	if ex, ok := p.exceptions[string(key[1:n+1])]; ok {
+		return append(points, ex...)
+	}
+	// levels[i] is the level between key[i-1] and key[i].
+	levels := make([]uint8, len(key)+1)
+	for start := range key {
+		for end := start + 1; end <= len(key) && end-start <= p.maxLen; end++ {
+			pat, ok := p.levels[string(key[start:end])]
+			if !ok {
+				continue
+			}
+			for i, l := range pat {
+				levels[start+i] = max(levels[start+i], l)
+			}
+		}
+	}
+	for i := p.LeftMin; i <= n-p.RightMin; i++ {
+		// Account for the leading period of key.
+		if levels[i+1]%2 == 1 {
+			points = append(points, i)
+		}
+	}
+	return points
+}
+
+// Register makes patterns available for the language lang, such as "de"
+// or "en-US", replacing any patterns previously registered for lang.
+func Register(lang string, p *Patterns) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if registry.patterns == nil {
+		registry.patterns = make(map[string]*Patterns)
+	}
+	registry.patterns[normalize(lang)] = p
+}
+
+// Lookup returns the patterns registered for lang, falling back to the
+// patterns of its primary language. For example, the patterns of "de"
+// are used for "de-CH" if there are no patterns for "de-CH".
+func Lookup(lang string) *Patterns {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	lang = normalize(lang)
+	for lang != "" {
+		if p, ok := registry.patterns[lang]; ok {
+			return p
+		}
+		i := strings.LastIndexByte(lang, '-')
+		if i == -1 {
+			break
+		}
+		lang = lang[:i]
+	}
+	return nil
+}
+
+func normalize(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package hyphen
+
+import (
+	"slices"
+	"testing"
+)
+
+// liang are the patterns that hyphenate "hyphenation" in Liang's thesis.
+const liang = `
+% Patterns from "Word Hy-phen-a-tion by Com-put-er".
+hy3ph he2n hena4 hen5at 1na n2at 1tio 2io o2n
+`
+
+func TestHyphenate(t *testing.T) {
+	p, err := Parse(liang, "ta-ble")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		word   string
+		points []int
+	}{
+		{"hyphenation", []int{2, 6}},
+		{"Hyphenation", []int{2, 6}},
+		{"table", []int{2}},
+		{"hen", nil},
+	} {
+		if got := p.Hyphenate([]rune(tc.word), nil); !slices.Equal(got, tc.points) {
+			t.Errorf("Hyphenate(%q) = %v, want %v", tc.word, got, tc.points)
+		}
+	}
+	// The "hy-phen" point is too close to the start.
+	p.LeftMin = 3
+	if got, want := p.Hyphenate([]rune("hyphenation"), nil), []int{6}; !slices.Equal(got, want) {
+		t.Errorf("Hyphenate with LeftMin 3 = %v, want %v", got, want)
+	}
+	if _, err := Parse("1 2", ""); err == nil {
+		t.Error("parsed a pattern without letters")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	p, err := Parse(liang, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Register("xx", p)
+	Register("xx-YY", p)
+	for _, lang := range []string{"xx", "XX-zz", "xx_YY"} {
+		if Lookup(lang) != p {
+			t.Errorf("no patterns for %q", lang)
+		}
+	}
+	if Lookup("yy") != nil {
+		t.Error("patterns for an unregistered language")
+	}
+}
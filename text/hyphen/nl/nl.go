@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+// Package nl registers hyphenation patterns for Dutch. Import it for its
+// side effect:
+//
+//	import _ "gioui.org/text/hyphen/nl"
+//
+// Unlike the code of the package, the patterns are distributed under the
+// licence of their authors, in the LICENSE file of the package directory.
+package nl
+
+import (
+	_ "embed"
+
+	"gioui.org/text/hyphen"
+)
+
+//go:embed patterns.txt
+var patterns string
+
+func init() {
+	p, err := hyphen.Parse(patterns, "")
+	if err != nil {
+		panic(err)
+	}
+	hyphen.Register("nl", p)
+}
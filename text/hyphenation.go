@@ -0,0 +1,273 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"unicode"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/image/math/fixed"
+
+	"gioui.org/text/hyphen"
+)
+
+// softHyphen marks a hyphenation point. It is invisible unless a line is
+// broken after it.
+const softHyphen = '\u00ad'
+
+// hyphenation tracks the soft hyphens inserted at the hyphenation points
+// of a paragraph, so that the line wrapper may break lines within words.
+type hyphenation struct {
+	// txt is the paragraph with soft hyphens.
+	txt []rune
+	// inserted is the number of soft hyphens before each rune of txt, with
+	// the total appended.
+	inserted []int
+	// outs is a copy of the shaped paragraph, and glyphs holds its glyphs.
+	outs   []shaping.Output
+	glyphs []shaping.Glyph
+	// widths are the widths of the wrapped lines, and lines the lines.
+	widths []int
+	lines  []shaping.Line
+}
+
+// hyphenate inserts soft hyphens at the hyphenation points of txt according
+// to the patterns p, and adjusts spans to cover them. It reports false if
+// there are no hyphenation points.
+func (h *hyphenation) hyphenate(p *hyphen.Patterns, txt []rune, spans []spanStyle) ([]spanStyle, bool) {
+	h.txt = h.txt[:0]
+	h.inserted = h.inserted[:0]
+	var points []int
+	// spanIdx and spanEnd track the span containing the current rune.
+	spanIdx, spanEnd := 0, 0
+	if len(spans) > 0 {
+		spans = append([]spanStyle(nil), spans...)
+		spanEnd = spans[0].runes
+	}
+	for start := 0; start < len(txt); {
+		end := start
+		for end < len(txt) && isWordRune(txt[end]) {
+			end++
+		}
+		if end == start {
+			end++
+		} else {
+			points = p.Hyphenate(txt[start:end], points[:0])
+		}
+		for i := start; i < end; i++ {
+			for len(spans) > 0 && i >= spanEnd && spanIdx < len(spans)-1 {
+				spanIdx++
+				spanEnd += spans[spanIdx].runes
+			}
+			if len(points) > 0 && points[0] == i-start {
+				points = points[1:]
+				h.inserted = append(h.inserted, len(h.txt)-i)
+				h.txt = append(h.txt, softHyphen)
+				if len(spans) > 0 {
+					// The hyphen takes the style of the rune before it.
+					spans[spanIdx].runes++
+					spanEnd++
+				}
+			}
+			h.inserted = append(h.inserted, len(h.txt)-i)
+			h.txt = append(h.txt, txt[i])
+		}
+		start = end
+	}
+	h.inserted = append(h.inserted, len(h.txt)-len(txt))
+	return spans, len(h.txt) > len(txt)
+}
+
+// isWordRune reports whether r is part of a hyphenatable word.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r)
+}
+
+// isInserted reports whether the rune at idx of the hyphenated text is a
+// soft hyphen inserted by hyphenate, as opposed to one of the original
+// text.
+func (h *hyphenation) isInserted(idx int) bool {
+	return h.inserted[idx+1] > h.inserted[idx]
+}
+
+// original maps the index of a rune in the hyphenated text to its index
+// in the original text.
+func (h *hyphenation) original(idx int) int {
+	return idx - h.inserted[idx]
+}
+
+// finish replaces the inserted soft hyphens that end the lines by visible
+// hyphens, removes the other inserted soft hyphens and maps the runes of
+// the lines back to the original text. The last run of the final line is
+// skipped if it is a truncator. It returns the number of truncated runes
+// of the original text.
+func (h *hyphenation) finish(lines []shaping.Line, truncated int, hasTruncator bool) int {
+	for i, l := range lines {
+		for j := range l {
+			run := &l[j]
+			if hasTruncator && i == len(lines)-1 && j == len(l)-1 {
+				break
+			}
+			lastRun := j == len(l)-1
+			changed := false
+			glyphs := run.Glyphs[:0]
+			for k, g := range run.Glyphs {
+				if g.ClusterIndex < len(h.txt) && h.isInserted(g.ClusterIndex) {
+					changed = true
+					lastGlyph := lastRun && k == len(run.Glyphs)-1 && i < len(lines)-1
+					if lastGlyph && len(glyphs) > 0 && run.Direction.Progression() != di.TowardTopLeft && h.toHyphen(run, &g, glyphs) {
+						glyphs = append(glyphs, g)
+					}
+					continue
+				}
+				g.ClusterIndex = h.original(g.ClusterIndex)
+				glyphs = append(glyphs, g)
+			}
+			run.Glyphs = glyphs
+			end := h.original(run.Runes.Offset + run.Runes.Count)
+			run.Runes.Offset = h.original(run.Runes.Offset)
+			run.Runes.Count = end - run.Runes.Offset
+			if changed {
+				run.RecalculateAll()
+			}
+		}
+	}
+	if truncated > 0 {
+		truncated = h.original(len(h.txt)) - h.original(len(h.txt)-truncated)
+	}
+	return truncated
+}
+
+// save copies the shaped paragraph outs, because the line wrapper trims
+// the advance of the blank glyphs that end lines in place.
+func (h *hyphenation) save(outs []shaping.Output) {
+	h.outs = append(h.outs[:0], outs...)
+	h.glyphs = h.glyphs[:0]
+	for _, out := range outs {
+		h.glyphs = append(h.glyphs, out.Glyphs...)
+	}
+	glyphs := h.glyphs
+	for i, out := range outs {
+		n := len(out.Glyphs)
+		h.outs[i].Glyphs, glyphs = glyphs[:n:n], glyphs[n:]
+	}
+}
+
+// restore copies the saved paragraph back to outs, to wrap it again.
+func (h *hyphenation) restore(outs []shaping.Output) {
+	for i := range outs {
+		copy(outs[i].Glyphs, h.outs[i].Glyphs)
+		outs[i].Advance = h.outs[i].Advance
+	}
+}
+
+// wrap wraps the hyphenated paragraph outs like WrapParagraph of w. Lines
+// that end in an inserted soft hyphen are narrowed if the hyphen that
+// replaces it wouldn't fit, and the other lines get the full maxWidth.
+// Every narrowed line wraps the paragraph again.
+func (h *hyphenation) wrap(w *shaping.LineWrapper, wc shaping.WrapConfig, maxWidth int, outs []shaping.Output) ([]shaping.Line, int) {
+	h.save(outs)
+	h.widths = h.widths[:0]
+	for {
+		w.Prepare(wc, h.txt, shaping.NewSliceIterator(outs))
+		h.lines = h.lines[:0]
+		truncated := 0
+		narrowed := false
+		for done := false; !done && !narrowed; {
+			width := maxWidth
+			if i := len(h.lines); i < len(h.widths) {
+				width = h.widths[i]
+			}
+			var line shaping.WrappedLine
+			line, done = w.WrapNextLine(width)
+			if line.Line == nil {
+				continue
+			}
+			truncated = line.Truncated
+			// A narrowed line overflows only if none of its breaks fit.
+			if adv, ok := h.hyphenOverflow(line.Line, width); ok && !done && truncated == 0 && width == maxWidth {
+				// Wrap again, with this line narrowed by the hyphen.
+				for len(h.widths) < len(h.lines) {
+					h.widths = append(h.widths, maxWidth)
+				}
+				h.widths = append(h.widths[:len(h.lines)], max(width-adv.Ceil(), 0))
+				narrowed = true
+				continue
+			}
+			h.lines = append(h.lines, line.Line)
+		}
+		if !narrowed {
+			return h.lines, truncated
+		}
+		h.restore(outs)
+	}
+}
+
+// hyphenOverflow reports whether l ends in an inserted soft hyphen, and
+// whether the hyphen that replaces it overflows maxWidth. It returns the
+// advance of the hyphen.
+func (h *hyphenation) hyphenOverflow(l shaping.Line, maxWidth int) (fixed.Int26_6, bool) {
+	if len(l) == 0 {
+		return 0, false
+	}
+	last := &l[len(l)-1]
+	idx := last.Runes.Offset + last.Runes.Count - 1
+	if idx < 0 || idx >= len(h.txt) || !h.isInserted(idx) || last.Direction.Progression() == di.TowardTopLeft {
+		return 0, false
+	}
+	adv, ok := hyphenAdvance(last)
+	if !ok {
+		return 0, false
+	}
+	var width fixed.Int26_6
+	for _, run := range l {
+		width += run.Advance
+	}
+	return adv, width+adv > fixed.I(maxWidth)
+}
+
+// hyphenAdvance returns the advance of the hyphen of the font of run.
+func hyphenAdvance(run *shaping.Output) (fixed.Int26_6, bool) {
+	if run.Face == nil {
+		return 0, false
+	}
+	gid, ok := run.Face.NominalGlyph('-')
+	if !ok {
+		return 0, false
+	}
+	return run.FromFontUnit(run.Face.HorizontalAdvance(gid)), true
+}
+
+// toHyphen converts the soft hyphen g that ends a line to a hyphen glyph
+// in the cluster of the glyphs before it. It reports false if the font of
+// the run has no hyphen.
+func (h *hyphenation) toHyphen(run *shaping.Output, g *shaping.Glyph, before []shaping.Glyph) bool {
+	face := run.Face
+	if face == nil {
+		return false
+	}
+	gid, ok := face.NominalGlyph('-')
+	if !ok {
+		return false
+	}
+	ext, _ := face.GlyphExtents(gid)
+	prev := before[len(before)-1]
+	*g = shaping.Glyph{
+		Width:    run.FromFontUnit(ext.Width),
+		Height:   run.FromFontUnit(ext.Height),
+		XBearing: run.FromFontUnit(ext.XBearing),
+		YBearing: run.FromFontUnit(ext.YBearing),
+		XAdvance: run.FromFontUnit(face.HorizontalAdvance(gid)),
+		GlyphID:  gid,
+		// Join the cluster before the hyphen, so the hyphen doesn't
+		// represent any runes.
+		ClusterIndex: prev.ClusterIndex,
+		RuneCount:    prev.RuneCount,
+		GlyphCount:   prev.GlyphCount + 1,
+	}
+	for i := len(before) - 1; i >= 0 && before[i].ClusterIndex == prev.ClusterIndex; i-- {
+		before[i].GlyphCount++
+	}
+	return true
+}
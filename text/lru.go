@@ -58,6 +58,11 @@ func (l *lru[K, V]) Put(k K, v V) {
 	}
 }
 
+// clear removes every entry.
+func (l *lru[K, V]) clear() {
+	l.m = nil
+}
+
 // remove cuts e out of the lru linked list.
 func (l *lru[K, V]) remove(e *entry[K, V]) {
 	e.next.prev = e.prev
@@ -140,6 +145,10 @@ func (c *glyphLRU[V]) Put(key uint64, glyphs []Glyph, v V) {
 	c.cache.Put(key, val)
 }
 
+func (c *glyphLRU[V]) clear() {
+	c.cache.clear()
+}
+
 type pathCache = glyphLRU[clip.PathSpec]
 
 type bitmapShapeCache = glyphLRU[op.CallOp]
@@ -161,6 +170,15 @@ type layoutKey struct {
 	wrapPolicy         WrapPolicy
 	lineHeight         fixed.Int26_6
 	lineHeightScale    float32
+	letterSpacing      fixed.Int26_6
+	wordSpacing        fixed.Int26_6
+	tabWidth           fixed.Int26_6
+	justify            bool
+	features           FontFeatures
+	variations         FontVariations
+	tabStops           TabStops
+	// spans encodes the styles of rich text spans, if any.
+	spans string
 }
 
 const maxSize = 1000
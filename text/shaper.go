@@ -4,7 +4,10 @@ package text
 
 import (
 	"bufio"
+	"encoding/binary"
+	"fmt"
 	"io"
+	"math"
 	"strings"
 	"unicode/utf8"
 
@@ -35,6 +38,14 @@ const (
 	// breaking any word across lines on UAX#29 grapheme cluster boundaries to maximize the number of
 	// grapheme clusters on each line.
 	WrapGraphemes
+	// WrapHyphenate behaves like WrapHeuristically, but also breaks words at
+	// the hyphenation points given by the patterns registered with package
+	// [gioui.org/text/hyphen] for the language of the text, ending the broken
+	// line with a hyphen. Importing a subpackage of hyphen, such as
+	// [gioui.org/text/hyphen/de], registers the patterns of its language.
+	// Without patterns for the language, it is equivalent to
+	// WrapHeuristically.
+	WrapHyphenate
 )
 
 // Parameters are static text shaping attributes applied to the entire shaped text.
@@ -60,6 +71,14 @@ type Parameters struct {
 	// for the shaped text.
 	MinWidth, MaxWidth int
 	// Locale provides primary direction and language information for the shaped text.
+	//
+	// Text with a vertical direction such as [system.TTB] is laid out in
+	// columns. The glyphs are positioned as if the columns were horizontal
+	// lines, with MaxWidth limiting the column height, and the layout must
+	// be rotated 90° clockwise for display. Glyphs with [FlagUpright] are
+	// rotated counter-clockwise by [Shaper.Shape] and [Shaper.Bitmaps] so
+	// that they stand upright after the layout is rotated; the other glyphs
+	// read sideways.
 	Locale system.Locale
 
 	// LineHeightScale is a scaling factor applied to the LineHeight of a paragraph. If zero, a default
@@ -81,6 +100,168 @@ type Parameters struct {
 	// This is desirable for text editors (so that the whitespace can be selected), but is undesirable
 	// for ordinary display text.
 	DisableSpaceTrim bool
+
+	// Features sets OpenType features of the fonts, such as ligature
+	// variants, tabular numbers or stylistic sets.
+	Features FontFeatures
+	// Variations sets the axes of variable fonts. Fonts without the axes
+	// ignore them.
+	Variations FontVariations
+
+	// LetterSpacing is added between the grapheme clusters of the text.
+	LetterSpacing fixed.Int26_6
+	// WordSpacing is added to word separators such as spaces.
+	WordSpacing fixed.Int26_6
+	// TabStops are the positions of tab stops from the start of a line, in
+	// increasing order. A tab extends to the first stop after it.
+	TabStops TabStops
+	// TabWidth is the interval between the tab stops after the last of
+	// TabStops. If both TabWidth and TabStops are zero, tabs are shaped
+	// like any other character.
+	TabWidth fixed.Int26_6
+}
+
+// tabStop returns the position of the first tab stop after x, if any.
+func (p Parameters) tabStop(x fixed.Int26_6) (fixed.Int26_6, bool) {
+	for i := range p.TabStops.Len() {
+		if stop := p.TabStops.At(i); stop > x {
+			return stop, true
+		}
+	}
+	if w := p.TabWidth; w > 0 {
+		return (x/w + 1) * w, true
+	}
+	return 0, false
+}
+
+// hasTabStops reports whether the parameters configure tab stops.
+func (p Parameters) hasTabStops() bool {
+	return p.TabStops.Len() > 0 || p.TabWidth > 0
+}
+
+// FontFeatures is a comparable list of OpenType feature settings, for use
+// in Parameters. The zero value has no features.
+type FontFeatures struct {
+	// enc holds the tag and value of every feature, 8 bytes each.
+	enc string
+}
+
+// NewFontFeatures returns the list of features. Features with tags that
+// are not four letters long are ignored.
+func NewFontFeatures(features ...FontFeature) FontFeatures {
+	var enc []byte
+	for _, f := range features {
+		if len(f.Tag) != 4 {
+			continue
+		}
+		enc = append(enc, f.Tag...)
+		enc = binary.BigEndian.AppendUint32(enc, f.Value)
+	}
+	return FontFeatures{enc: string(enc)}
+}
+
+// Len returns the number of features.
+func (f FontFeatures) Len() int {
+	return len(f.enc) / 8
+}
+
+// At returns the feature at index i.
+func (f FontFeatures) At(i int) FontFeature {
+	e := f.enc[i*8 : i*8+8]
+	return FontFeature{Tag: e[:4], Value: decodeUint32(e[4:])}
+}
+
+// FontVariations is a comparable list of variable font axis settings, for
+// use in Parameters. The zero value has no variations.
+type FontVariations struct {
+	// enc holds the tag and value of every variation, 8 bytes each.
+	enc string
+}
+
+// NewFontVariations returns the list of variations. Variations with tags
+// that are not four letters long are ignored.
+func NewFontVariations(variations ...FontVariation) FontVariations {
+	var enc []byte
+	for _, v := range variations {
+		if len(v.Tag) != 4 {
+			continue
+		}
+		enc = append(enc, v.Tag...)
+		enc = binary.BigEndian.AppendUint32(enc, math.Float32bits(v.Value))
+	}
+	return FontVariations{enc: string(enc)}
+}
+
+// Len returns the number of variations.
+func (v FontVariations) Len() int {
+	return len(v.enc) / 8
+}
+
+// At returns the variation at index i.
+func (v FontVariations) At(i int) FontVariation {
+	e := v.enc[i*8 : i*8+8]
+	return FontVariation{Tag: e[:4], Value: math.Float32frombits(decodeUint32(e[4:]))}
+}
+
+// TabStops is a comparable list of tab stop positions, for use in
+// Parameters. The zero value has no stops.
+type TabStops struct {
+	// enc holds the positions, 4 bytes each.
+	enc string
+}
+
+// NewTabStops returns the list of tab stops.
+func NewTabStops(stops ...fixed.Int26_6) TabStops {
+	enc := make([]byte, 0, 4*len(stops))
+	for _, s := range stops {
+		enc = binary.BigEndian.AppendUint32(enc, uint32(s))
+	}
+	return TabStops{enc: string(enc)}
+}
+
+// Len returns the number of tab stops.
+func (t TabStops) Len() int {
+	return len(t.enc) / 4
+}
+
+// At returns the tab stop at index i.
+func (t TabStops) At(i int) fixed.Int26_6 {
+	return fixed.Int26_6(decodeUint32(t.enc[i*4 : i*4+4]))
+}
+
+// decodeUint32 decodes a big endian uint32 from the first 4 bytes of s.
+func decodeUint32(s string) uint32 {
+	return uint32(s[0])<<24 | uint32(s[1])<<16 | uint32(s[2])<<8 | uint32(s[3])
+}
+
+// FontFeature sets an OpenType feature, such as "liga", "tnum", "smcp" or
+// "ss01". A Value of zero disables the feature, one enables it and larger
+// values select alternates.
+type FontFeature struct {
+	// Tag is the four letter tag of the feature.
+	Tag   string
+	Value uint32
+}
+
+// FontVariation sets an axis of variable fonts, such as "wght", "wdth" or
+// "opsz".
+type FontVariation struct {
+	// Tag is the four letter tag of the axis.
+	Tag string
+	// Value is in the design units of the axis, such as 700 for a bold
+	// "wght".
+	Value float32
+}
+
+// Span is a range of rich text with a common style. See [Shaper.LayoutSpans].
+type Span struct {
+	// Text is the text of the span.
+	Text string
+	// Font describes the preferred typeface of the span.
+	Font giofont.Font
+	// PxPerEm is the pixels-per-em to shape the span with. If zero, the
+	// PxPerEm of the Parameters is used.
+	PxPerEm fixed.Int26_6
 }
 
 type FontFace = giofont.FontFace
@@ -129,6 +310,9 @@ type Glyph struct {
 	Runes uint16
 	// Flags encode special properties of this glyph.
 	Flags Flags
+	// Span is the index of the Span the glyph was shaped from by
+	// [Shaper.LayoutSpans]. It is zero for other text.
+	Span int
 }
 
 type Flags uint16
@@ -160,6 +344,9 @@ const (
 	// FlagTruncator and FlagClusterBreak will have a Runes field accounting for all
 	// runes truncated.
 	FlagTruncator
+	// FlagUpright is set for glyphs of vertical text that stand upright in
+	// their column, such as CJK ideographs. See [Parameters.Locale].
+	FlagUpright
 )
 
 func (f Flags) String() string {
@@ -199,6 +386,11 @@ func (f Flags) String() string {
 	} else {
 		b.WriteString("_")
 	}
+	if f&FlagUpright != 0 {
+		b.WriteString("U")
+	} else {
+		b.WriteString("_")
+	}
 	return b.String()
 }
 
@@ -217,15 +409,23 @@ type Shaper struct {
 	config struct {
 		disableSystemFonts bool
 		collection         []FontFace
+		fallbacks          []Fallback
 	}
 	initialized      bool
 	shaper           shaperImpl
 	pathCache        pathCache
 	bitmapShapeCache bitmapShapeCache
-	layoutCache      layoutCache
+	// decorationCache holds the decoration paths, indexed by Decoration.
+	decorationCache [Underline | Strikethrough + 1]pathCache
+	layoutCache     layoutCache
 
 	reader    *bufio.Reader
 	paragraph []byte
+	// spans holds the styles of the spans of the current rich text
+	// paragraph.
+	spans []spanStyle
+	// spanKey is scratch space for encoding spans as cache keys.
+	spanKey strings.Builder
 
 	// Iterator state.
 	brokeParagraph   bool
@@ -258,6 +458,31 @@ func WithCollection(collection []FontFace) ShaperOption {
 	}
 }
 
+// Fallback is a chain of typefaces to try for the runes of a script that
+// the typefaces of the text font have no glyphs for. Fallback typefaces are
+// tried before the typefaces chosen by the system.
+type Fallback struct {
+	// Script is the ISO 15924 code of the script, such as "Arab" or
+	// "Hani". The empty script matches all scripts.
+	Script string
+	// Language is the BCP 47 tag of the text language, such as "ja". It
+	// matches more specific tags such as "ja-JP". The empty language
+	// matches all languages.
+	Language string
+	// Typeface lists the typefaces to try, in the format of
+	// font.Font.Typeface.
+	Typeface giofont.Typeface
+}
+
+// WithFallbacks configures the fallback typefaces of the shaper. The
+// typefaces of every fallback matching a rune are tried in the order of the
+// fallbacks.
+func WithFallbacks(fallbacks ...Fallback) ShaperOption {
+	return func(s *Shaper) {
+		s.config.fallbacks = fallbacks
+	}
+}
+
 // NewShaper constructs a shaper with the provided options.
 //
 // NewShaper must be called after [app.NewWindow], unless the [NoSystemFonts]
@@ -279,6 +504,7 @@ func (l *Shaper) init() {
 	l.initialized = true
 	l.reader = bufio.NewReader(nil)
 	l.shaper = *newShaperImpl(!l.config.disableSystemFonts, l.config.collection)
+	l.shaper.setFallbacks(l.config.fallbacks)
 }
 
 // Layout text from an io.Reader according to a set of options. Results can be retrieved by
@@ -307,7 +533,7 @@ func (l *Shaper) reset(align Alignment) {
 func (l *Shaper) layoutText(params Parameters, txt io.Reader, str string) {
 	l.reset(params.Alignment)
 	if txt == nil && len(str) == 0 {
-		l.txt.append(l.layoutParagraph(params, "", nil))
+		l.txt.append(l.layoutParagraph(params, "", nil, nil))
 		return
 	}
 	l.reader.Reset(txt)
@@ -348,7 +574,7 @@ func (l *Shaper) layoutText(params Parameters, txt io.Reader, str string) {
 		}
 		if len(str[:endByte]) > 0 || (len(l.paragraph) > 0 || len(l.txt.lines) == 0) {
 			params.forceTruncate = truncating && !done
-			lines := l.layoutParagraph(params, str[:endByte], l.paragraph)
+			lines := l.layoutParagraph(params, str[:endByte], l.paragraph, nil)
 			if truncating {
 				params.MaxLines -= len(lines.lines)
 				if params.MaxLines == 0 {
@@ -379,17 +605,102 @@ func (l *Shaper) layoutText(params Parameters, txt io.Reader, str string) {
 	}
 }
 
+// LayoutSpans lays out rich text made of a sequence of differently styled
+// spans. The spans are shaped together, so that line wrapping and
+// bidirectional text work across span boundaries. Results can be retrieved
+// by iteratively calling NextGlyph, and the Span field of each glyph
+// identifies the span it was shaped from. The Font and PxPerEm of params
+// apply to the truncator.
+func (l *Shaper) LayoutSpans(params Parameters, spans []Span) {
+	l.init()
+	l.reset(params.Alignment)
+	// last is the index of the last span with text.
+	last := -1
+	for i, sp := range spans {
+		if sp.Text != "" {
+			last = i
+		}
+	}
+	truncating := params.MaxLines > 0
+	l.paragraph = l.paragraph[:0]
+	l.spans = l.spans[:0]
+	// first is the index of the first span of the current paragraph.
+	first := 0
+	// flush lays out the current paragraph and reports whether the
+	// layout is complete. rest is the number of runes after the paragraph.
+	flush := func(done bool, rest func() int) bool {
+		params.forceTruncate = truncating && !done
+		lines := l.layoutParagraph(params, "", l.paragraph, l.spans)
+		if truncating {
+			params.MaxLines -= len(lines.lines)
+			if params.MaxLines == 0 {
+				done = true
+				l.txt.unreadRuneCount = rest()
+			}
+		}
+		n := len(l.txt.lines)
+		l.txt.append(lines)
+		for i := n; i < len(l.txt.lines); i++ {
+			l.txt.lines[i].spanBase = first
+		}
+		l.paragraph = l.paragraph[:0]
+		l.spans = l.spans[:0]
+		return done
+	}
+	for i, sp := range spans {
+		ppem := sp.PxPerEm
+		if ppem == 0 {
+			ppem = params.PxPerEm
+		}
+		str := sp.Text
+		for {
+			end := len(str)
+			idx := strings.IndexByte(str, '\n')
+			if idx != -1 {
+				end = idx + 1
+			}
+			l.paragraph = append(l.paragraph, str[:end]...)
+			l.spans = append(l.spans, spanStyle{
+				font:  sp.Font,
+				ppem:  ppem,
+				runes: utf8.RuneCountInString(str[:end]),
+			})
+			str = str[end:]
+			if idx == -1 {
+				break
+			}
+			done := str == "" && i >= last
+			rest := func() int {
+				n := utf8.RuneCountInString(str)
+				for _, sp := range spans[i+1:] {
+					n += utf8.RuneCountInString(sp.Text)
+				}
+				return n
+			}
+			if flush(done, rest) {
+				return
+			}
+			first = i
+		}
+	}
+	if len(l.paragraph) > 0 || len(l.txt.lines) == 0 {
+		flush(true, func() int { return 0 })
+	}
+}
+
 // layoutParagraph shapes and wraps a paragraph using the provided parameters.
 // It accepts the paragraph data in either string or rune format, preferring the
-// string in order to hit the shaper cache more quickly.
-func (l *Shaper) layoutParagraph(params Parameters, asStr string, asBytes []byte) document {
+// string in order to hit the shaper cache more quickly. If spans is non-nil,
+// the paragraph is shaped with the span styles.
+func (l *Shaper) layoutParagraph(params Parameters, asStr string, asBytes []byte, spans []spanStyle) document {
 	if l == nil {
 		return document{}
 	}
 	if len(asStr) == 0 && len(asBytes) > 0 {
 		asStr = string(asBytes)
 	}
-	// Alignment is not part of the cache key because changing it does not impact shaping.
+	// Alignment is not part of the cache key because changing it does not impact shaping,
+	// except for justification.
 	lk := layoutKey{
 		ppem:            params.PxPerEm,
 		maxWidth:        params.MaxWidth,
@@ -403,11 +714,37 @@ func (l *Shaper) layoutParagraph(params Parameters, asStr string, asBytes []byte
 		str:             asStr,
 		lineHeight:      params.LineHeight,
 		lineHeightScale: params.LineHeightScale,
+		letterSpacing:   params.LetterSpacing,
+		wordSpacing:     params.WordSpacing,
+		tabWidth:        params.TabWidth,
+		justify:         params.Alignment == Justify,
+		features:        params.Features,
+		variations:      params.Variations,
+		tabStops:        params.TabStops,
+	}
+	if spans != nil {
+		l.spanKey.Reset()
+		for _, sp := range spans {
+			fmt.Fprintf(&l.spanKey, "%d,%d,%q,%d,%d;", sp.runes, sp.ppem, sp.font.Typeface, sp.font.Style, sp.font.Weight)
+		}
+		lk.spans = l.spanKey.String()
 	}
 	if l, ok := l.layoutCache.Get(lk); ok {
 		return l
 	}
-	lines := l.shaper.LayoutRunes(params, []rune(asStr))
+	lines := l.shaper.LayoutSpans(params, []rune(asStr), spans)
+	if l.shaper.evictedFaces {
+		// The cached layouts and shapes may refer to the indexes of
+		// evicted faces.
+		l.shaper.evictedFaces = false
+		l.layoutCache.clear()
+		l.pathCache.clear()
+		l.bitmapShapeCache.clear()
+		for i := range l.decorationCache {
+			l.decorationCache[i].clear()
+		}
+		l.shaper.bitmapGlyphCache.clear()
+	}
 	l.layoutCache.Put(lk, lines)
 	return lines
 }
@@ -446,6 +783,7 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 			return Glyph{
 				X:       align,
 				Y:       int32(line.yOffset),
+				Span:    line.spanBase,
 				Runes:   0,
 				Flags:   FlagLineBreak | FlagClusterBreak | FlagRunBreak,
 				Ascent:  line.ascent,
@@ -477,6 +815,7 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 		}
 		glyph := Glyph{
 			ID:      g.id,
+			Span:    line.spanBase + run.span,
 			X:       align + run.X + runOffset,
 			Y:       int32(line.yOffset),
 			Ascent:  line.ascent,
@@ -526,6 +865,9 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 		if run.Direction.Progression() == system.TowardOrigin {
 			glyph.Flags |= FlagTowardOrigin
 		}
+		if run.Direction.Axis() == system.Vertical {
+			glyph.Flags |= FlagUpright
+		}
 		if l.brokeParagraph {
 			glyph.Flags |= FlagParagraphStart
 			l.brokeParagraph = false
@@ -537,6 +879,7 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 				l.pararagraphStart = Glyph{
 					Ascent:  glyph.Ascent,
 					Descent: glyph.Descent,
+					Span:    glyph.Span,
 					Flags:   FlagParagraphStart | FlagLineBreak | FlagRunBreak | FlagClusterBreak,
 				}
 				// If a glyph is both a paragraph break and the final glyph, it's a newline
@@ -582,6 +925,50 @@ func splitGlyphID(g GlyphID) (fixed.Int26_6, int, font.GID) {
 	return ppem, faceIdx, gid
 }
 
+// MissingGlyphs returns the ranges of runes of the last laid out text that no
+// font has glyphs for. Such runes are displayed by a placeholder glyph,
+// usually a box.
+func (l *Shaper) MissingGlyphs() []Range {
+	var missing []Range
+	offset := 0
+	for _, line := range l.txt.lines {
+		for _, run := range line.runs {
+			if run.truncator {
+				continue
+			}
+			pos := offset + run.Runes.Offset
+			gs := run.Glyphs
+			// idx returns the index of the ith glyph in logical order.
+			idx := func(i int) int {
+				if run.Direction.Progression() == system.TowardOrigin {
+					return len(gs) - 1 - i
+				}
+				return i
+			}
+			for i := 0; i < len(gs); {
+				// Find the glyphs of the cluster.
+				cluster := gs[idx(i)]
+				absent := false
+				for ; i < len(gs) && gs[idx(i)].clusterIndex == cluster.clusterIndex; i++ {
+					g := gs[idx(i)]
+					_, _, gid := splitGlyphID(g.id)
+					absent = absent || gid == 0 && g.glyphCount > 0
+				}
+				if absent {
+					if n := len(missing); n > 0 && missing[n-1].Offset+missing[n-1].Count == pos {
+						missing[n-1].Count += cluster.runeCount
+					} else {
+						missing = append(missing, Range{Offset: pos, Count: cluster.runeCount})
+					}
+				}
+				pos += cluster.runeCount
+			}
+		}
+		offset += line.runeCount
+	}
+	return missing
+}
+
 // Shape converts the provided glyphs into a path. The path will enclose the forms
 // of all vector glyphs.
 // All glyphs are expected to be from a single line of text (their Y offsets are ignored).
@@ -598,9 +985,28 @@ func (l *Shaper) Shape(gs []Glyph) clip.PathSpec {
 	return shape
 }
 
+// Decorations converts the decorations d of the provided glyphs into a path.
+// The path aligns with the return value of Shape for the same gs slice.
+// All glyphs are expected to be from a single line of text.
+func (l *Shaper) Decorations(gs []Glyph, d Decoration) clip.PathSpec {
+	l.init()
+	cache := &l.decorationCache[d&(Underline|Strikethrough)]
+	key := cache.hashGlyphs(gs)
+	shape, ok := cache.Get(key, gs)
+	if ok {
+		return shape
+	}
+	pathOps := new(op.Ops)
+	shape = l.shaper.Decorations(pathOps, gs, d)
+	cache.Put(key, gs, shape)
+	return shape
+}
+
 // Bitmaps extracts bitmap glyphs from the provided slice and creates an op.CallOp to present
-// them. The returned op.CallOp will align correctly with the return value of Shape() for the
-// same gs slice.
+// them. The layers of color glyphs (COLR) are presented in their palette colors, except
+// for the layers painted with the text color, which are part of the path returned by
+// Shape(). The returned op.CallOp will align correctly with the return value of Shape()
+// for the same gs slice.
 // All glyphs are expected to be from a single line of text (their Y offsets are ignored).
 func (l *Shaper) Bitmaps(gs []Glyph) op.CallOp {
 	l.init()
@@ -614,3 +1020,21 @@ func (l *Shaper) Bitmaps(gs []Glyph) op.CallOp {
 	l.bitmapShapeCache.Put(key, gs, call)
 	return call
 }
+
+// SystemFont describes a font installed on the system.
+type SystemFont struct {
+	// Font describes the font. Its typeface is in the normalized form of
+	// the font index, which matches the typeface in a font.Font.
+	Font giofont.Font
+	// File is the path of the font file.
+	File string
+	// Index is the index of the font in a font collection file.
+	Index int
+}
+
+// SystemFonts returns the fonts found on the system, or nil if the shaper
+// doesn't use system fonts.
+func (l *Shaper) SystemFonts() []SystemFont {
+	l.init()
+	return l.shaper.systemFontList()
+}
@@ -1,16 +1,21 @@
 package text
 
 import (
+	"encoding/binary"
 	"fmt"
 	"slices"
 	"strings"
 	"testing"
 
 	nsareg "eliasnaur.com/font/noto/sans/arabic/regular"
+	nsjpreg "eliasnaur.com/font/noto/sans/jp/regular"
+	"eliasnaur.com/font/roboto/robotoregular"
 	"gioui.org/font"
 	"gioui.org/font/gofont"
 	"gioui.org/font/opentype"
 	"gioui.org/io/system"
+	"gioui.org/text/hyphen"
+	_ "gioui.org/text/hyphen/de"
 	"golang.org/x/image/font/gofont/goregular"
 	"golang.org/x/image/math/fixed"
 )
@@ -560,6 +565,13 @@ func TestShapeStringRuneAccounting(t *testing.T) {
 						shaper.Layout(params, strings.NewReader(input))
 					},
 				},
+				{
+					kind: "LayoutSpans",
+					do: func(shaper *Shaper, params Parameters, input string) {
+						mid := len(input) / 2
+						shaper.LayoutSpans(params, []Span{{Text: input[:mid]}, {}, {Text: input[mid:]}})
+					},
+				},
 			} {
 				t.Run(setup.kind, func(t *testing.T) {
 					shaper := NewShaper(NoSystemFonts(), WithCollection(gofont.Collection()))
@@ -581,3 +593,658 @@ func TestShapeStringRuneAccounting(t *testing.T) {
 		})
 	}
 }
+
+// TestLayoutSpans checks that spans are shaped with their own sizes, that
+// glyphs report their spans, and that spans don't affect line wrapping.
+func TestLayoutSpans(t *testing.T) {
+	shaper := NewShaper(NoSystemFonts(), WithCollection(gofont.Collection()))
+	params := Parameters{
+		PxPerEm:  fixed.I(10),
+		MaxWidth: 100,
+		Locale:   english,
+	}
+	const txt = "Lorem ipsum dolor sit amet, consectetur adipiscing elit,\nsed do eiusmod tempor"
+	shaper.LayoutString(params, txt)
+	var want []Glyph
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		want = append(want, g)
+	}
+	// Split the text on word boundaries into equally styled spans.
+	var spans []Span
+	for _, w := range strings.SplitAfter(txt, " ") {
+		spans = append(spans, Span{Text: w})
+	}
+	shaper.LayoutSpans(params, spans)
+	var got []Glyph
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		got = append(got, g)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d glyphs, want %d", len(got), len(want))
+	}
+	span, runes := 0, 0
+	for i, g := range got {
+		w := want[i]
+		// Span boundaries break runs.
+		if g.ID != w.ID || g.X != w.X || g.Y != w.Y || g.Flags&^FlagRunBreak != w.Flags&^FlagRunBreak || g.Runes != w.Runes {
+			t.Errorf("glyph %d: got %+v, want %+v", i, g, w)
+		}
+		for runes >= len([]rune(spans[span].Text)) {
+			runes -= len([]rune(spans[span].Text))
+			span++
+		}
+		if g.Span != span {
+			t.Errorf("glyph %d: span %d, want %d", i, g.Span, span)
+		}
+		runes += int(g.Runes)
+	}
+
+	// Shape spans with different sizes.
+	spans = []Span{
+		{Text: "ab"},
+		{Text: "c\nd", PxPerEm: fixed.I(20), Font: font.Font{Weight: font.Bold}},
+		{Text: "e"},
+	}
+	shaper.LayoutSpans(params, spans)
+	wantSpans := []struct {
+		span int
+		ppem fixed.Int26_6
+	}{{0, fixed.I(10)}, {0, fixed.I(10)}, {1, fixed.I(20)}, {1, fixed.I(20)}, {1, fixed.I(20)}, {2, fixed.I(10)}}
+	var i int
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		if i >= len(wantSpans) {
+			t.Fatalf("too many glyphs")
+		}
+		ppem, _, _ := splitGlyphID(g.ID)
+		w := wantSpans[i]
+		if g.Span != w.span {
+			t.Errorf("glyph %d: span %d, want %d", i, g.Span, w.span)
+		}
+		if g.Flags&FlagParagraphBreak == 0 && ppem != w.ppem {
+			t.Errorf("glyph %d: size %v, want %v", i, ppem, w.ppem)
+		}
+		i++
+	}
+	if i != len(wantSpans) {
+		t.Errorf("got %d glyphs, want %d", i, len(wantSpans))
+	}
+}
+
+func TestDecorations(t *testing.T) {
+	shaper := NewShaper(NoSystemFonts(), WithCollection(gofont.Collection()))
+	params := Parameters{
+		PxPerEm:  fixed.I(20),
+		MaxWidth: 1000,
+		Locale:   english,
+	}
+	layout := func(txt string) []Glyph {
+		shaper.LayoutString(params, txt)
+		var gs []Glyph
+		for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+			gs = append(gs, g)
+		}
+		return gs
+	}
+	width := func(gs []Glyph) float32 {
+		var w fixed.Int26_6
+		for _, g := range gs {
+			w += g.Advance
+		}
+		return fixedToFloat(w)
+	}
+	gs := layout("xxx")
+	rects := shaper.shaper.decorationRects(gs, Underline)
+	if len(rects) != 1 {
+		t.Fatalf("underline of %q has %d parts, want 1", "xxx", len(rects))
+	}
+	r := rects[0]
+	if r.x0 != 0 || r.x1 != width(gs) {
+		t.Errorf("underline spans [%v, %v], want [0, %v]", r.x0, r.x1, width(gs))
+	}
+	if r.y0 <= 0 || r.y1 <= r.y0 {
+		t.Errorf("underline at [%v, %v], want below the baseline", r.y0, r.y1)
+	}
+
+	// Descenders interrupt the underline, but not the strikethrough.
+	gs = layout("xgx")
+	if n := len(shaper.shaper.decorationRects(gs, Underline)); n != 2 {
+		t.Errorf("underline of %q has %d parts, want 2", "xgx", n)
+	}
+	rects = shaper.shaper.decorationRects(gs, Strikethrough)
+	if len(rects) != 1 {
+		t.Fatalf("strikethrough of %q has %d parts, want 1", "xgx", len(rects))
+	}
+	if r := rects[0]; r.y1 >= 0 {
+		t.Errorf("strikethrough at [%v, %v], want above the baseline", r.y0, r.y1)
+	}
+	if n := len(shaper.shaper.decorationRects(gs, Underline|Strikethrough)); n != 3 {
+		t.Errorf("%d decoration parts, want 3", n)
+	}
+}
+
+// Parameters must remain comparable.
+var _ = map[Parameters]bool{}
+
+func TestFontFeatures(t *testing.T) {
+	face, err := opentype.Parse(robotoregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: face}}))
+	layout := func(features []FontFeature, variations []FontVariation) []GlyphID {
+		shaper.LayoutString(Parameters{
+			PxPerEm:    fixed.I(20),
+			MaxWidth:   1000,
+			Locale:     english,
+			Features:   NewFontFeatures(features...),
+			Variations: NewFontVariations(variations...),
+		}, "office")
+		var ids []GlyphID
+		for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+			ids = append(ids, g.ID)
+		}
+		return ids
+	}
+	def := layout(nil, nil)
+	if len(def) >= len("office") {
+		t.Errorf("shaped %d glyphs, want ligatures", len(def))
+	}
+	if noLiga := layout([]FontFeature{{Tag: "liga", Value: 0}}, nil); len(noLiga) != len("office") {
+		t.Errorf("disabling ligatures shaped %d glyphs, want %d", len(noLiga), len("office"))
+	}
+	if smcp := layout([]FontFeature{{Tag: "smcp", Value: 1}}, nil); slices.Equal(smcp, def) {
+		t.Error("small caps didn't change the glyphs")
+	}
+	// Invalid tags are ignored.
+	if got := layout([]FontFeature{{Tag: "small-caps", Value: 1}}, nil); !slices.Equal(got, def) {
+		t.Errorf("invalid feature tag shaped %v, want %v", got, def)
+	}
+	// Roboto is not a variable font.
+	if got := layout(nil, []FontVariation{{Tag: "wght", Value: 700}}); !slices.Equal(got, def) {
+		t.Errorf("variations of a static font shaped %v, want %v", got, def)
+	}
+	if got := layout(nil, nil); !slices.Equal(got, def) {
+		t.Errorf("cached layout %v, want %v", got, def)
+	}
+}
+
+// variableFont returns Go Regular as a variable font with a "wght" axis
+// from 400 to 700. At 700, every advance is 500 units wider.
+func variableFont() []byte {
+	fvar := []byte{
+		0, 1, 0, 0, // version
+		0, 16, // axesArrayOffset
+		0, 2, // reserved
+		0, 1, // axisCount
+		0, 20, // axisSize
+		0, 0, // instanceCount
+		0, 8, // instanceSize
+		// Axis record.
+		'w', 'g', 'h', 't',
+		1, 144, 0, 0, // minValue 400
+		1, 144, 0, 0, // defaultValue 400
+		2, 188, 0, 0, // maxValue 700
+		0, 0, // flags
+		1, 0, // axisNameID
+	}
+	hvar := []byte{
+		0, 1, 0, 0, // version
+		0, 0, 0, 20, // itemVariationStoreOffset
+		0, 0, 0, 52, // advanceWidthMappingOffset
+		0, 0, 0, 0, // lsbMappingOffset
+		0, 0, 0, 0, // rsbMappingOffset
+		// ItemVariationStore.
+		0, 1, // format
+		0, 0, 0, 12, // variationRegionListOffset
+		0, 1, // itemVariationDataCount
+		0, 0, 0, 22, // itemVariationDataOffsets[0]
+		// VariationRegionList with a single region peaking at 700.
+		0, 1, // axisCount
+		0, 1, // regionCount
+		0, 0, 0x40, 0, 0x40, 0, // start, peak, end
+		// ItemVariationData.
+		0, 1, // itemCount
+		0, 1, // wordDeltaCount
+		0, 1, // regionIndexCount
+		0, 0, // regionIndexes[0]
+		0x01, 0xf4, // delta 500
+		// DeltaSetIndexMap mapping every glyph to the delta.
+		0, 0, // format, entryFormat
+		0, 1, // mapCount
+		0, // map[0]
+	}
+	return withTables(goregular.TTF, map[string][]byte{"fvar": fvar, "HVAR": hvar})
+}
+
+func TestFontVariations(t *testing.T) {
+	face, err := opentype.Parse(variableFont())
+	if err != nil {
+		t.Fatal(err)
+	}
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: face}}))
+	advance := func(weight float32) fixed.Int26_6 {
+		shaper.LayoutString(Parameters{
+			PxPerEm:    fixed.I(20),
+			MaxWidth:   1000,
+			Locale:     english,
+			Variations: NewFontVariations(FontVariation{Tag: "wght", Value: weight}),
+		}, "a")
+		g, ok := shaper.NextGlyph()
+		if !ok {
+			t.Fatal("no glyph")
+		}
+		for _, ok := shaper.NextGlyph(); ok; _, ok = shaper.NextGlyph() {
+		}
+		return g.Advance
+	}
+	regular, bold := advance(400), advance(700)
+	// 500 units at 2048 units per em and 20 pixels per em.
+	if got, want := bold-regular, fixed.Int26_6(500*20*64/2048); got < want-1 || got > want+1 {
+		t.Errorf("bold advance is %v wider, want %v", got, want)
+	}
+	// Many variations evict faces, without running out of face indexes or
+	// reusing stale layouts.
+	for i := range 3 * maxVariedFaces {
+		advance(400 + float32(i)*300/(3*maxVariedFaces))
+	}
+	if n := len(shaper.shaper.faces); n > maxVariedFaces+1 {
+		t.Errorf("%d faces after evictions, want at most %d", n, maxVariedFaces+1)
+	}
+	if got := advance(700); got != bold {
+		t.Errorf("bold advance after evictions is %v, want %v", got, bold)
+	}
+	if got := advance(400); got != regular {
+		t.Errorf("regular advance after evictions is %v, want %v", got, regular)
+	}
+}
+
+func TestSpacingAndTabs(t *testing.T) {
+	shaper := NewShaper(NoSystemFonts(), WithCollection(gofont.Collection()))
+	layout := func(params Parameters, txt string) []Glyph {
+		params.PxPerEm = fixed.I(10)
+		params.Locale = english
+		if params.MaxWidth == 0 {
+			params.MaxWidth = 1000
+		}
+		shaper.LayoutString(params, txt)
+		var gs []Glyph
+		for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+			gs = append(gs, g)
+		}
+		return gs
+	}
+	const txt = "ab cd"
+	def := layout(Parameters{}, txt)
+	letter := layout(Parameters{LetterSpacing: fixed.I(2)}, txt)
+	word := layout(Parameters{WordSpacing: fixed.I(5)}, txt)
+	for i := range def {
+		// Letter spacing is added between, but not before, clusters.
+		want := def[i].X
+		if i > 0 {
+			// The start of the first cluster is trimmed.
+			want += fixed.I(2*i - 1)
+		}
+		if got := letter[i].X; got != want {
+			t.Errorf("letter spaced glyph %d at %v, want %v", i, got, want)
+		}
+		want = def[i].X
+		if i > 2 {
+			want += fixed.I(5)
+		}
+		if got := word[i].X; got != want {
+			t.Errorf("word spaced glyph %d at %v, want %v", i, got, want)
+		}
+	}
+
+	gs := layout(Parameters{TabWidth: fixed.I(40)}, "a\tb\tc")
+	if got, want := gs[2].X, fixed.I(40); got != want {
+		t.Errorf("glyph after tab at %v, want %v", got, want)
+	}
+	if got, want := gs[4].X, fixed.I(80); got != want {
+		t.Errorf("glyph after second tab at %v, want %v", got, want)
+	}
+	if _, _, gid := splitGlyphID(gs[1].ID); gid == 0 {
+		t.Error("tab shaped with the missing glyph")
+	}
+	gs = layout(Parameters{TabStops: NewTabStops(fixed.I(15), fixed.I(25)), TabWidth: fixed.I(40)}, "a\tb\tc\td")
+	for i, want := range []fixed.Int26_6{fixed.I(15), fixed.I(25), fixed.I(40)} {
+		if got := gs[2*i+2].X; got != want {
+			t.Errorf("glyph after tab %d at %v, want %v", i, got, want)
+		}
+	}
+	// Tab stops are measured from the start of wrapped lines.
+	gs = layout(Parameters{TabWidth: fixed.I(40), MaxWidth: 60}, "aaaa aaaa bb\tb")
+	if last := gs[len(gs)-1]; last.X != fixed.I(40) || last.Y == gs[0].Y {
+		t.Errorf("glyph after wrapped tab at (%v, %d), want (%v, > %d)", last.X, last.Y, fixed.I(40), gs[0].Y)
+	}
+}
+
+func TestJustify(t *testing.T) {
+	shaper := NewShaper(NoSystemFonts(), WithCollection(gofont.Collection()))
+	const maxWidth = 60
+	lines := func(params Parameters, txt string) [][]Glyph {
+		params.PxPerEm = fixed.I(10)
+		params.MaxWidth = maxWidth
+		params.Locale = english
+		shaper.LayoutString(params, txt)
+		var lines [][]Glyph
+		var line []Glyph
+		for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+			line = append(line, g)
+			if g.Flags&FlagLineBreak != 0 {
+				lines = append(lines, line)
+				line = nil
+			}
+		}
+		return lines
+	}
+	for _, tc := range []struct {
+		txt  string
+		wrap WrapPolicy
+	}{
+		{"Lorem ipsum dolor sit amet, consectetur", WrapHeuristically},
+		{"Loremipsumdolorsitametconsectetur", WrapGraphemes},
+	} {
+		start := lines(Parameters{WrapPolicy: tc.wrap}, tc.txt)
+		justified := lines(Parameters{WrapPolicy: tc.wrap, Alignment: Justify}, tc.txt)
+		if len(start) < 3 || len(justified) != len(start) {
+			t.Fatalf("%q: justified to %d lines, want %d > 2", tc.txt, len(justified), len(start))
+		}
+		for i, l := range justified {
+			if last := i == len(justified)-1; last {
+				if !slices.Equal(l, start[i]) {
+					t.Errorf("%q: justified last line %v, want %v", tc.txt, l, start[i])
+				}
+				continue
+			}
+			if l[0].X != 0 {
+				t.Errorf("%q: justified line %d starts at %v, want 0", tc.txt, i, l[0].X)
+			}
+			// Lines without word gaps are stretched between clusters.
+			if tc.wrap == WrapGraphemes && l[1].X <= start[i][1].X {
+				t.Errorf("%q: justified line %d has no gap after its first glyph", tc.txt, i)
+			}
+			// Find the right ink edge of the last visible glyph.
+			var end fixed.Int26_6
+			for _, g := range l {
+				if g.Bounds.Max.X > g.Bounds.Min.X {
+					end = g.X + g.Bounds.Max.X
+				}
+			}
+			if d := end - fixed.I(maxWidth); d < -fixed.I(1) || d > fixed.I(1) {
+				t.Errorf("%q: justified line %d ends at %v, want %d", tc.txt, i, end, maxWidth)
+			}
+		}
+	}
+}
+
+func TestHyphenate(t *testing.T) {
+	p, err := hyphen.Parse("hy3ph he2n hena4 hen5at 1na n2at 1tio 2io o2n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hyphen.Register("x-test", p)
+	shaper := NewShaper(NoSystemFonts(), WithCollection(gofont.Collection()))
+	params := Parameters{
+		PxPerEm:    fixed.I(10),
+		MaxWidth:   40,
+		Locale:     system.Locale{Language: "x-test"},
+		WrapPolicy: WrapHyphenate,
+	}
+	lines := hyphenatedLines(t, shaper, params, "a hyphenation hyphenation")
+	want := []string{"a hy-", "phen-", "ation ", "hyphen-", "ation"}
+	if !slices.Equal(lines, want) {
+		t.Errorf("hyphenated lines %q, want %q", lines, want)
+	}
+	// Soft hyphens of the text are kept.
+	lines = hyphenatedLines(t, shaper, params, "a hy\u00adphenation")
+	if got, want := strings.Join(lines, ""), "a hy\u00adphen-ation"; got != want {
+		t.Errorf("hyphenated text with soft hyphen %q, want %q", got, want)
+	}
+}
+
+func TestHyphenateGerman(t *testing.T) {
+	shaper := NewShaper(NoSystemFonts(), WithCollection(gofont.Collection()))
+	params := Parameters{
+		PxPerEm:    fixed.I(10),
+		MaxWidth:   60,
+		Locale:     system.Locale{Language: "de-DE"},
+		WrapPolicy: WrapHyphenate,
+	}
+	lines := hyphenatedLines(t, shaper, params, "Silbentrennung")
+	want := []string{"Silbentren-", "nung"}
+	if !slices.Equal(lines, want) {
+		t.Errorf("hyphenated lines %q, want %q", lines, want)
+	}
+	// Space for a hyphen is reserved only where one is drawn.
+	params.MaxWidth = 70
+	lines = hyphenatedLines(t, shaper, params, "Silbentrennung")
+	want = []string{"Silbentrennung"}
+	if !slices.Equal(lines, want) {
+		t.Errorf("hyphenated lines %q, want %q", lines, want)
+	}
+}
+
+// hyphenatedLines lays out txt and returns its lines, with a '-' appended
+// to the lines that end in an inserted hyphen.
+func hyphenatedLines(t *testing.T, shaper *Shaper, params Parameters, txt string) []string {
+	t.Helper()
+	shaper.LayoutString(params, "-")
+	g, _ := shaper.NextGlyph()
+	hyphenID := g.ID
+	shaper.LayoutString(params, txt)
+	var lines []string
+	var line []rune
+	runes := 0
+	rtxt := []rune(txt)
+	var prev Glyph
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		line = append(line, rtxt[runes:runes+int(g.Runes)]...)
+		runes += int(g.Runes)
+		if g.Flags&FlagLineBreak != 0 {
+			// Mark lines ending in a hyphen glyph that is not in the text.
+			if g.ID == hyphenID && prev.Runes == 0 {
+				line = append(line, '-')
+			}
+			lines = append(lines, string(line))
+			line = nil
+		}
+		prev = g
+	}
+	if runes != len(rtxt) {
+		t.Errorf("glyphs of %q cover %d runes, want %d", txt, runes, len(rtxt))
+	}
+	return lines
+}
+
+func TestVerticalText(t *testing.T) {
+	jpFace, err := opentype.Parse(nsjpreg.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection := append(gofont.Collection(), font.FontFace{Face: jpFace})
+	shaper := NewShaper(NoSystemFonts(), WithCollection(collection))
+	const ppem = 10
+	shaper.LayoutString(Parameters{
+		PxPerEm:  fixed.I(ppem),
+		MaxWidth: 4 * ppem,
+		Locale:   system.Locale{Language: "ja", Direction: system.TTB},
+	}, "日本語の文章 Go")
+	var glyphs []Glyph
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		glyphs = append(glyphs, g)
+	}
+	if len(glyphs) != 9 {
+		t.Fatalf("got %d glyphs, want 9", len(glyphs))
+	}
+	for i, g := range glyphs {
+		upright := g.Flags&FlagUpright != 0
+		if i < 6 {
+			if !upright {
+				t.Errorf("glyph %d is sideways, want upright", i)
+			}
+			// Ideographs advance by an em down the column.
+			if g.Advance != fixed.I(ppem) {
+				t.Errorf("glyph %d advances %v, want %d", i, g.Advance, ppem)
+			}
+			// The ideograph stands within its advance.
+			if g.Bounds.Min.X < 0 || g.Bounds.Max.X > g.Advance {
+				t.Errorf("glyph %d bounds %v exceed its advance %v", i, g.Bounds, g.Advance)
+			}
+		} else if i > 6 && upright {
+			// Latin reads sideways; the space takes the orientation of
+			// the ideographs before it.
+			t.Errorf("glyph %d is upright, want sideways", i)
+		}
+	}
+	// The columns are laid out as lines limited by MaxWidth.
+	if g := glyphs[4]; g.X != 0 || g.Y <= glyphs[0].Y {
+		t.Errorf("glyph 4 at (%v, %d), want the start of the second column", g.X, g.Y)
+	}
+	if glyphs[3].Flags&FlagLineBreak == 0 {
+		t.Errorf("glyph 3 does not end the first column")
+	}
+}
+
+// withTables returns the font ttf with the tables added. The tables must
+// not be present in ttf.
+func withTables(ttf []byte, tables map[string][]byte) []byte {
+	type record struct {
+		tag  string
+		data []byte
+	}
+	n := int(ttf[4])<<8 | int(ttf[5])
+	var recs []record
+	for i := range n {
+		r := ttf[12+16*i:]
+		off := int(binary.BigEndian.Uint32(r[8:]))
+		l := int(binary.BigEndian.Uint32(r[12:]))
+		recs = append(recs, record{tag: string(r[:4]), data: ttf[off : off+l]})
+	}
+	for tag, data := range tables {
+		recs = append(recs, record{tag: tag, data: data})
+	}
+	slices.SortFunc(recs, func(a, b record) int { return strings.Compare(a.tag, b.tag) })
+	out := slices.Clone(ttf[:12])
+	binary.BigEndian.PutUint16(out[4:], uint16(len(recs)))
+	off := 12 + 16*len(recs)
+	var data []byte
+	for _, r := range recs {
+		out = append(out, r.tag...)
+		out = binary.BigEndian.AppendUint32(out, 0)
+		out = binary.BigEndian.AppendUint32(out, uint32(off+len(data)))
+		out = binary.BigEndian.AppendUint32(out, uint32(len(r.data)))
+		data = append(data, r.data...)
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+	}
+	return append(out, data...)
+}
+
+func TestColorGlyphs(t *testing.T) {
+	plain, _ := opentype.Parse(goregular.TTF)
+	gidA, _ := plain.Face().NominalGlyph('A')
+	gidO, _ := plain.Face().NominalGlyph('O')
+	// A color 'A' layered over a red 'O'.
+	colr := []byte{
+		0, 0, // version
+		0, 1, // numBaseGlyphRecords
+		0, 0, 0, 14, // baseGlyphRecordsOffset
+		0, 0, 0, 20, // layerRecordsOffset
+		0, 2, // numLayerRecords
+		byte(gidA >> 8), byte(gidA), 0, 0, 0, 2,
+		byte(gidO >> 8), byte(gidO), 0, 0,
+		byte(gidA >> 8), byte(gidA), 0xff, 0xff,
+	}
+	cpal := []byte{
+		0, 0, 0, 1, 0, 1, 0, 1,
+		0, 0, 0, 14,
+		0, 0,
+		0, 0, 0xff, 0xff,
+	}
+	face, err := opentype.Parse(withTables(goregular.TTF, map[string][]byte{"COLR": colr, "CPAL": cpal}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: face}}))
+	shaper.LayoutString(Parameters{
+		PxPerEm:  fixed.I(20),
+		MaxWidth: 2000,
+		Locale:   english,
+	}, "AO")
+	var gs []Glyph
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		gs = append(gs, g)
+	}
+	if len(gs) != 2 {
+		t.Fatalf("got %d glyphs, expected 2", len(gs))
+	}
+	impl := shaper.shaper
+	for i, want := range []int{2, 0} {
+		_, faceIdx, gid := splitGlyphID(gs[i].ID)
+		if l := impl.layers(faceIdx, gid); len(l) != want {
+			t.Errorf("glyph %d has %d layers, expected %d", i, len(l), want)
+		}
+	}
+	// Ensure painting the color glyph does not panic.
+	shaper.Shape(gs)
+	shaper.Bitmaps(gs)
+}
+
+func TestMissingGlyphs(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: ltrFace}}))
+	shaper.LayoutString(Parameters{
+		PxPerEm:  fixed.I(10),
+		MaxWidth: 1000,
+		Locale:   english,
+	}, "ab日本c\nd語")
+	for _, ok := shaper.NextGlyph(); ok; _, ok = shaper.NextGlyph() {
+	}
+	want := []Range{{Offset: 2, Count: 2}, {Offset: 7, Count: 1}}
+	if got := shaper.MissingGlyphs(); !slices.Equal(got, want) {
+		t.Errorf("got missing glyphs %v, want %v", got, want)
+	}
+}
+
+func TestFallbacks(t *testing.T) {
+	goFace, _ := opentype.Parse(goregular.TTF)
+	robotoFace, _ := opentype.Parse(robotoregular.TTF)
+	arabicFace, _ := opentype.Parse(nsareg.TTF)
+	collection := []FontFace{
+		{Face: goFace, Font: goFace.Font()},
+		{Face: robotoFace, Font: robotoFace.Font()},
+		{Face: arabicFace, Font: arabicFace.Font()},
+	}
+	for _, tc := range []struct {
+		name      string
+		fallbacks []Fallback
+		face      int
+	}{
+		{name: "none", face: 0},
+		{name: "script", fallbacks: []Fallback{{Script: "Latn", Typeface: robotoFace.Font().Typeface}}, face: 1},
+		{name: "other script", fallbacks: []Fallback{{Script: "Grek", Typeface: robotoFace.Font().Typeface}}, face: 0},
+		{name: "language", fallbacks: []Fallback{{Language: "en", Typeface: robotoFace.Font().Typeface}}, face: 1},
+		{name: "other language", fallbacks: []Fallback{{Language: "fr", Typeface: robotoFace.Font().Typeface}}, face: 0},
+		{name: "order", fallbacks: []Fallback{
+			{Language: "en", Typeface: goFace.Font().Typeface},
+			{Script: "Latn", Typeface: robotoFace.Font().Typeface},
+		}, face: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			shaper := NewShaper(NoSystemFonts(), WithCollection(collection), WithFallbacks(tc.fallbacks...))
+			shaper.LayoutString(Parameters{
+				PxPerEm:  fixed.I(10),
+				MaxWidth: 1000,
+				Locale:   english,
+				Font:     arabicFace.Font(),
+			}, "xyz")
+			for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+				if _, face, _ := splitGlyphID(g.ID); face != tc.face {
+					t.Errorf("glyph shaped with face %d, want %d", face, tc.face)
+				}
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ package text
 
 import (
 	"fmt"
+	"strings"
 
 	"gioui.org/io/system"
 	"golang.org/x/image/math/fixed"
@@ -15,6 +16,11 @@ const (
 	Start Alignment = iota
 	End
 	Middle
+	// Justify stretches the lines of a paragraph to the maximum width by
+	// widening the gaps between words, or between characters in lines
+	// without word gaps, such as CJK text. The last line of each paragraph
+	// is aligned to the start.
+	Justify
 )
 
 func (a Alignment) String() string {
@@ -25,6 +31,8 @@ func (a Alignment) String() string {
 		return "End"
 	case Middle:
 		return "Middle"
+	case Justify:
+		return "Justify"
 	default:
 		panic("invalid Alignment")
 	}
@@ -37,7 +45,7 @@ func (a Alignment) Align(dir system.TextDirection, width fixed.Int26_6, maxWidth
 	mw := fixed.I(maxWidth)
 	if dir.Progression() == system.TowardOrigin {
 		switch a {
-		case Start:
+		case Start, Justify:
 			a = End
 		case End:
 			a = Start
@@ -48,9 +56,36 @@ func (a Alignment) Align(dir system.TextDirection, width fixed.Int26_6, maxWidth
 		return (mw - width) / 2
 	case End:
 		return (mw - width)
-	case Start:
+	case Start, Justify:
+		// Justified lines span maxWidth, except the last line of a
+		// paragraph which is start aligned.
 		return 0
 	default:
 		panic(fmt.Errorf("unknown alignment %v", a))
 	}
 }
+
+// Decoration is a set of lines drawn along text.
+type Decoration uint8
+
+const (
+	// Underline draws a line below the baseline, interrupted where glyphs
+	// descend through it.
+	Underline Decoration = 1 << iota
+	// Strikethrough draws a line through the text.
+	Strikethrough
+)
+
+func (d Decoration) String() string {
+	var parts []string
+	if d&Underline != 0 {
+		parts = append(parts, "Underline")
+	}
+	if d&Strikethrough != 0 {
+		parts = append(parts, "Strikethrough")
+	}
+	if len(parts) == 0 {
+		return "None"
+	}
+	return strings.Join(parts, "|")
+}
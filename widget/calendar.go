@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"time"
+
+	"gioui.org/io/system"
+	"golang.org/x/text/language"
+)
+
+// calendarNames holds the localized names used by the date and time
+// pickers.
+type calendarNames struct {
+	months [12]string
+	// weekdays are the abbreviated day names, starting with Sunday.
+	weekdays [7]string
+}
+
+var calendars = map[string]*calendarNames{
+	"en": {
+		months:   [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		weekdays: [7]string{"Su", "Mo", "Tu", "We", "Th", "Fr", "Sa"},
+	},
+	"da": {
+		months:   [12]string{"januar", "februar", "marts", "april", "maj", "juni", "juli", "august", "september", "oktober", "november", "december"},
+		weekdays: [7]string{"sø", "ma", "ti", "on", "to", "fr", "lø"},
+	},
+	"de": {
+		months:   [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		weekdays: [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+	},
+	"es": {
+		months:   [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		weekdays: [7]string{"do", "lu", "ma", "mi", "ju", "vi", "sá"},
+	},
+	"fi": {
+		months:   [12]string{"tammikuu", "helmikuu", "maaliskuu", "huhtikuu", "toukokuu", "kesäkuu", "heinäkuu", "elokuu", "syyskuu", "lokakuu", "marraskuu", "joulukuu"},
+		weekdays: [7]string{"su", "ma", "ti", "ke", "to", "pe", "la"},
+	},
+	"fr": {
+		months:   [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		weekdays: [7]string{"di", "lu", "ma", "me", "je", "ve", "sa"},
+	},
+	"it": {
+		months:   [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		weekdays: [7]string{"do", "lu", "ma", "me", "gi", "ve", "sa"},
+	},
+	"ja": {
+		months:   [12]string{"1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"},
+		weekdays: [7]string{"日", "月", "火", "水", "木", "金", "土"},
+	},
+	"ko": {
+		months:   [12]string{"1월", "2월", "3월", "4월", "5월", "6월", "7월", "8월", "9월", "10월", "11월", "12월"},
+		weekdays: [7]string{"일", "월", "화", "수", "목", "금", "토"},
+	},
+	"nb": {
+		months:   [12]string{"januar", "februar", "mars", "april", "mai", "juni", "juli", "august", "september", "oktober", "november", "desember"},
+		weekdays: [7]string{"sø", "ma", "ti", "on", "to", "fr", "lø"},
+	},
+	"nl": {
+		months:   [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		weekdays: [7]string{"zo", "ma", "di", "wo", "do", "vr", "za"},
+	},
+	"pl": {
+		months:   [12]string{"styczeń", "luty", "marzec", "kwiecień", "maj", "czerwiec", "lipiec", "sierpień", "wrzesień", "październik", "listopad", "grudzień"},
+		weekdays: [7]string{"nd", "pn", "wt", "śr", "cz", "pt", "so"},
+	},
+	"pt": {
+		months:   [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		weekdays: [7]string{"dom", "seg", "ter", "qua", "qui", "sex", "sáb"},
+	},
+	"ru": {
+		months:   [12]string{"январь", "февраль", "март", "апрель", "май", "июнь", "июль", "август", "сентябрь", "октябрь", "ноябрь", "декабрь"},
+		weekdays: [7]string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"},
+	},
+	"sv": {
+		months:   [12]string{"januari", "februari", "mars", "april", "maj", "juni", "juli", "augusti", "september", "oktober", "november", "december"},
+		weekdays: [7]string{"sö", "må", "ti", "on", "to", "fr", "lö"},
+	},
+	"tr": {
+		months:   [12]string{"Ocak", "Şubat", "Mart", "Nisan", "Mayıs", "Haziran", "Temmuz", "Ağustos", "Eylül", "Ekim", "Kasım", "Aralık"},
+		weekdays: [7]string{"Pz", "Pt", "Sa", "Ça", "Pe", "Cu", "Ct"},
+	},
+	"uk": {
+		months:   [12]string{"січень", "лютий", "березень", "квітень", "травень", "червень", "липень", "серпень", "вересень", "жовтень", "листопад", "грудень"},
+		weekdays: [7]string{"нд", "пн", "вт", "ср", "чт", "пт", "сб"},
+	},
+	"zh": {
+		months:   [12]string{"一月", "二月", "三月", "四月", "五月", "六月", "七月", "八月", "九月", "十月", "十一月", "十二月"},
+		weekdays: [7]string{"日", "一", "二", "三", "四", "五", "六"},
+	},
+}
+
+// sundayRegions and saturdayRegions list the regions where weeks start on
+// Sunday and Saturday. Weeks start on Monday elsewhere.
+var (
+	sundayRegions = map[string]bool{
+		"AG": true, "AS": true, "BR": true, "BS": true, "BT": true, "BW": true,
+		"BZ": true, "CA": true, "CO": true, "DM": true, "DO": true, "ET": true,
+		"GT": true, "GU": true, "HK": true, "HN": true, "ID": true, "IL": true,
+		"IN": true, "JM": true, "JP": true, "KE": true, "KH": true, "KR": true,
+		"LA": true, "MH": true, "MM": true, "MO": true, "MT": true, "MX": true,
+		"MZ": true, "NI": true, "NP": true, "PA": true, "PE": true, "PH": true,
+		"PK": true, "PR": true, "PT": true, "PY": true, "SA": true, "SG": true,
+		"SV": true, "TH": true, "TT": true, "TW": true, "UM": true, "US": true,
+		"VE": true, "VI": true, "WS": true, "YE": true, "ZA": true, "ZW": true,
+	}
+	saturdayRegions = map[string]bool{
+		"AE": true, "AF": true, "BH": true, "DJ": true, "DZ": true, "EG": true,
+		"IQ": true, "IR": true, "JO": true, "KW": true, "LY": true, "OM": true,
+		"QA": true, "SD": true, "SY": true,
+	}
+	// hour12Regions list the regions that use a 12-hour clock.
+	hour12Regions = map[string]bool{
+		"AU": true, "BD": true, "CA": true, "EG": true, "IN": true, "NZ": true,
+		"PH": true, "PK": true, "SA": true, "US": true,
+	}
+)
+
+// localeRegion returns the region of the locale, guessing it from the
+// language if it is not specified.
+func localeRegion(l system.Locale) string {
+	tag, err := language.Parse(l.Language)
+	if err != nil {
+		return ""
+	}
+	r, _ := tag.Region()
+	return r.String()
+}
+
+func localeCalendar(l system.Locale) *calendarNames {
+	if tag, err := language.Parse(l.Language); err == nil {
+		base, _ := tag.Base()
+		b := base.String()
+		if b == "no" || b == "nn" {
+			b = "nb"
+		}
+		if c, ok := calendars[b]; ok {
+			return c
+		}
+	}
+	return calendars["en"]
+}
+
+// FirstWeekday returns the first day of the week in the region of the
+// locale.
+func FirstWeekday(l system.Locale) time.Weekday {
+	r := localeRegion(l)
+	switch {
+	case sundayRegions[r]:
+		return time.Sunday
+	case saturdayRegions[r]:
+		return time.Saturday
+	default:
+		return time.Monday
+	}
+}
+
+// MonthName returns the name of the month in the language of the locale.
+// English names are returned for unknown languages.
+func MonthName(l system.Locale, m time.Month) string {
+	return localeCalendar(l).months[m-time.January]
+}
+
+// WeekdayName returns the abbreviated name of the day in the language of
+// the locale. English names are returned for unknown languages.
+func WeekdayName(l system.Locale, d time.Weekday) string {
+	return localeCalendar(l).weekdays[d]
+}
+
+// Uses12Hour reports whether the region of the locale uses a 12-hour
+// clock.
+func Uses12Hour(l system.Locale) bool {
+	return hour12Regions[localeRegion(l)]
+}
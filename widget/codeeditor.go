@@ -0,0 +1,328 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gioui.org/font"
+	"gioui.org/gesture"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+)
+
+// CodeEditor is an Editor for source code. It has a gutter for line
+// numbers, fold markers and annotations, indents new lines like the line
+// before them, and highlights matching brackets.
+type CodeEditor struct {
+	Editor
+	// Indent is the indentation added to lines that follow an opening
+	// bracket. If empty, a tab is used.
+	Indent string
+	// Brackets lists pairs of opening and closing brackets. If empty,
+	// "()[]{}" is used.
+	Brackets string
+
+	annotations []Annotation
+	folds       []Fold
+	lines       []LineInfo
+	regions     []Region
+	gutter      gesture.Click
+}
+
+// Annotation is a message about a line, such as a compiler error.
+type Annotation struct {
+	// Line is the zero-based line number.
+	Line     int
+	Severity Severity
+	Message  string
+}
+
+// Fold is a range of lines that can be folded. Hiding the folded lines is
+// up to the application.
+type Fold struct {
+	// Start and End are the zero-based numbers of the first and last line.
+	Start, End int
+	Folded     bool
+}
+
+// A FoldEvent is generated when the fold marker of a Fold is clicked.
+type FoldEvent struct {
+	Fold Fold
+}
+
+func (FoldEvent) isEditorEvent() {}
+
+// SetAnnotations replaces the annotations.
+func (c *CodeEditor) SetAnnotations(annotations []Annotation) {
+	c.annotations = append(c.annotations[:0], annotations...)
+	slices.SortStableFunc(c.annotations, func(a, b Annotation) int {
+		return a.Line - b.Line
+	})
+}
+
+// Annotations returns the annotations of a line.
+func (c *CodeEditor) Annotations(line int) []Annotation {
+	i, _ := slices.BinarySearchFunc(c.annotations, line, func(a Annotation, line int) int {
+		return a.Line - line
+	})
+	j := i
+	for j < len(c.annotations) && c.annotations[j].Line == line {
+		j++
+	}
+	return c.annotations[i:j]
+}
+
+// SetFolds replaces the foldable ranges of lines.
+func (c *CodeEditor) SetFolds(folds []Fold) {
+	c.folds = append(c.folds[:0], folds...)
+}
+
+// Folds returns the foldable ranges of lines.
+func (c *CodeEditor) Folds() []Fold {
+	return c.folds
+}
+
+// FoldAt returns the fold that starts at a line.
+func (c *CodeEditor) FoldAt(line int) (Fold, bool) {
+	for _, f := range c.folds {
+		if f.Start == line {
+			return f, true
+		}
+	}
+	return Fold{}, false
+}
+
+// CaretLine returns the visible line of the caret, as of the last layout.
+func (c *CodeEditor) CaretLine() (LineInfo, bool) {
+	c.initBuffer()
+	start, _ := c.text.Selection()
+	for _, l := range c.lines {
+		if l.Start <= start && (start < l.End || l.End == c.text.Len()) {
+			return l, true
+		}
+	}
+	return LineInfo{}, false
+}
+
+// Update the state of the editor in response to input events. It is like
+// Editor.Update, but also generates FoldEvents.
+func (c *CodeEditor) Update(gtx layout.Context) (EditorEvent, bool) {
+	c.Editor.indent = c.indentation
+	for {
+		ev, ok := c.gutter.Update(gtx.Source)
+		if !ok {
+			break
+		}
+		if ev.Kind != gesture.KindClick {
+			continue
+		}
+		for _, l := range c.lines {
+			if ev.Position.Y < l.Top || ev.Position.Y >= l.Bottom {
+				continue
+			}
+			for i := range c.folds {
+				if f := &c.folds[i]; f.Start == l.Line {
+					f.Folded = !f.Folded
+					return FoldEvent{Fold: *f}, true
+				}
+			}
+		}
+	}
+	return c.Editor.Update(gtx)
+}
+
+// Lines returns the visible lines of the last layout.
+func (c *CodeEditor) Lines() []LineInfo {
+	return c.lines
+}
+
+// Layout lays out the editor to the right of a gutter that is gutterWidth
+// pixels wide. The gutter widget is laid out after the editor, and may use
+// Lines to draw the line numbers, markers and annotations of the visible
+// lines. The background of the caret line is painted with lineMaterial
+// and the background of matching brackets with matchMaterial.
+func (c *CodeEditor) Layout(gtx layout.Context, lt *text.Shaper, font font.Font, size unit.Sp, gutterWidth int, gutter layout.Widget, textMaterial, selectMaterial, lineMaterial, matchMaterial op.CallOp) layout.Dimensions {
+	for {
+		_, ok := c.Update(gtx)
+		if !ok {
+			break
+		}
+	}
+	egtx := gtx
+	egtx.Constraints.Min.X = max(egtx.Constraints.Min.X-gutterWidth, 0)
+	egtx.Constraints.Max.X = max(egtx.Constraints.Max.X-gutterWidth, 0)
+	macro := op.Record(gtx.Ops)
+	dims := c.Editor.Layout(egtx, lt, font, size, textMaterial, selectMaterial)
+	call := macro.Stop()
+	c.lines = c.Editor.Lines(c.lines)
+	dims.Size.X += gutterWidth
+
+	if l, ok := c.CaretLine(); ok {
+		rect := clip.Rect{Min: image.Pt(0, l.Top), Max: image.Pt(dims.Size.X, l.Bottom)}.Push(gtx.Ops)
+		lineMaterial.Add(gtx.Ops)
+		paint.PaintOp{}.Add(gtx.Ops)
+		rect.Pop()
+	}
+
+	gsize := image.Pt(gutterWidth, dims.Size.Y)
+	area := clip.Rect{Max: gsize}.Push(gtx.Ops)
+	pointer.CursorPointer.Add(gtx.Ops)
+	c.gutter.Add(gtx.Ops)
+	ggtx := gtx
+	ggtx.Constraints = layout.Exact(gsize)
+	gutter(ggtx)
+	area.Pop()
+
+	defer op.Offset(image.Pt(gutterWidth, 0)).Push(gtx.Ops).Pop()
+	if open, close, ok := c.MatchingBrackets(); ok {
+		for _, r := range [2]int{open, close} {
+			c.regions = c.Editor.Regions(r, r+1, c.regions)
+			for _, reg := range c.regions {
+				area := clip.Rect(reg.Bounds).Push(gtx.Ops)
+				matchMaterial.Add(gtx.Ops)
+				paint.PaintOp{}.Add(gtx.Ops)
+				area.Pop()
+			}
+		}
+	}
+	call.Add(gtx.Ops)
+	return dims
+}
+
+// brackets returns the pairs of brackets.
+func (c *CodeEditor) brackets() []rune {
+	if c.Brackets == "" {
+		return []rune("()[]{}")
+	}
+	return []rune(c.Brackets)
+}
+
+// maxBracketDistance limits the number of runes searched for a matching
+// bracket.
+const maxBracketDistance = 1 << 16
+
+// MatchingBrackets returns the rune offsets of the bracket next to the
+// caret and its matching bracket, in text order. The bracket before the
+// caret is preferred.
+func (c *CodeEditor) MatchingBrackets() (open, close int, ok bool) {
+	c.initBuffer()
+	start, end := c.text.Selection()
+	if start != end {
+		return 0, 0, false
+	}
+	off := c.text.ByteOffset(start)
+	brackets := c.brackets()
+	if r, n, err := c.text.ReadRuneBefore(off); err == nil {
+		if m, ok := c.matchBracket(brackets, r, start-1, off-int64(n)); ok {
+			return min(start-1, m), max(start-1, m), true
+		}
+	}
+	if r, _, err := c.text.ReadRuneAt(off); err == nil {
+		if m, ok := c.matchBracket(brackets, r, start, off); ok {
+			return min(start, m), max(start, m), true
+		}
+	}
+	return 0, 0, false
+}
+
+// matchBracket returns the rune offset of the bracket matching the
+// bracket b at rune offset pos and byte offset off.
+func (c *CodeEditor) matchBracket(brackets []rune, b rune, pos int, off int64) (int, bool) {
+	i := slices.Index(brackets, b)
+	if i == -1 || i+1 == len(brackets) && i%2 == 0 {
+		return 0, false
+	}
+	depth := 0
+	if i%2 == 0 {
+		// Search forward for the closing bracket.
+		pair := brackets[i+1]
+		for n := 0; n < maxBracketDistance; n++ {
+			r, size, err := c.text.ReadRuneAt(off)
+			if err != nil {
+				break
+			}
+			switch r {
+			case b:
+				depth++
+			case pair:
+				depth--
+			}
+			if depth == 0 {
+				return pos, true
+			}
+			off += int64(size)
+			pos++
+		}
+		return 0, false
+	}
+	// Search backward for the opening bracket.
+	pair := brackets[i-1]
+	off += int64(len(string(b)))
+	for n := 0; n < maxBracketDistance; n++ {
+		r, size, err := c.text.ReadRuneBefore(off)
+		if err != nil {
+			break
+		}
+		switch r {
+		case b:
+			depth++
+		case pair:
+			depth--
+		}
+		if depth == 0 {
+			return pos, true
+		}
+		off -= int64(size)
+		pos--
+	}
+	return 0, false
+}
+
+// indentation returns the indentation of a new line inserted at the caret:
+// the indentation of the caret line, and Indent if the text before the
+// caret ends with an opening bracket.
+func (c *CodeEditor) indentation() string {
+	start, end := c.text.Selection()
+	off := c.text.ByteOffset(min(start, end))
+	brackets := c.brackets()
+	// Find the start of the line, and the last rune before the caret that
+	// isn't a space.
+	lineStart := off
+	var last rune
+	for lineStart > 0 {
+		r, n, err := c.text.ReadRuneBefore(lineStart)
+		if err != nil || r == '\n' {
+			break
+		}
+		if last == 0 && !unicode.IsSpace(r) {
+			last = r
+		}
+		lineStart -= int64(n)
+	}
+	var indent strings.Builder
+	for pos := lineStart; pos < off; {
+		r, n, err := c.text.ReadRuneAt(pos)
+		if err != nil || r != ' ' && r != '\t' {
+			break
+		}
+		indent.WriteRune(r)
+		pos += int64(n)
+	}
+	if i := slices.Index(brackets, last); i != -1 && i%2 == 0 {
+		if c.Indent == "" {
+			indent.WriteString("\t")
+		} else {
+			indent.WriteString(c.Indent)
+		}
+	}
+	return indent.String()
+}
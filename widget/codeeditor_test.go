@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/font"
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+)
+
+func TestEditorLines(t *testing.T) {
+	e := new(Editor)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(60, 1000)),
+		Locale:      english,
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e.SetText("one\nthe second line wraps\n\nfour\n")
+	e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	lines := e.Lines(nil)
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, want 5: %+v", len(lines), lines)
+	}
+	starts := []int{0, 4, 26, 27, 32}
+	for i, l := range lines {
+		if l.Line != i || l.Start != starts[i] {
+			t.Errorf("line %d: got number %d and start %d, want %d and %d", i, l.Line, l.Start, i, starts[i])
+		}
+		if i > 0 && (l.Top < lines[i-1].Baseline || l.Baseline <= lines[i-1].Baseline) {
+			t.Errorf("line %d at %+v is not below %+v", i, l, lines[i-1])
+		}
+	}
+	if h0, h1 := lines[0].Bottom-lines[0].Top, lines[1].Bottom-lines[1].Top; h1 < 2*h0-2 {
+		t.Errorf("wrapped line height %d, want at least two lines of height %d", h1, h0)
+	}
+}
+
+func TestCodeEditorIndent(t *testing.T) {
+	c := new(CodeEditor)
+	c.Indent = "  "
+	r := new(input.Router)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 200)),
+		Source:      r.Source(),
+		Locale:      english,
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	layoutEditor := func() {
+		c.Layout(gtx, cache, font.Font{}, unit.Sp(10), 20, func(gtx layout.Context) layout.Dimensions {
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		}, op.CallOp{}, op.CallOp{}, op.CallOp{}, op.CallOp{})
+	}
+	gtx.Execute(key.FocusCmd{Tag: &c.Editor})
+	c.SetText("\tif x {")
+	c.SetCaret(c.Len(), c.Len())
+	layoutEditor()
+	r.Frame(gtx.Ops)
+	r.Queue(key.Event{Name: key.NameReturn, State: key.Press})
+	layoutEditor()
+	if got, want := c.Text(), "\tif x {\n\t  "; got != want {
+		t.Errorf("got %q after Return, want %q", got, want)
+	}
+	c.Insert("y")
+	r.Queue(key.Event{Name: key.NameReturn, State: key.Press})
+	layoutEditor()
+	if got, want := c.Text(), "\tif x {\n\t  y\n\t  "; got != want {
+		t.Errorf("got %q after second Return, want %q", got, want)
+	}
+}
+
+func TestCodeEditorBrackets(t *testing.T) {
+	c := new(CodeEditor)
+	c.SetText("a(b[c]d)e")
+	tests := []struct {
+		caret       int
+		open, close int
+		ok          bool
+	}{
+		{caret: 0},
+		{caret: 1, open: 1, close: 7, ok: true},
+		{caret: 2, open: 1, close: 7, ok: true},
+		{caret: 6, open: 3, close: 5, ok: true},
+		{caret: 8, open: 1, close: 7, ok: true},
+		{caret: 9},
+	}
+	for _, tc := range tests {
+		c.SetCaret(tc.caret, tc.caret)
+		open, close, ok := c.MatchingBrackets()
+		if ok != tc.ok || ok && (open != tc.open || close != tc.close) {
+			t.Errorf("caret %d: got (%d, %d, %v), want (%d, %d, %v)", tc.caret, open, close, ok, tc.open, tc.close, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"gioui.org/gesture"
+	"gioui.org/internal/f32color"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+)
+
+// ColorPicker is for selecting a color with a saturation and value square,
+// hue and alpha sliders, a hexadecimal input and a set of swatches.
+//
+// Use SetColor to set the initial color; the zero value picks opaque black.
+type ColorPicker struct {
+	// Hue and Alpha are the sliders for the hue and alpha components of
+	// the color.
+	Hue, Alpha Float
+	// Hex is the editor for entering colors in the #RRGGBB or #RRGGBBAA
+	// formats.
+	Hex Editor
+	// Swatches is the list of predefined colors.
+	Swatches []color.NRGBA
+
+	sat, val float32
+	init     bool
+	drag     gesture.Drag
+	// size is the size of the saturation and value square.
+	size     image.Point
+	swatches []Clickable
+	// hex is the text most recently written to Hex.
+	hex string
+}
+
+// Color returns the selected color.
+func (p *ColorPicker) Color() color.NRGBA {
+	p.initialize()
+	return f32color.HSVToNRGBA(p.Hue.Value, p.sat, p.val, uint8(p.Alpha.Value*0xff+.5))
+}
+
+// SetColor sets the selected color.
+func (p *ColorPicker) SetColor(c color.NRGBA) {
+	p.init = true
+	p.setColor(c)
+	p.writeHex()
+}
+
+// setColor updates the components of the picker without changing the hue of
+// gray colors or the saturation of black.
+func (p *ColorPicker) setColor(c color.NRGBA) {
+	h, s, v := f32color.NRGBAToHSV(c)
+	if v > 0 {
+		if s > 0 {
+			p.Hue.Value = h
+		}
+		p.sat = s
+	}
+	p.val = v
+	p.Alpha.Value = float32(c.A) / 0xff
+}
+
+func (p *ColorPicker) initialize() {
+	if !p.init {
+		p.SetColor(color.NRGBA{A: 0xff})
+	}
+}
+
+func (p *ColorPicker) writeHex() {
+	p.Hex.SingleLine = true
+	p.Hex.Submit = true
+	p.hex = FormatHexColor(p.Color())
+	p.Hex.SetText(p.hex)
+}
+
+// SaturationValue returns the saturation and value components of the
+// selected color, in the [0; 1] range.
+func (p *ColorPicker) SaturationValue() (sat, val float32) {
+	p.initialize()
+	return p.sat, p.val
+}
+
+// Swatch returns the clickable for the ith swatch.
+func (p *ColorPicker) Swatch(i int) *Clickable {
+	if n := len(p.Swatches); len(p.swatches) < n {
+		p.swatches = append(p.swatches, make([]Clickable, n-len(p.swatches))...)
+	}
+	return &p.swatches[i]
+}
+
+// Dragging reports whether the saturation and value square is being
+// dragged.
+func (p *ColorPicker) Dragging() bool {
+	return p.drag.Dragging()
+}
+
+// Update the state of the picker and report whether the color was changed
+// by the user.
+func (p *ColorPicker) Update(gtx layout.Context) bool {
+	p.initialize()
+	changed := p.Hue.Update(gtx)
+	changed = p.Alpha.Update(gtx) || changed
+	for {
+		e, ok := p.drag.Update(gtx.Metric, gtx.Source, gesture.Both)
+		if !ok {
+			break
+		}
+		if p.size.X <= 0 || p.size.Y <= 0 {
+			continue
+		}
+		if e.Kind == pointer.Press || e.Kind == pointer.Drag {
+			p.sat = clamp01(e.Position.X / float32(p.size.X))
+			p.val = 1 - clamp01(e.Position.Y/float32(p.size.Y))
+			changed = true
+		}
+	}
+	for i, c := range p.Swatches {
+		if p.Swatch(i).Clicked(gtx) {
+			p.setColor(c)
+			changed = true
+		}
+	}
+	if changed {
+		p.writeHex()
+	}
+	for {
+		e, ok := p.Hex.Update(gtx)
+		if !ok {
+			break
+		}
+		switch e.(type) {
+		case ChangeEvent:
+			txt := p.Hex.Text()
+			if txt == p.hex {
+				break
+			}
+			p.hex = txt
+			if c, err := ParseHexColor(txt); err == nil {
+				p.setColor(c)
+				changed = true
+			}
+		case SubmitEvent:
+			// Replace invalid or abbreviated input.
+			p.writeHex()
+		}
+	}
+	return changed
+}
+
+// LayoutSaturationValue adds the input area of the saturation and value
+// square, sized by the minimum constraints. Saturation increases to the
+// right and value increases upwards.
+func (p *ColorPicker) LayoutSaturationValue(gtx layout.Context) layout.Dimensions {
+	p.Update(gtx)
+	p.size = gtx.Constraints.Min
+	defer clip.Rect{Max: p.size}.Push(gtx.Ops).Pop()
+	p.drag.Add(gtx.Ops)
+	pointer.CursorCrosshair.Add(gtx.Ops)
+	return layout.Dimensions{Size: p.size}
+}
+
+// FormatHexColor formats c in the #RRGGBB format, or #RRGGBBAA if c is
+// translucent.
+func FormatHexColor(c color.NRGBA) string {
+	if c.A == 0xff {
+		return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("#%02X%02X%02X%02X", c.R, c.G, c.B, c.A)
+}
+
+// ParseHexColor parses a color in the #RRGGBB or #RRGGBBAA formats. The #
+// is optional.
+func ParseHexColor(s string) (color.NRGBA, error) {
+	h := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(h) != 6 && len(h) != 8 {
+		return color.NRGBA{}, fmt.Errorf("widget: invalid hex color %q", s)
+	}
+	v, err := strconv.ParseUint(h, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("widget: invalid hex color %q", s)
+	}
+	if len(h) == 6 {
+		v = v<<8 | 0xff
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+func clamp01(v float32) float32 {
+	switch {
+	case v < 0:
+		return 0
+	case v > 1:
+		return 1
+	default:
+		return v
+	}
+}
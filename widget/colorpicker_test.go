@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/io/input"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/widget"
+)
+
+func TestHexColor(t *testing.T) {
+	tests := []struct {
+		in  string
+		out color.NRGBA
+		hex string
+	}{
+		{"#FF8000", color.NRGBA{R: 0xff, G: 0x80, A: 0xff}, "#FF8000"},
+		{"12345678", color.NRGBA{R: 0x12, G: 0x34, B: 0x56, A: 0x78}, "#12345678"},
+		{" #abcdef ", color.NRGBA{R: 0xab, G: 0xcd, B: 0xef, A: 0xff}, "#ABCDEF"},
+	}
+	for _, tc := range tests {
+		c, err := widget.ParseHexColor(tc.in)
+		if err != nil || c != tc.out {
+			t.Errorf("ParseHexColor(%q) = %v, %v, want %v", tc.in, c, err, tc.out)
+		}
+		if got := widget.FormatHexColor(c); got != tc.hex {
+			t.Errorf("FormatHexColor(%v) = %q, want %q", c, got, tc.hex)
+		}
+	}
+	for _, in := range []string{"", "#FFF", "#GG0000", "#1234567"} {
+		if _, err := widget.ParseHexColor(in); err == nil {
+			t.Errorf("ParseHexColor(%q) succeeded", in)
+		}
+	}
+}
+
+func TestColorPicker(t *testing.T) {
+	var (
+		r input.Router
+		p = widget.ColorPicker{
+			Swatches: []color.NRGBA{{G: 0xff, A: 0xff}},
+		}
+	)
+	gtx := layout.Context{
+		Ops:    new(op.Ops),
+		Source: r.Source(),
+	}
+	if got, want := p.Color(), (color.NRGBA{A: 0xff}); got != want {
+		t.Errorf("zero value color %v, want %v", got, want)
+	}
+	p.SetColor(color.NRGBA{R: 0xff, A: 0xff})
+	if got := p.Hex.Text(); got != "#FF0000" {
+		t.Errorf("hex text %q, want #FF0000", got)
+	}
+	frame := func() {
+		gtx.Reset()
+		gtx.Constraints = layout.Exact(image.Pt(100, 100))
+		p.LayoutSaturationValue(gtx)
+		off := op.Offset(image.Pt(0, 100)).Push(gtx.Ops)
+		gtx.Constraints = layout.Exact(image.Pt(10, 10))
+		p.Swatch(0).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		})
+		off.Pop()
+		r.Frame(gtx.Ops)
+	}
+	frame()
+	// Pick half saturation and value.
+	r.Queue(pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Pt(50, 50)})
+	if !p.Update(gtx) {
+		t.Error("pressing the square didn't change the color")
+	}
+	if got, want := p.Color(), (color.NRGBA{R: 0x80, G: 0x40, B: 0x40, A: 0xff}); got != want {
+		t.Errorf("picked %v, want %v", got, want)
+	}
+	if got := p.Hex.Text(); got != "#804040" {
+		t.Errorf("hex text %q, want #804040", got)
+	}
+	// Drag to the bottom keeps the hue and saturation of black.
+	r.Queue(
+		pointer.Event{Kind: pointer.Move, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Pt(50, 150)},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: f32.Pt(50, 150)},
+	)
+	p.Update(gtx)
+	if s, v := p.SaturationValue(); s != .5 || v != 0 {
+		t.Errorf("saturation and value %v, %v, want 0.5, 0", s, v)
+	}
+
+	p.Hex.SetText("#0000FF80")
+	if !p.Update(gtx) {
+		t.Error("editing the hex text didn't change the color")
+	}
+	if got, want := p.Color(), (color.NRGBA{B: 0xff, A: 0x80}); got != want {
+		t.Errorf("hex input %v, want %v", got, want)
+	}
+	p.Hex.SetText("#00")
+	if p.Update(gtx) {
+		t.Error("invalid hex text changed the color")
+	}
+
+	frame()
+	r.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Pt(5, 105)},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: f32.Pt(5, 105)},
+	)
+	if !p.Update(gtx) {
+		t.Error("clicking a swatch didn't change the color")
+	}
+	if got, want := p.Color(), p.Swatches[0]; got != want {
+		t.Errorf("swatch color %v, want %v", got, want)
+	}
+	if got := p.Hex.Text(); got != "#00FF00" {
+		t.Errorf("hex text %q, want #00FF00", got)
+	}
+}
@@ -0,0 +1,526 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"strings"
+	"time"
+
+	"gioui.org/gesture"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/io/semantic"
+	"gioui.org/io/system"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+)
+
+// DatePicker is a month calendar for selecting a date or a range of dates.
+//
+// Dates are represented by midnight UTC of their day. The time of day and
+// location of dates assigned to the fields are ignored.
+type DatePicker struct {
+	// Start is the selected date or, if Range is set, the first date of the
+	// selected range. The zero value means no selection.
+	Start time.Time
+	// End is the last date of the selected range, if Range is set.
+	End time.Time
+	// Range enables the selection of a range of dates. The first click
+	// selects Start, the second End.
+	Range bool
+	// Min and Max limit the selectable dates. A zero value means no limit.
+	Min, Max time.Time
+	// Prev and Next are the clickables for showing the previous and next
+	// months.
+	Prev, Next Clickable
+
+	month time.Time
+	// cursor is the date with the keyboard cursor.
+	cursor time.Time
+	// picking is set when the first date of a range has been selected.
+	picking bool
+	focused bool
+	// cancel is set when the user dismisses the picker.
+	cancel bool
+	today  time.Time
+	days   [calendarCells]gesture.Click
+}
+
+// DateCell describes a day in the month grid of a DatePicker.
+type DateCell struct {
+	Date time.Time
+	// Outside is set for the days of the adjacent months.
+	Outside bool
+	// Selected is set for the start and end dates of the selection.
+	Selected bool
+	// InRange is set for days inside the selected range.
+	InRange  bool
+	Today    bool
+	Disabled bool
+	// Cursor is set for the day with the keyboard cursor, if the picker
+	// is focused.
+	Cursor  bool
+	Hovered bool
+}
+
+// calendarCells is the number of days in the grid; 6 weeks are enough
+// for any month.
+const calendarCells = 6 * 7
+
+// dateOf returns the date of t as midnight UTC.
+func dateOf(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Month returns the first day of the displayed month.
+func (p *DatePicker) Month() time.Time {
+	return p.month
+}
+
+// ShowMonth displays the month of date t.
+func (p *DatePicker) ShowMonth(t time.Time) {
+	t = dateOf(t)
+	p.month = t.AddDate(0, 0, 1-t.Day())
+	if p.cursor.IsZero() || !p.sameMonth(p.cursor) {
+		p.cursor = p.month
+	}
+}
+
+// Focused reports whether the picker has the keyboard focus.
+func (p *DatePicker) Focused() bool {
+	return p.focused
+}
+
+// Weekdays returns the days of the week in the order of the grid columns.
+func (p *DatePicker) Weekdays(l system.Locale) [7]time.Weekday {
+	first := FirstWeekday(l)
+	var days [7]time.Weekday
+	for i := range days {
+		days[i] = (first + time.Weekday(i)) % 7
+	}
+	return days
+}
+
+func (p *DatePicker) sameMonth(t time.Time) bool {
+	return t.Year() == p.month.Year() && t.Month() == p.month.Month()
+}
+
+// Enabled reports whether date t is within the Min and Max limits.
+func (p *DatePicker) Enabled(t time.Time) bool {
+	t = dateOf(t)
+	if min := dateOf(p.Min); !min.IsZero() && t.Before(min) {
+		return false
+	}
+	if max := dateOf(p.Max); !max.IsZero() && t.After(max) {
+		return false
+	}
+	return true
+}
+
+// first returns the date of the first cell of the grid.
+func (p *DatePicker) first(l system.Locale) time.Time {
+	off := (int(p.month.Weekday()) - int(FirstWeekday(l)) + 7) % 7
+	return p.month.AddDate(0, 0, -off)
+}
+
+func (p *DatePicker) init(gtx layout.Context) {
+	p.today = dateOf(gtx.Now)
+	if !p.month.IsZero() {
+		return
+	}
+	switch {
+	case !p.Start.IsZero():
+		p.ShowMonth(p.Start)
+		p.cursor = dateOf(p.Start)
+	case !p.today.IsZero():
+		p.ShowMonth(p.today)
+		p.cursor = p.today
+	default:
+		p.ShowMonth(time.Now())
+	}
+}
+
+// Update the state of the picker and report whether the user completed a
+// selection.
+func (p *DatePicker) Update(gtx layout.Context) bool {
+	p.init(gtx)
+	if p.Prev.Clicked(gtx) {
+		p.ShowMonth(p.month.AddDate(0, -1, 0))
+	}
+	if p.Next.Clicked(gtx) {
+		p.ShowMonth(p.month.AddDate(0, 1, 0))
+	}
+	first := p.first(gtx.Locale)
+	for i := range p.days {
+		for {
+			e, ok := p.days[i].Update(gtx.Source)
+			if !ok {
+				break
+			}
+			switch e.Kind {
+			case gesture.KindPress:
+				if e.Source == pointer.Mouse {
+					gtx.Execute(key.FocusCmd{Tag: p})
+				}
+			case gesture.KindClick:
+				if p.pick(first.AddDate(0, 0, i)) {
+					return true
+				}
+			}
+		}
+	}
+	for {
+		ev, ok := gtx.Event(
+			key.FocusFilter{Target: p},
+			key.Filter{Focus: p, Name: key.NameLeftArrow},
+			key.Filter{Focus: p, Name: key.NameRightArrow},
+			key.Filter{Focus: p, Name: key.NameUpArrow},
+			key.Filter{Focus: p, Name: key.NameDownArrow},
+			key.Filter{Focus: p, Name: key.NamePageUp, Optional: key.ModShift},
+			key.Filter{Focus: p, Name: key.NamePageDown, Optional: key.ModShift},
+			key.Filter{Focus: p, Name: key.NameHome},
+			key.Filter{Focus: p, Name: key.NameEnd},
+			key.Filter{Focus: p, Name: key.NameReturn},
+			key.Filter{Focus: p, Name: key.NameEnter},
+			key.Filter{Focus: p, Name: key.NameSpace},
+			key.Filter{Focus: p, Name: key.NameEscape},
+		)
+		if !ok {
+			break
+		}
+		switch e := ev.(type) {
+		case key.FocusEvent:
+			p.focused = e.Focus
+		case key.Event:
+			if e.State != key.Press {
+				break
+			}
+			c := p.cursor
+			// Columns run from right to left in right-to-left locales.
+			dir := 1
+			if gtx.Locale.Direction.Progression() == system.TowardOrigin {
+				dir = -1
+			}
+			months := 1
+			if e.Modifiers.Contain(key.ModShift) {
+				months = 12
+			}
+			switch e.Name {
+			case key.NameLeftArrow:
+				c = c.AddDate(0, 0, -dir)
+			case key.NameRightArrow:
+				c = c.AddDate(0, 0, dir)
+			case key.NameUpArrow:
+				c = c.AddDate(0, 0, -7)
+			case key.NameDownArrow:
+				c = c.AddDate(0, 0, 7)
+			case key.NamePageUp:
+				c = addMonths(c, -months)
+			case key.NamePageDown:
+				c = addMonths(c, months)
+			case key.NameHome:
+				c = c.AddDate(0, 0, -((int(c.Weekday()) - int(FirstWeekday(gtx.Locale)) + 7) % 7))
+			case key.NameEnd:
+				c = c.AddDate(0, 0, 6-(int(c.Weekday())-int(FirstWeekday(gtx.Locale))+7)%7)
+			case key.NameReturn, key.NameEnter, key.NameSpace:
+				if p.pick(c) {
+					return true
+				}
+			case key.NameEscape:
+				p.picking = false
+				p.cancel = true
+			}
+			if !p.Enabled(c) {
+				break
+			}
+			p.cursor = c
+			if !p.sameMonth(c) {
+				p.ShowMonth(c)
+			}
+		}
+	}
+	return false
+}
+
+// addMonths adds n months to t, clamping the day to the length of the
+// resulting month.
+func addMonths(t time.Time, n int) time.Time {
+	first := t.AddDate(0, 0, 1-t.Day()).AddDate(0, n, 0)
+	last := first.AddDate(0, 1, -1)
+	return first.AddDate(0, 0, min(t.Day(), last.Day())-1)
+}
+
+// pick selects date t and reports whether the selection is complete.
+func (p *DatePicker) pick(t time.Time) bool {
+	if !p.Enabled(t) {
+		return false
+	}
+	p.cursor = t
+	if !p.sameMonth(t) {
+		p.ShowMonth(t)
+	}
+	if !p.Range {
+		p.Start = t
+		return true
+	}
+	if !p.picking {
+		p.Start, p.End = t, time.Time{}
+		p.picking = true
+		return false
+	}
+	p.picking = false
+	if start := dateOf(p.Start); t.Before(start) {
+		p.Start, p.End = t, start
+	} else {
+		p.Start, p.End = start, t
+	}
+	return true
+}
+
+// cell describes the day of the cell at index i.
+func (p *DatePicker) cell(first time.Time, i int) DateCell {
+	d := first.AddDate(0, 0, i)
+	start, end := dateOf(p.Start), dateOf(p.End)
+	c := DateCell{
+		Date:     d,
+		Outside:  !p.sameMonth(d),
+		Today:    d.Equal(p.today),
+		Disabled: !p.Enabled(d),
+		Cursor:   p.focused && d.Equal(p.cursor),
+		Hovered:  p.days[i].Hovered(),
+	}
+	c.Selected = d.Equal(start) || (p.Range && d.Equal(end))
+	c.InRange = p.Range && !end.IsZero() && d.After(start) && d.Before(end)
+	return c
+}
+
+// Layout the grid of days of the displayed month, 6 rows of 7 days each.
+// The cells have equal widths and the height of the first cell.
+func (p *DatePicker) Layout(gtx layout.Context, day func(gtx layout.Context, c DateCell) layout.Dimensions) layout.Dimensions {
+	p.Update(gtx)
+	first := p.first(gtx.Locale)
+	cellW := gtx.Constraints.Max.X / 7
+	// Measure the height of the cells.
+	measure := gtx
+	measure.Constraints = layout.Constraints{
+		Min: image.Pt(cellW, 0),
+		Max: image.Pt(cellW, gtx.Constraints.Max.Y/6),
+	}
+	rec := op.Record(gtx.Ops)
+	cellH := day(measure, p.cell(first, 0)).Size.Y
+	rec.Stop()
+	rtl := gtx.Locale.Direction.Progression() == system.TowardOrigin
+	macro := op.Record(gtx.Ops)
+	for i := range p.days {
+		gtx := gtx
+		gtx.Constraints = layout.Exact(image.Pt(cellW, cellH))
+		col, row := i%7, i/7
+		if rtl {
+			col = 6 - col
+		}
+		off := op.Offset(image.Pt(col*cellW, row*cellH)).Push(gtx.Ops)
+		cl := clip.Rect{Max: image.Pt(cellW, cellH)}.Push(gtx.Ops)
+		c := p.cell(first, i)
+		if !c.Disabled {
+			p.days[i].Add(gtx.Ops)
+		}
+		semantic.SelectedOp(c.Selected).Add(gtx.Ops)
+		semantic.EnabledOp(!c.Disabled).Add(gtx.Ops)
+		semantic.LabelOp(c.Date.Format("2006-01-02")).Add(gtx.Ops)
+		day(gtx, c)
+		cl.Pop()
+		off.Pop()
+	}
+	call := macro.Stop()
+	size := gtx.Constraints.Constrain(image.Pt(cellW*7, cellH*6))
+	defer clip.Rect{Max: size}.Push(gtx.Ops).Pop()
+	event.Op(gtx.Ops, p)
+	call.Add(gtx.Ops)
+	return layout.Dimensions{Size: size}
+}
+
+// DateField is an Editor for entering a date, along with a DatePicker shown
+// in a popup below the editor. The popup is toggled by Button or by pressing
+// F4 in the editor, and closed when the user picks a date, presses Escape,
+// or presses the pointer outside the popup.
+type DateField struct {
+	Editor Editor
+	Picker DatePicker
+	// Button toggles the popup.
+	Button Clickable
+	// Format is the layout, as defined by time.Parse, of the dates in the
+	// editor. If empty, "2006-01-02" is used.
+	Format string
+
+	open bool
+	// focusPicker is set when the picker should acquire the keyboard focus.
+	focusPicker bool
+	scrim       struct{}
+}
+
+// dateRangeSep separates the dates of a range in the editor.
+const dateRangeSep = " – "
+
+func (f *DateField) format() string {
+	if f.Format == "" {
+		return "2006-01-02"
+	}
+	return f.Format
+}
+
+// Open the popup and move the keyboard focus to the picker.
+func (f *DateField) Open() {
+	f.open = true
+	f.focusPicker = true
+	if !f.Picker.Start.IsZero() {
+		f.Picker.ShowMonth(f.Picker.Start)
+		f.Picker.cursor = dateOf(f.Picker.Start)
+	}
+}
+
+// Close the popup.
+func (f *DateField) Close() {
+	f.open = false
+	f.Picker.picking = false
+}
+
+// Opened reports whether the popup is open.
+func (f *DateField) Opened() bool {
+	return f.open
+}
+
+// Update the state of the field and report whether the date was changed by
+// the user, either in the picker or by editing the text.
+func (f *DateField) Update(gtx layout.Context) bool {
+	changed := false
+	if f.Button.Clicked(gtx) {
+		if f.open {
+			f.Close()
+		} else {
+			f.Open()
+		}
+	}
+	for {
+		ev, ok := gtx.Event(key.Filter{Focus: &f.Editor, Name: key.NameF4})
+		if !ok {
+			break
+		}
+		if e, ok := ev.(key.Event); ok && e.State == key.Press {
+			f.Open()
+		}
+	}
+	for {
+		ev, ok := f.Editor.Update(gtx)
+		if !ok {
+			break
+		}
+		switch ev.(type) {
+		case ChangeEvent, SubmitEvent:
+			if f.parse() {
+				changed = true
+			}
+		}
+	}
+	if !f.open {
+		return changed
+	}
+	for {
+		ev, ok := gtx.Event(pointer.Filter{Target: &f.scrim, Kinds: pointer.Press})
+		if !ok {
+			break
+		}
+		if _, ok := ev.(pointer.Event); ok {
+			f.Close()
+		}
+	}
+	if f.Picker.Update(gtx) {
+		f.Editor.SetText(f.text())
+		f.Close()
+		gtx.Execute(key.FocusCmd{Tag: &f.Editor})
+		changed = true
+	}
+	if f.Picker.cancel {
+		f.Picker.cancel = false
+		f.Close()
+		gtx.Execute(key.FocusCmd{Tag: &f.Editor})
+	}
+	if f.open && f.focusPicker {
+		gtx.Execute(key.FocusCmd{Tag: &f.Picker})
+		// The picker can't be focused before it is laid out; try again in
+		// the next frame.
+		f.focusPicker = !gtx.Focused(&f.Picker)
+	}
+	return changed
+}
+
+// text formats the selection of the picker.
+func (f *DateField) text() string {
+	p := &f.Picker
+	if p.Start.IsZero() {
+		return ""
+	}
+	txt := p.Start.Format(f.format())
+	if p.Range && !p.End.IsZero() {
+		txt += dateRangeSep + p.End.Format(f.format())
+	}
+	return txt
+}
+
+// parse updates the picker from the editor text and reports whether the
+// selection changed.
+func (f *DateField) parse() bool {
+	p := &f.Picker
+	txt := f.Editor.Text()
+	start, end := txt, ""
+	if p.Range {
+		start, end, _ = strings.Cut(txt, dateRangeSep)
+	}
+	s, err := time.Parse(f.format(), strings.TrimSpace(start))
+	if err != nil || !p.Enabled(s) {
+		return false
+	}
+	var e time.Time
+	if p.Range {
+		e, err = time.Parse(f.format(), strings.TrimSpace(end))
+		if err != nil || !p.Enabled(e) || e.Before(s) {
+			return false
+		}
+	}
+	s, e = dateOf(s), dateOf(e)
+	if s.Equal(dateOf(p.Start)) && e.Equal(dateOf(p.End)) {
+		return false
+	}
+	p.Start, p.End = s, e
+	p.ShowMonth(s)
+	return true
+}
+
+// Layout the field and, if the popup is open, the picker below it. The
+// picker is deferred to be drawn on top of the content laid out after
+// the field.
+func (f *DateField) Layout(gtx layout.Context, field, picker layout.Widget) layout.Dimensions {
+	f.Update(gtx)
+	dims := field(gtx)
+	if !f.open {
+		return dims
+	}
+	macro := op.Record(gtx.Ops)
+	// Cover the window to detect presses outside the popup.
+	const inf = 1e6
+	scrim := clip.Rect{Min: image.Pt(-inf, -inf), Max: image.Pt(inf, inf)}.Push(gtx.Ops)
+	event.Op(gtx.Ops, &f.scrim)
+	scrim.Pop()
+	off := op.Offset(image.Pt(0, dims.Size.Y)).Push(gtx.Ops)
+	gtx.Constraints.Min = image.Point{}
+	picker(gtx)
+	off.Pop()
+	op.Defer(gtx.Ops, macro.Stop())
+	return dims
+}
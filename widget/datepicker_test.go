@@ -0,0 +1,244 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget_test
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"gioui.org/f32"
+	"gioui.org/font"
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/io/system"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/widget"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCalendarLocale(t *testing.T) {
+	tests := []struct {
+		lang    string
+		first   time.Weekday
+		month   string
+		weekday string
+		hour12  bool
+	}{
+		{"en-US", time.Sunday, "March", "We", true},
+		{"en-GB", time.Monday, "March", "We", false},
+		{"de", time.Monday, "März", "Mi", false},
+		{"ar-EG", time.Saturday, "March", "We", true},
+		{"pt-BR", time.Sunday, "março", "qua", false},
+		{"", time.Monday, "March", "We", false},
+	}
+	for _, tc := range tests {
+		l := system.Locale{Language: tc.lang}
+		if got := widget.FirstWeekday(l); got != tc.first {
+			t.Errorf("%q: first weekday %v, want %v", tc.lang, got, tc.first)
+		}
+		if got := widget.MonthName(l, time.March); got != tc.month {
+			t.Errorf("%q: month name %q, want %q", tc.lang, got, tc.month)
+		}
+		if got := widget.WeekdayName(l, time.Wednesday); got != tc.weekday {
+			t.Errorf("%q: weekday name %q, want %q", tc.lang, got, tc.weekday)
+		}
+		if got := widget.Uses12Hour(l); got != tc.hour12 {
+			t.Errorf("%q: 12-hour clock %v, want %v", tc.lang, got, tc.hour12)
+		}
+	}
+}
+
+func TestDatePicker(t *testing.T) {
+	var (
+		r     input.Router
+		p     widget.DatePicker
+		cells []widget.DateCell
+	)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Source:      r.Source(),
+		Constraints: layout.Exact(image.Pt(70, 60)),
+		Locale:      system.Locale{Language: "de-DE"},
+		Now:         date(2024, time.February, 14).Add(15 * time.Hour),
+	}
+	p.Max = date(2024, time.March, 5)
+	frame := func() {
+		gtx.Reset()
+		cells = cells[:0]
+		p.Layout(gtx, func(gtx layout.Context, c widget.DateCell) layout.Dimensions {
+			cells = append(cells, c)
+			return layout.Dimensions{Size: image.Pt(10, 10)}
+		})
+		r.Frame(gtx.Ops)
+	}
+	frame()
+	// The grid starts with the Monday before February 1st, 2024 and
+	// includes the measured cell.
+	cells = cells[1:]
+	if got, want := cells[0].Date, date(2024, time.January, 29); !got.Equal(want) || !cells[0].Outside {
+		t.Errorf("first cell %v (outside %v), want %v", got, cells[0].Outside, want)
+	}
+	if !cells[16].Today {
+		t.Errorf("cell %v not marked today", cells[16].Date)
+	}
+	if !cells[37].Disabled || cells[36].Disabled {
+		t.Error("Max not applied to cells")
+	}
+	// Click February 14th.
+	r.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Pt(25, 25)},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: f32.Pt(25, 25)},
+	)
+	if !p.Update(gtx) || !p.Start.Equal(date(2024, time.February, 14)) {
+		t.Errorf("click selected %v, want 2024-02-14", p.Start)
+	}
+	frame()
+	if !gtx.Focused(&p) {
+		t.Fatal("click didn't focus the picker")
+	}
+	for _, n := range []key.Name{key.NameDownArrow, key.NameRightArrow, key.NameEnd} {
+		r.Queue(key.Event{Name: n, State: key.Press})
+	}
+	frame()
+	r.Queue(key.Event{Name: key.NameReturn, State: key.Press})
+	if !p.Update(gtx) || !p.Start.Equal(date(2024, time.February, 25)) {
+		t.Errorf("keyboard selected %v, want 2024-02-25", p.Start)
+	}
+	// The cursor can't move past Max.
+	r.Queue(
+		key.Event{Name: key.NamePageDown, State: key.Press},
+		key.Event{Name: key.NameReturn, State: key.Press},
+	)
+	if !p.Update(gtx) || !p.Start.Equal(date(2024, time.February, 25)) {
+		t.Errorf("selected %v past Max", p.Start)
+	}
+
+	p.Range = true
+	r.Queue(
+		key.Event{Name: key.NameReturn, State: key.Press},
+		key.Event{Name: key.NameUpArrow, State: key.Press},
+	)
+	if p.Update(gtx) {
+		t.Error("range completed after one date")
+	}
+	r.Queue(key.Event{Name: key.NameReturn, State: key.Press})
+	if !p.Update(gtx) {
+		t.Error("range not completed after two dates")
+	}
+	if !p.Start.Equal(date(2024, time.February, 18)) || !p.End.Equal(date(2024, time.February, 25)) {
+		t.Errorf("range %v-%v, want 2024-02-18-2024-02-25", p.Start, p.End)
+	}
+	frame()
+	if c := cells[1+21]; !c.InRange {
+		t.Errorf("%v not in selected range", c.Date)
+	}
+}
+
+func TestTimePicker(t *testing.T) {
+	var (
+		r input.Router
+		p = widget.TimePicker{Hour: 9, Minute: 7, MinuteStep: 5}
+	)
+	gtx := layout.Context{
+		Ops:    new(op.Ops),
+		Source: r.Source(),
+		Locale: system.Locale{Language: "en-US"},
+	}
+	var texts []string
+	frame := func() {
+		gtx.Reset()
+		texts = texts[:0]
+		p.Layout(gtx, func(gtx layout.Context, c widget.TimeCell) layout.Dimensions {
+			texts = append(texts, c.Text)
+			return layout.Dimensions{Size: image.Pt(20, 20)}
+		})
+		r.Frame(gtx.Ops)
+	}
+	gtx.Execute(key.FocusCmd{Tag: &p})
+	frame()
+	if got := texts; len(got) != 3 || got[0] != "09" || got[1] != "07" || got[2] != "AM" {
+		t.Errorf("fields %v, want [09 07 AM]", got)
+	}
+	press := func(names ...key.Name) {
+		for _, n := range names {
+			r.Queue(key.Event{Name: n, State: key.Press})
+		}
+		frame()
+	}
+	press(key.NameRightArrow, key.NameUpArrow)
+	if p.Minute != 10 {
+		t.Errorf("minute %d after step up, want 10", p.Minute)
+	}
+	press(key.NameDownArrow, key.NameDownArrow)
+	if p.Minute != 0 {
+		t.Errorf("minute %d after two steps down, want 0", p.Minute)
+	}
+	press(key.NameLeftArrow, "1", "1", "4", "5", "P")
+	if p.Hour != 23 || p.Minute != 45 {
+		t.Errorf("typed time %02d:%02d, want 23:45", p.Hour, p.Minute)
+	}
+	gtx.Locale = system.Locale{Language: "fr-FR"}
+	frame()
+	if len(texts) != 2 {
+		t.Errorf("%d fields for a 24-hour clock, want 2", len(texts))
+	}
+}
+
+func TestDateField(t *testing.T) {
+	var (
+		r     input.Router
+		f     = widget.DateField{Format: "02.01.2006"}
+		cache = text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Source:      r.Source(),
+		Constraints: layout.Exact(image.Pt(200, 200)),
+		Now:         date(2024, time.February, 14),
+	}
+	frame := func() {
+		gtx.Reset()
+		f.Layout(gtx,
+			func(gtx layout.Context) layout.Dimensions {
+				return f.Editor.Layout(gtx, cache, font.Font{}, 10, op.CallOp{}, op.CallOp{})
+			},
+			func(gtx layout.Context) layout.Dimensions {
+				return f.Picker.Layout(gtx, func(gtx layout.Context, c widget.DateCell) layout.Dimensions {
+					return layout.Dimensions{Size: image.Pt(10, 10)}
+				})
+			},
+		)
+		r.Frame(gtx.Ops)
+	}
+	f.Editor.SetText("29.02.2024")
+	if !f.Update(gtx) || !f.Picker.Start.Equal(date(2024, time.February, 29)) {
+		t.Errorf("parsed %v, want 2024-02-29", f.Picker.Start)
+	}
+	f.Open()
+	frame()
+	frame()
+	if !gtx.Focused(&f.Picker) {
+		t.Fatal("opening didn't focus the picker")
+	}
+	r.Queue(
+		key.Event{Name: key.NameRightArrow, State: key.Press},
+		key.Event{Name: key.NameReturn, State: key.Press},
+	)
+	if !f.Update(gtx) {
+		t.Error("picking a date didn't change the field")
+	}
+	if got, want := f.Editor.Text(), "01.03.2024"; got != want {
+		t.Errorf("editor text %q, want %q", got, want)
+	}
+	if f.Opened() {
+		t.Error("picking a date didn't close the popup")
+	}
+}
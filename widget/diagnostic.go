@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// Severity is the severity of a diagnostic or an annotation.
+type Severity uint8
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+// Diagnostic is a message about a range of text, such as a misspelling or
+// a validation error.
+type Diagnostic struct {
+	// Start and End are the rune offsets of the range.
+	Start, End int
+	Severity   Severity
+	Message    string
+}
+
+// shiftDiagnostics adjusts diagnostics for the replacement of the runes
+// between start and end with n runes, and removes the diagnostics whose
+// text is deleted.
+func shiftDiagnostics(diags []Diagnostic, start, end, n int) []Diagnostic {
+	kept := diags[:0]
+	for _, d := range diags {
+		d.Start = shiftOffset(d.Start, start, end, n)
+		d.End = shiftOffset(d.End, start, end, n)
+		if d.Start < d.End {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+// paintWave paints a wavy line below the baseline of r with material.
+func paintWave(gtx layout.Context, r Region, material op.CallOp) {
+	amp := float32(max(gtx.Dp(1), 1))
+	width := amp
+	// The wave starts a stroke width below the baseline.
+	y := float32(r.Bounds.Max.Y-r.Baseline) + width + amp
+	x0, x1 := float32(r.Bounds.Min.X), float32(r.Bounds.Max.X)
+	if x1-x0 < 1 {
+		return
+	}
+	halfPeriod := 2 * amp
+	var p clip.Path
+	p.Begin(gtx.Ops)
+	p.MoveTo(f32.Pt(x0, y))
+	dir := float32(-1)
+	for x := x0; x < x1; x += halfPeriod {
+		next := x + halfPeriod
+		if next > x1 {
+			next = x1
+		}
+		// Scale the last half period if it is cut short.
+		frac := (next - x) / halfPeriod
+		p.QuadTo(f32.Pt((x+next)/2, y+2*dir*amp*frac), f32.Pt(next, y))
+		dir = -dir
+	}
+	area := clip.Stroke{Path: p.End(), Width: width}.Op().Push(gtx.Ops)
+	material.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+	area.Pop()
+}
@@ -7,6 +7,7 @@ import (
 	"image"
 	"io"
 	"math"
+	"slices"
 	"strings"
 	"time"
 	"unicode"
@@ -42,6 +43,15 @@ type Editor struct {
 	// LineHeightScale is multiplied by LineHeight to determine the final gap
 	// between baselines. If zero, a sensible default will be used.
 	LineHeightScale float32
+	// Decoration is the set of lines drawn along the text.
+	Decoration text.Decoration
+	// LetterSpacing is added between the characters of the text.
+	LetterSpacing unit.Sp
+	// WordSpacing is added to the spaces between words.
+	WordSpacing unit.Sp
+	// TabWidth is the interval between tab stops. If zero, tabs are as
+	// wide as their glyph.
+	TabWidth unit.Sp
 	// SingleLine force the text to stay on a single line.
 	// SingleLine also sets the scrolling direction to
 	// horizontal.
@@ -61,13 +71,22 @@ type Editor struct {
 	InputHint key.InputHint
 	// MaxLen limits the editor content to a maximum length. Zero means no limit.
 	MaxLen int
+	// HistoryLimit limits the number of undo steps. Zero means no limit.
+	HistoryLimit int
 	// Filter is the list of characters allowed in the Editor. If Filter is empty,
 	// all characters are allowed.
 	Filter string
 	// WrapPolicy configures how displayed text will be broken into lines.
 	WrapPolicy text.WrapPolicy
-
-	buffer *editBuffer
+	// Formatter, if set, transforms the text after every change, such as to
+	// lay it out according to an InputMask. The formatted text is not
+	// subject to Filter and MaxLen, and formatting is undone together with
+	// the change that caused it. Formatting copies the text on every change
+	// and is meant for short fields.
+	Formatter Formatter
+
+	// source stores the text, and defaults to a gap buffer.
+	source TextSource
 	// scratch is a byte buffer that is reused to efficiently read portions of text
 	// from the textView.
 	scratch    []byte
@@ -79,7 +98,14 @@ type Editor struct {
 		scratch []byte
 	}
 
-	dragging    bool
+	dragging bool
+	// primaryStale is set when the selection changed since it was last
+	// written to the primary selection.
+	primaryStale bool
+	// columnStart is the position in the text where a column selection
+	// started, and column is set while it is dragged.
+	columnStart image.Point
+	column      bool
 	dragger     gesture.Drag
 	scroller    gesture.Scroll
 	scrollCaret bool
@@ -88,11 +114,36 @@ type Editor struct {
 	clicker gesture.Click
 
 	// history contains undo history.
-	history []modification
+	history []Modification
 	// nextHistoryIdx is the index within the history of the next modification. This
 	// is only not len(history) immediately after undo operations occur. It is framed as the "next" value
 	// to make the zero value consistent.
 	nextHistoryIdx int
+	// typing is set while text typed by the user is inserted, and coalesce
+	// is set while typed text may be grouped with the previous modification.
+	typing, coalesce bool
+	// txDepth is the nesting depth of transactions, and txStart the history
+	// index at the start of the outermost transaction.
+	txDepth, txStart int
+
+	// styles are the styled ranges set by SetStyles.
+	styles []StyledRange
+	// tokens tracks the styles computed by the tokenizer.
+	tokens tokenCache
+	// restyle is set when the styles of the text must be recomputed.
+	restyle bool
+	// indent, if set, returns the indentation of a new line inserted at
+	// the caret.
+	indent func() string
+	// formatting is set while the Formatter output replaces the text.
+	formatting bool
+
+	// diagnostics are the diagnostics set by SetDiagnostics.
+	diagnostics []Diagnostic
+	regions     []Region
+	// hovered is set while the pointer at hoverPos is over the editor.
+	hovered  bool
+	hoverPos image.Point
 
 	pending []EditorEvent
 }
@@ -197,6 +248,7 @@ func (e *Editor) processEvents(gtx layout.Context) (ev EditorEvent, ok bool) {
 	defer func() {
 		afterSelStart, afterSelEnd := e.Selection()
 		if selStart != afterSelStart || selEnd != afterSelEnd {
+			e.primaryStale = true
 			if ok {
 				e.pending = append(e.pending, SelectEvent{})
 			} else {
@@ -204,17 +256,63 @@ func (e *Editor) processEvents(gtx layout.Context) (ev EditorEvent, ok bool) {
 				ok = true
 			}
 		}
+		// Write the selection to the primary selection once a drag
+		// completes. Masked text is never written.
+		if e.primaryStale && !e.dragging {
+			e.primaryStale = false
+			if text := e.copyText(); text != "" && e.Mask == 0 {
+				writeClipboard(gtx, text, true)
+			}
+		}
 	}()
 
+	if e.Formatter != nil {
+		// Undo and redo the formatting together with the change that
+		// caused it.
+		e.BeginTransaction()
+		defer e.EndTransaction()
+	}
 	ev, ok = e.processPointer(gtx)
-	if ok {
-		return ev, ok
+	if !ok {
+		ev, ok = e.processKey(gtx)
 	}
-	ev, ok = e.processKey(gtx)
-	if ok {
-		return ev, ok
+	if _, changed := ev.(ChangeEvent); changed {
+		e.format()
 	}
-	return nil, false
+	return ev, ok
+}
+
+// format applies the Formatter to the text and the caret. It copies the
+// text, which is fine for the short fields formatters are meant for.
+func (e *Editor) format() {
+	if e.Formatter == nil {
+		return
+	}
+	old := e.Text()
+	caret, _ := e.text.Selection()
+	formatted, pos := e.Formatter.Format(old, caret)
+	if formatted == old {
+		return
+	}
+	// Replace only the runes that changed, to keep the styles and
+	// diagnostics of the rest.
+	o, f := []rune(old), []rune(formatted)
+	prefix := 0
+	for prefix < len(o) && prefix < len(f) && o[prefix] == f[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(o)-prefix && suffix < len(f)-prefix && o[len(o)-1-suffix] == f[len(f)-1-suffix] {
+		suffix++
+	}
+	e.formatting = true
+	e.replace(prefix, len(o)-suffix, string(f[prefix:len(f)-suffix]), true)
+	e.formatting = false
+	pos = max(0, min(pos, len(f)))
+	e.text.ClearCarets()
+	e.text.SetCaret(pos, pos)
+	// The change is reported by the caller.
+	e.text.Changed()
 }
 
 func (e *Editor) processPointer(gtx layout.Context) (EditorEvent, bool) {
@@ -249,6 +347,38 @@ func (e *Editor) processPointer(gtx layout.Context) (EditorEvent, bool) {
 		e.text.ScrollRel(0, sdist)
 		soff = e.text.ScrollOff().Y
 	}
+	kinds := pointer.Enter | pointer.Move | pointer.Leave | pointer.Cancel
+	if gtx.Source.PrimarySupported() && !e.ReadOnly {
+		kinds |= pointer.Press
+	}
+	for {
+		evt, ok := gtx.Event(pointer.Filter{Target: e, Kinds: kinds})
+		if !ok {
+			break
+		}
+		pe, ok := evt.(pointer.Event)
+		if !ok {
+			continue
+		}
+		switch pe.Kind {
+		case pointer.Press:
+			// Paste the primary selection at the position of a middle
+			// click on platforms that have one; the other half is in
+			// Editor.processKey.
+			if pe.Source == pointer.Mouse && pe.Buttons == pointer.ButtonTertiary {
+				e.text.ClearCarets()
+				e.text.MoveCoord(pe.Position.Round())
+				e.text.ClearSelection()
+				e.blinkStart = gtx.Now
+				e.coalesce = false
+				gtx.Execute(key.FocusCmd{Tag: e})
+				gtx.Execute(clipboard.ReadCmd{Tag: e, Types: textTypes, Primary: true})
+			}
+		default:
+			e.hovered = pe.Kind == pointer.Enter || pe.Kind == pointer.Move
+			e.hoverPos = pe.Position.Round()
+		}
+	}
 	for {
 		evt, ok := e.clicker.Update(gtx.Source)
 		if !ok {
@@ -284,10 +414,19 @@ func (e *Editor) processPointerEvent(gtx layout.Context, ev event.Event) (Editor
 			evt.Kind == gesture.KindClick && evt.Source != pointer.Mouse:
 			prevCaretPos, _ := e.text.Selection()
 			e.blinkStart = gtx.Now
-			e.text.MoveCoord(image.Point{
+			e.coalesce = false
+			// Alt+click adds a caret, and Alt+drag selects a column.
+			addCaret := evt.Modifiers == key.ModAlt && evt.NumClicks == 1 && !e.SingleLine
+			if addCaret {
+				e.text.AddCaret()
+			} else {
+				e.text.ClearCarets()
+			}
+			pos := image.Point{
 				X: int(math.Round(float64(evt.Position.X))),
 				Y: int(math.Round(float64(evt.Position.Y))),
-			})
+			}
+			e.text.MoveCoord(pos)
 			gtx.Execute(key.FocusCmd{Tag: e})
 			if !e.ReadOnly {
 				gtx.Execute(key.SoftKeyboardCmd{Show: true})
@@ -307,6 +446,12 @@ func (e *Editor) processPointerEvent(gtx layout.Context, ev event.Event) (Editor
 				e.text.ClearSelection()
 			}
 			e.dragging = true
+			e.column = false
+			if addCaret {
+				e.text.mergeCarets()
+				e.columnStart = pos.Add(e.text.ScrollOff())
+				e.column = true
+			}
 
 			// Process multi-clicks.
 			switch {
@@ -329,10 +474,19 @@ func (e *Editor) processPointerEvent(gtx layout.Context, ev event.Event) (Editor
 		case evt.Kind == pointer.Drag && evt.Source == pointer.Mouse:
 			if e.dragging {
 				e.blinkStart = gtx.Now
-				e.text.MoveCoord(image.Point{
+				pos := image.Point{
 					X: int(math.Round(float64(evt.Position.X))),
 					Y: int(math.Round(float64(evt.Position.Y))),
-				})
+				}
+				if e.column {
+					// Select a column only if the pointer moved, so that
+					// Alt+click adds a caret.
+					if end := pos.Add(e.text.ScrollOff()); end != e.columnStart {
+						e.text.SelectColumn(e.columnStart, end)
+					}
+				} else {
+					e.text.MoveCoord(pos)
+				}
 				e.scrollCaret = true
 
 				if release {
@@ -357,14 +511,17 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 		return ChangeEvent{}, true
 	}
 	caret, _ := e.text.Selection()
-	atBeginning := caret == 0
-	atEnd := caret == e.text.Len()
+	// Other carets may move even if the caret can't.
+	multi := e.text.Carets() > 1
+	atBeginning := caret == 0 && !multi
+	atEnd := caret == e.text.Len() && !multi
 	if gtx.Locale.Direction.Progression() != system.FromOrigin {
 		atEnd, atBeginning = atBeginning, atEnd
 	}
 	filters := []event.Filter{
 		key.FocusFilter{Target: e},
-		transfer.TargetFilter{Target: e, Type: "application/text"},
+		transfer.TargetFilter{Target: e, Type: textTypes[0]},
+		transfer.TargetFilter{Target: e, Type: textTypes[1]},
 		key.Filter{Focus: e, Name: key.NameEnter, Optional: key.ModShift},
 		key.Filter{Focus: e, Name: key.NameReturn, Optional: key.ModShift},
 
@@ -373,6 +530,8 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 		key.Filter{Focus: e, Name: "V", Required: key.ModShortcut},
 		key.Filter{Focus: e, Name: "X", Required: key.ModShortcut},
 		key.Filter{Focus: e, Name: "A", Required: key.ModShortcut},
+		key.Filter{Focus: e, Name: "D", Required: key.ModShortcut},
+		condFilter(multi, key.Filter{Focus: e, Name: key.NameEscape}),
 
 		key.Filter{Focus: e, Name: key.NameDeleteBackward, Optional: key.ModShortcutAlt | key.ModShift},
 		key.Filter{Focus: e, Name: key.NameDeleteForward, Optional: key.ModShortcutAlt | key.ModShift},
@@ -440,7 +599,15 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 			case e.SingleLine:
 				s = strings.ReplaceAll(s, "\n", " ")
 			}
-			moves += e.replace(ke.Range.Start, ke.Range.End, s, true)
+			e.typing = true
+			if start, end := e.text.Selection(); multi && (ke.Range == key.Range{Start: start, End: end} || ke.Range == key.Range{Start: end, End: start}) {
+				// Type at every caret.
+				e.Insert(s)
+				moves += utf8.RuneCountInString(s)
+			} else {
+				moves += e.replace(ke.Range.Start, ke.Range.End, s, true)
+			}
+			e.typing = false
 			adjust += utf8.RuneCountInString(ke.Text) - moves
 			// Reset caret xoff.
 			e.text.MoveCaret(0, 0)
@@ -455,13 +622,14 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 				}
 				return submitEvent, true
 			}
-		// Complete a paste event, initiated by Shortcut-V in Editor.command().
+		// Complete a paste event, initiated by Shortcut-V in Editor.command()
+		// or a middle click in Editor.processPointer().
 		case transfer.DataEvent:
 			e.scrollCaret = true
 			e.scroller.Stop()
 			content, err := io.ReadAll(ke.Open())
 			if err == nil {
-				if e.Insert(string(content)) != 0 {
+				if e.paste(string(content)) != 0 {
 					return ChangeEvent{}, true
 				}
 			}
@@ -481,6 +649,7 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 }
 
 func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
+	e.coalesce = false
 	direction := 1
 	if gtx.Locale.Direction.Progression() == system.TowardOrigin {
 		direction = -1
@@ -496,13 +665,12 @@ func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
 		// half is in Editor.processKey() under clipboard.Event.
 		case "V":
 			if !e.ReadOnly {
-				gtx.Execute(clipboard.ReadCmd{Tag: e})
+				gtx.Execute(clipboard.ReadCmd{Tag: e, Types: textTypes})
 			}
 		// Copy or Cut selection -- ignored if nothing selected.
 		case "C", "X":
-			e.scratch = e.text.SelectedText(e.scratch)
-			if text := string(e.scratch); text != "" {
-				gtx.Execute(clipboard.WriteCmd{Type: "application/text", Data: io.NopCloser(strings.NewReader(text))})
+			if text := e.copyText(); text != "" {
+				writeClipboard(gtx, text, false)
 				if k.Name == "X" && !e.ReadOnly {
 					if e.Delete(1) != 0 {
 						return ChangeEvent{}, true
@@ -511,7 +679,10 @@ func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
 			}
 		// Select all
 		case "A":
+			e.text.ClearCarets()
 			e.text.SetCaret(0, e.text.Len())
+		case "D":
+			e.addNextOccurrence()
 		case "Z":
 			if !e.ReadOnly {
 				if k.Modifiers.Contain(key.ModShift) {
@@ -525,8 +696,10 @@ func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
 				}
 			}
 		case key.NameHome:
+			e.text.ClearCarets()
 			e.text.MoveTextStart(selAct)
 		case key.NameEnd:
+			e.text.ClearCarets()
 			e.text.MoveTextEnd(selAct)
 		}
 		return nil, false
@@ -534,14 +707,14 @@ func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
 	switch k.Name {
 	case key.NameReturn, key.NameEnter:
 		if !e.ReadOnly {
-			if e.Insert("\n") != 0 {
+			if e.newline() != 0 {
 				return ChangeEvent{}, true
 			}
 		}
 	case key.NameDeleteBackward:
 		if !e.ReadOnly {
 			if moveByWord {
-				if e.deleteWord(-1) != 0 {
+				if e.deleteWords(-1) != 0 {
 					return ChangeEvent{}, true
 				}
 			} else {
@@ -553,7 +726,7 @@ func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
 	case key.NameDeleteForward:
 		if !e.ReadOnly {
 			if moveByWord {
-				if e.deleteWord(1) != 0 {
+				if e.deleteWords(1) != 0 {
 					return ChangeEvent{}, true
 				}
 			} else {
@@ -562,6 +735,19 @@ func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
 				}
 			}
 		}
+	case key.NameEscape:
+		e.text.ClearCarets()
+	default:
+		e.text.eachCaret(func() {
+			e.move(k.Name, selAct, direction, moveByWord)
+		})
+	}
+	return nil, false
+}
+
+// move moves the caret for the key name.
+func (e *Editor) move(name key.Name, selAct selectionAction, direction int, moveByWord bool) {
+	switch name {
 	case key.NameUpArrow:
 		e.text.MoveLines(-1, selAct)
 	case key.NameDownArrow:
@@ -593,20 +779,23 @@ func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
 	case key.NameEnd:
 		e.text.MoveLineEnd(selAct)
 	}
-	return nil, false
 }
 
 // initBuffer should be invoked first in every exported function that accesses
 // text state. It ensures that the underlying text widget is both ready to use
 // and has its fields synced with the editor.
 func (e *Editor) initBuffer() {
-	if e.buffer == nil {
-		e.buffer = new(editBuffer)
-		e.text.SetSource(e.buffer)
+	if e.source == nil {
+		e.source = new(editBuffer)
+		e.text.SetSource(e.source)
 	}
 	e.text.Alignment = e.Alignment
 	e.text.LineHeight = e.LineHeight
 	e.text.LineHeightScale = e.LineHeightScale
+	e.text.Decoration = e.Decoration
+	e.text.LetterSpacing = e.LetterSpacing
+	e.text.WordSpacing = e.WordSpacing
+	e.text.TabWidth = e.TabWidth
 	e.text.SingleLine = e.SingleLine
 	e.text.Mask = e.Mask
 	e.text.WrapPolicy = e.WrapPolicy
@@ -653,10 +842,36 @@ func (e *Editor) Layout(gtx layout.Context, lt *text.Shaper, font font.Font, siz
 		}
 	}
 
+	e.updateStyles()
 	e.text.Layout(gtx, lt, font, size)
 	return e.layout(gtx, textMaterial, selectMaterial)
 }
 
+// updateStyles tokenizes the edited lines and updates the styles of the
+// text.
+func (e *Editor) updateStyles() {
+	e.initBuffer()
+	if !e.restyle {
+		return
+	}
+	e.restyle = false
+	var ranges []StyledRange
+	if e.tokens.tokenizer != nil {
+		ranges = e.tokens.update(e.line, ranges)
+	}
+	ranges = append(ranges, e.styles...)
+	e.text.SetStyles(flattenStyles(ranges))
+}
+
+// line returns the n runes of text at the rune offset start.
+func (e *Editor) line(start, n int) string {
+	startOff := e.text.ByteOffset(start)
+	endOff := e.text.ByteOffset(start + n)
+	e.scratch = slices.Grow(e.scratch[:0], int(endOff-startOff))[:endOff-startOff]
+	n, _ = e.text.ReadAt(e.scratch, startOff)
+	return string(e.scratch[:n])
+}
+
 // updateSnippet queues a key.SnippetCmd if the snippet content or position
 // have changed. off and len are in runes.
 func (e *Editor) updateSnippet(gtx layout.Context, start, end int) {
@@ -782,6 +997,31 @@ func (e *Editor) Text() string {
 	return string(e.scratch)
 }
 
+// SetSource replaces the storage of the editor text with src, such as a
+// rope or a piece table for very large texts. The contents of src must only
+// change through its ReplaceRunes method; if they change otherwise, call
+// SetSource again. SetSource clears the undo history and moves the caret to
+// the beginning.
+//
+// Only the paragraphs around the visible part of large texts are laid out,
+// so the size and line numbers of the text are estimates until all of it
+// has been displayed.
+func (e *Editor) SetSource(src TextSource) {
+	e.initBuffer()
+	e.source = src
+	e.text.SetSource(src)
+	e.history = e.history[:0]
+	e.nextHistoryIdx = 0
+	e.coalesce = false
+	e.styles = e.styles[:0]
+	e.diagnostics = e.diagnostics[:0]
+	if e.tokens.tokenizer != nil {
+		e.tokens.reset(e.Text())
+	}
+	e.restyle = true
+	e.SetCaret(0, 0)
+}
+
 func (e *Editor) SetText(s string) {
 	e.initBuffer()
 	if e.SingleLine {
@@ -809,13 +1049,20 @@ func (e *Editor) CaretCoords() f32.Point {
 // direction to delete: positive is forward, negative is backward.
 //
 // If there is a selection, it is deleted and counts as a single grapheme
-// cluster.
+// cluster. If there are multiple carets, runes are deleted at every caret.
 func (e *Editor) Delete(graphemeClusters int) (deletedRunes int) {
 	e.initBuffer()
 	if graphemeClusters == 0 {
 		return 0
 	}
+	e.eachCaret(func() {
+		deletedRunes += e.delete(graphemeClusters)
+	})
+	return deletedRunes
+}
 
+// delete is like Delete for the caret only.
+func (e *Editor) delete(graphemeClusters int) (deletedRunes int) {
 	start, end := e.text.Selection()
 	if start != end {
 		graphemeClusters -= sign(graphemeClusters)
@@ -828,15 +1075,35 @@ func (e *Editor) Delete(graphemeClusters int) (deletedRunes int) {
 	e.replace(start, end, "", true)
 	// Reset xoff.
 	e.text.MoveCaret(0, 0)
-	e.ClearSelection()
+	e.text.ClearSelection()
 	return end - start
 }
 
+// Insert replaces the selection with s, at every caret.
 func (e *Editor) Insert(s string) (insertedRunes int) {
 	e.initBuffer()
 	if e.SingleLine {
 		s = strings.ReplaceAll(s, "\n", " ")
 	}
+	e.eachCaret(func() {
+		insertedRunes += e.insert(s)
+	})
+	return insertedRunes
+}
+
+// newline inserts a newline at every caret, indented by the indent hook.
+func (e *Editor) newline() (insertedRunes int) {
+	if e.indent == nil || e.SingleLine {
+		return e.Insert("\n")
+	}
+	e.eachCaret(func() {
+		insertedRunes += e.insert("\n" + e.indent())
+	})
+	return insertedRunes
+}
+
+// insert is like Insert for the caret only.
+func (e *Editor) insert(s string) (insertedRunes int) {
 	start, end := e.text.Selection()
 	moves := e.replace(start, end, s, true)
 	if end < start {
@@ -844,15 +1111,211 @@ func (e *Editor) Insert(s string) (insertedRunes int) {
 	}
 	// Reset xoff.
 	e.text.MoveCaret(0, 0)
-	e.SetCaret(start+moves, start+moves)
+	e.text.SetCaret(start+moves, start+moves)
 	e.scrollCaret = true
+	e.scroller.Stop()
 	return moves
 }
 
-// modification represents a change to the contents of the editor buffer.
+// paste inserts s at the carets. If there are multiple carets and s has as
+// many lines, each caret gets a line in text order.
+func (e *Editor) paste(s string) int {
+	n := e.text.Carets()
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	if n == 1 || len(lines) != n {
+		return e.Insert(s)
+	}
+	inserted := 0
+	// eachCaret starts with the last caret.
+	e.eachCaret(func() {
+		n--
+		inserted += e.insert(lines[n])
+	})
+	return inserted
+}
+
+// copyText returns the selected text of the carets, separated by newlines,
+// or the empty string if no text is selected.
+func (e *Editor) copyText() string {
+	var b strings.Builder
+	sels := e.text.Selections()
+	for i, c := range sels {
+		start, end := e.text.ByteOffset(c.start), e.text.ByteOffset(c.end)
+		if start > end {
+			start, end = end, start
+		}
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		io.Copy(&b, io.NewSectionReader(&e.text, start, end-start))
+	}
+	if e.text.Carets() > 1 && b.Len() == len(sels)-1 {
+		return ""
+	}
+	return b.String()
+}
+
+// textTypes are the MIME types of the text the Editor pastes, in order of
+// preference.
+var textTypes = []string{"application/text", "text/plain"}
+
+// writeClipboard copies text to the clipboard, or the primary selection,
+// both as Gio text and as standard plain text for other applications.
+func writeClipboard(gtx layout.Context, text string, primary bool) {
+	gtx.Execute(clipboard.WriteCmd{
+		Type:    "application/text",
+		Data:    io.NopCloser(strings.NewReader(text)),
+		Formats: []clipboard.Format{{Type: "text/plain;charset=utf-8", Data: io.NopCloser(strings.NewReader(text))}},
+		Primary: primary,
+	})
+}
+
+// addNextOccurrence selects the word at the caret if nothing is selected.
+// Otherwise, it adds a caret that selects the next occurrence of the
+// selected text.
+func (e *Editor) addNextOccurrence() {
+	start, end := e.text.Selection()
+	if start == end {
+		e.text.MoveWord(-1, selectionClear)
+		e.text.MoveWord(1, selectionExtend)
+		return
+	}
+	e.scratch = e.text.SelectedText(e.scratch)
+	sel := e.scratch
+	runeStart := e.text.indexRunes(sel, max(start, end))
+	if runeStart == -1 {
+		// Wrap around.
+		runeStart = e.text.indexRunes(sel, 0)
+	}
+	next := textCaret{start: runeStart + utf8.RuneCount(sel), end: runeStart}
+	for _, c := range e.text.Selections() {
+		if min(c.start, c.end) == next.end && max(c.start, c.end) == next.start {
+			// Every occurrence is selected.
+			return
+		}
+	}
+	e.text.AddCaret()
+	e.text.SetCaret(next.start, next.end)
+	e.scrollCaret = true
+}
+
+// eachCaret calls f for every caret as if it were the only caret. The
+// modifications by f are undone and redone together.
+func (e *Editor) eachCaret(f func()) {
+	e.BeginTransaction()
+	e.text.eachCaret(f)
+	e.EndTransaction()
+}
+
+// BeginTransaction starts a group of modifications that are undone and
+// redone as a single step, until the matching call to EndTransaction.
+// Transactions may be nested; the outermost transaction forms the step.
+func (e *Editor) BeginTransaction() {
+	if e.txDepth == 0 {
+		e.txStart = e.nextHistoryIdx
+	}
+	e.txDepth++
+}
+
+// EndTransaction ends the transaction started by the matching call to
+// BeginTransaction.
+func (e *Editor) EndTransaction() {
+	if e.txDepth == 0 {
+		return
+	}
+	e.txDepth--
+	if e.txDepth == 0 {
+		e.chainHistory(min(e.txStart, e.nextHistoryIdx))
+		e.trimHistory()
+	}
+}
+
+// chainHistory chains the modifications from the history index first, so
+// that they are undone and redone together.
+func (e *Editor) chainHistory(first int) {
+	for i := first + 1; i < e.nextHistoryIdx; i++ {
+		e.history[i].Chained = true
+	}
+}
+
+// trimHistory removes the oldest undo steps beyond HistoryLimit.
+func (e *Editor) trimHistory() {
+	if e.HistoryLimit <= 0 || e.txDepth > 0 {
+		return
+	}
+	steps := 0
+	for i := len(e.history) - 1; i >= 0; i-- {
+		if e.history[i].Chained {
+			continue
+		}
+		steps++
+		if steps == e.HistoryLimit {
+			e.history = slices.Delete(e.history, 0, i)
+			e.nextHistoryIdx = max(e.nextHistoryIdx-i, 0)
+			return
+		}
+	}
+}
+
+// Undo reverts the last undo step, and reports whether there was a step to
+// undo.
+func (e *Editor) Undo() bool {
+	_, ok := e.undo()
+	return ok
+}
+
+// Redo applies the last undone step again, and reports whether there was a
+// step to redo.
+func (e *Editor) Redo() bool {
+	_, ok := e.redo()
+	return ok
+}
+
+// CanUndo reports whether there is a step to undo.
+func (e *Editor) CanUndo() bool {
+	return e.nextHistoryIdx > 0
+}
+
+// CanRedo reports whether there is an undone step to redo.
+func (e *Editor) CanRedo() bool {
+	return e.nextHistoryIdx < len(e.history)
+}
+
+// EditorHistory is the undo history of an Editor. It contains only exported
+// fields, so it can be encoded, such as with package encoding/json, and
+// restored in a later session.
+type EditorHistory struct {
+	// Modifications are the modifications in the order they were made.
+	Modifications []Modification
+	// Next is the number of modifications that are applied. The
+	// modifications from Next on are undone and may be redone.
+	Next int
+}
+
+// History returns a copy of the undo history.
+func (e *Editor) History() EditorHistory {
+	return EditorHistory{
+		Modifications: slices.Clone(e.history),
+		Next:          e.nextHistoryIdx,
+	}
+}
+
+// SetHistory replaces the undo history. The editor text must be the text
+// the history was recorded for, such as the text at the time History was
+// called.
+func (e *Editor) SetHistory(h EditorHistory) {
+	e.history = slices.Clone(h.Modifications)
+	if len(e.history) > 0 {
+		e.history[0].Chained = false
+	}
+	e.nextHistoryIdx = min(max(h.Next, 0), len(e.history))
+	e.coalesce = false
+}
+
+// Modification represents a change to the contents of the editor buffer.
 // It contains the necessary information to both apply the change and
 // reverse it, and is useful for implementing undo/redo.
-type modification struct {
+type Modification struct {
 	// StartRune is the inclusive index of the first rune
 	// modified.
 	StartRune int
@@ -862,6 +1325,26 @@ type modification struct {
 	// ReverseContent is the data inserted at StartRune to
 	// apply this operation. It overwrites len([]rune(ApplyContent)) runes.
 	ReverseContent string
+	// Chained is set if the modification is undone and redone together with
+	// the previous modification, such as for edits at multiple carets,
+	// consecutive typing and transactions.
+	Chained bool
+}
+
+// coalesces reports whether the typed modification mod continues the typing
+// of the previous modification. Typing is grouped by word: a group ends
+// after a space, before the next non-space.
+func (e *Editor) coalesces(mod Modification) bool {
+	if !e.coalesce || e.nextHistoryIdx == 0 || mod.ReverseContent != "" || mod.ApplyContent == "" {
+		return false
+	}
+	prev := e.history[e.nextHistoryIdx-1]
+	if prev.ReverseContent != "" || prev.StartRune+utf8.RuneCountInString(prev.ApplyContent) != mod.StartRune {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(prev.ApplyContent)
+	first, _ := utf8.DecodeRuneInString(mod.ApplyContent)
+	return !unicode.IsSpace(last) || unicode.IsSpace(first)
 }
 
 // undo applies the modification at e.history[e.historyIdx] and decrements
@@ -871,12 +1354,24 @@ func (e *Editor) undo() (EditorEvent, bool) {
 	if len(e.history) < 1 || e.nextHistoryIdx == 0 {
 		return nil, false
 	}
-	mod := e.history[e.nextHistoryIdx-1]
-	replaceEnd := mod.StartRune + utf8.RuneCountInString(mod.ApplyContent)
-	e.replace(mod.StartRune, replaceEnd, mod.ReverseContent, false)
-	caretEnd := mod.StartRune + utf8.RuneCountInString(mod.ReverseContent)
-	e.SetCaret(caretEnd, mod.StartRune)
-	e.nextHistoryIdx--
+	e.coalesce = false
+	e.text.ClearCarets()
+	for {
+		mod := e.history[e.nextHistoryIdx-1]
+		replaceEnd := mod.StartRune + utf8.RuneCountInString(mod.ApplyContent)
+		e.replace(mod.StartRune, replaceEnd, mod.ReverseContent, false)
+		caretEnd := mod.StartRune + utf8.RuneCountInString(mod.ReverseContent)
+		e.text.SetCaret(caretEnd, mod.StartRune)
+		e.nextHistoryIdx--
+		if !mod.Chained {
+			break
+		}
+		// Restore a caret for every modification.
+		e.text.AddCaret()
+	}
+	e.text.mergeCarets()
+	e.scrollCaret = true
+	e.scroller.Stop()
 	return ChangeEvent{}, true
 }
 
@@ -887,12 +1382,32 @@ func (e *Editor) redo() (EditorEvent, bool) {
 	if len(e.history) < 1 || e.nextHistoryIdx == len(e.history) {
 		return nil, false
 	}
-	mod := e.history[e.nextHistoryIdx]
-	end := mod.StartRune + utf8.RuneCountInString(mod.ReverseContent)
-	e.replace(mod.StartRune, end, mod.ApplyContent, false)
-	caretEnd := mod.StartRune + utf8.RuneCountInString(mod.ApplyContent)
-	e.SetCaret(caretEnd, mod.StartRune)
-	e.nextHistoryIdx++
+	e.coalesce = false
+	e.text.ClearCarets()
+	// caretStart is the start of the caret selection, which extends over
+	// adjacent modifications such as consecutive typing.
+	caretStart := -1
+	for {
+		mod := e.history[e.nextHistoryIdx]
+		end := mod.StartRune + utf8.RuneCountInString(mod.ReverseContent)
+		e.replace(mod.StartRune, end, mod.ApplyContent, false)
+		caretEnd := mod.StartRune + utf8.RuneCountInString(mod.ApplyContent)
+		if caretStart == -1 {
+			caretStart = mod.StartRune
+		}
+		e.text.SetCaret(caretEnd, caretStart)
+		e.nextHistoryIdx++
+		if e.nextHistoryIdx == len(e.history) || !e.history[e.nextHistoryIdx].Chained {
+			break
+		}
+		if e.history[e.nextHistoryIdx].StartRune != caretEnd {
+			e.text.AddCaret()
+			caretStart = -1
+		}
+	}
+	e.text.mergeCarets()
+	e.scrollCaret = true
+	e.scroller.Stop()
 	return ChangeEvent{}, true
 }
 
@@ -913,12 +1428,12 @@ func (e *Editor) replace(start, end int, s string, addHistory bool) int {
 	var sc int
 	idx := 0
 	for idx < len(s) {
-		if e.MaxLen > 0 && el-replaceSize+sc >= e.MaxLen {
+		if e.MaxLen > 0 && !e.formatting && el-replaceSize+sc >= e.MaxLen {
 			s = s[:idx]
 			break
 		}
 		_, n := utf8.DecodeRuneInString(s[idx:])
-		if e.Filter != "" && !strings.Contains(e.Filter, s[idx:idx+n]) {
+		if e.Filter != "" && !e.formatting && !strings.Contains(e.Filter, s[idx:idx+n]) {
 			s = s[:idx] + s[idx+n:]
 			continue
 		}
@@ -937,12 +1452,18 @@ func (e *Editor) replace(start, end int, s string, addHistory bool) int {
 		if e.nextHistoryIdx < len(e.history) {
 			e.history = e.history[:e.nextHistoryIdx]
 		}
-		e.history = append(e.history, modification{
+		mod := Modification{
 			StartRune:      start,
 			ApplyContent:   s,
 			ReverseContent: string(deleted),
-		})
+		}
+		if e.typing {
+			mod.Chained = e.coalesces(mod)
+		}
+		e.coalesce = e.typing
+		e.history = append(e.history, mod)
 		e.nextHistoryIdx++
+		e.trimHistory()
 	}
 
 	sc = e.text.Replace(start, end, s)
@@ -959,6 +1480,12 @@ func (e *Editor) replace(start, end int, s string, addHistory bool) int {
 	}
 	e.ime.start = adjust(e.ime.start)
 	e.ime.end = adjust(e.ime.end)
+	e.diagnostics = shiftDiagnostics(e.diagnostics, start, end, sc)
+	if len(e.styles) > 0 || e.tokens.tokenizer != nil {
+		e.styles = shiftRanges(e.styles, start, end, sc)
+		e.tokens.replace(start, end, s)
+		e.restyle = true
+	}
 	return sc
 }
 
@@ -984,7 +1511,7 @@ func (e *Editor) deleteWord(distance int) (deletedRunes int) {
 
 	start, end := e.text.Selection()
 	if start != end {
-		deletedRunes = e.Delete(1)
+		deletedRunes = e.delete(1)
 		distance -= sign(distance)
 	}
 	if distance == 0 {
@@ -1028,7 +1555,15 @@ func (e *Editor) deleteWord(distance int) (deletedRunes int) {
 			runes += 1
 		}
 	}
-	deletedRunes += e.Delete(runes * direction)
+	deletedRunes += e.delete(runes * direction)
+	return deletedRunes
+}
+
+// deleteWords is like deleteWord for every caret.
+func (e *Editor) deleteWords(distance int) (deletedRunes int) {
+	e.eachCaret(func() {
+		deletedRunes += e.deleteWord(distance)
+	})
 	return deletedRunes
 }
 
@@ -1047,14 +1582,23 @@ func (e *Editor) Selection() (start, end int) {
 }
 
 // SetCaret moves the caret to start, and sets the selection end to end. start
-// and end are in runes, and represent offsets into the editor text.
+// and end are in runes, and represent offsets into the editor text. Other
+// carets are removed.
 func (e *Editor) SetCaret(start, end int) {
 	e.initBuffer()
+	e.coalesce = false
+	e.text.ClearCarets()
 	e.text.SetCaret(start, end)
 	e.scrollCaret = true
 	e.scroller.Stop()
 }
 
+// ScrollToCaret scrolls the caret into view at the next layout.
+func (e *Editor) ScrollToCaret() {
+	e.scrollCaret = true
+	e.scroller.Stop()
+}
+
 // SelectedText returns the currently selected text (if any) from the editor.
 func (e *Editor) SelectedText() string {
 	e.initBuffer()
@@ -1093,6 +1637,113 @@ func (e *Editor) Regions(start, end int, regions []Region) []Region {
 	return e.text.Regions(start, end, regions)
 }
 
+// Lines returns the visible lines of text, such as for drawing line
+// numbers next to the editor. Lines that wrap are returned once, with the
+// extent of all their screen lines.
+func (e *Editor) Lines(lines []LineInfo) []LineInfo {
+	e.initBuffer()
+	return e.text.Lines(lines)
+}
+
+// SetStyles sets the styled ranges of the text. The ranges move along with
+// edits of the text, and are removed when their text is deleted. Where
+// ranges overlap, later ranges take precedence over earlier ranges and over
+// the styles of the tokenizer.
+func (e *Editor) SetStyles(ranges []StyledRange) {
+	e.styles = append(e.styles[:0], ranges...)
+	e.restyle = true
+}
+
+// Styles returns the styled ranges set by SetStyles, moved along with the
+// edits since.
+func (e *Editor) Styles() []StyledRange {
+	return slices.Clone(e.styles)
+}
+
+// SetDiagnostics sets the diagnostics of the text, such as misspellings
+// and validation errors. The diagnostics move along with edits of the text,
+// and are removed when their text is deleted.
+func (e *Editor) SetDiagnostics(diags []Diagnostic) {
+	e.diagnostics = append(e.diagnostics[:0], diags...)
+}
+
+// Diagnostics returns the diagnostics set by SetDiagnostics, moved along
+// with the edits since.
+func (e *Editor) Diagnostics() []Diagnostic {
+	return slices.Clone(e.diagnostics)
+}
+
+// DiagnosticAt returns the visible diagnostic at pos, relative to the
+// editor. Where diagnostics overlap, the last one is returned.
+func (e *Editor) DiagnosticAt(pos image.Point) (Diagnostic, bool) {
+	e.initBuffer()
+	for i := len(e.diagnostics) - 1; i >= 0; i-- {
+		d := e.diagnostics[i]
+		e.regions = e.text.Regions(d.Start, d.End, e.regions)
+		for _, r := range e.regions {
+			if pos.In(r.Bounds) {
+				return d, true
+			}
+		}
+	}
+	return Diagnostic{}, false
+}
+
+// HoveredDiagnostic returns the diagnostic under the pointer, and the
+// position of the pointer relative to the editor, such as for showing a
+// popup with suggestions.
+func (e *Editor) HoveredDiagnostic() (Diagnostic, image.Point, bool) {
+	if !e.hovered {
+		return Diagnostic{}, image.Point{}, false
+	}
+	d, ok := e.DiagnosticAt(e.hoverPos)
+	return d, e.hoverPos, ok
+}
+
+// CaretDiagnostic returns the diagnostic at the caret. Where diagnostics
+// overlap, the last one is returned.
+func (e *Editor) CaretDiagnostic() (Diagnostic, bool) {
+	e.initBuffer()
+	caret, _ := e.text.Selection()
+	for i := len(e.diagnostics) - 1; i >= 0; i-- {
+		if d := e.diagnostics[i]; d.Start <= caret && caret <= d.End {
+			return d, true
+		}
+	}
+	return Diagnostic{}, false
+}
+
+// PaintDiagnostics draws wavy underlines below the visible diagnostics,
+// with a material for each severity. It must be called with the
+// transformation of the layout of the editor, such as right after laying
+// it out.
+func (e *Editor) PaintDiagnostics(gtx layout.Context, infoMaterial, warningMaterial, errorMaterial op.CallOp) {
+	e.initBuffer()
+	for _, d := range e.diagnostics {
+		material := infoMaterial
+		switch d.Severity {
+		case SeverityWarning:
+			material = warningMaterial
+		case SeverityError:
+			material = errorMaterial
+		}
+		e.regions = e.text.Regions(d.Start, d.End, e.regions)
+		for _, r := range e.regions {
+			paintWave(gtx, r, material)
+		}
+	}
+}
+
+// SetTokenizer sets the tokenizer that styles the text, or removes it if t
+// is nil. When the text is edited, the tokenizer is run on the edited lines
+// and on the following lines whose tokenizer state changes.
+func (e *Editor) SetTokenizer(t Tokenizer) {
+	e.initBuffer()
+	e.tokens.tokenizer = t
+	e.tokens.reset(e.Text())
+	e.restyle = true
+}
+
 func max(a, b int) int {
 	if a > b {
 		return a
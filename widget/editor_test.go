@@ -9,6 +9,8 @@ import (
 	"io"
 	"math/rand"
 	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"testing/quick"
 	"time"
@@ -24,6 +26,7 @@ import (
 	"gioui.org/io/key"
 	"gioui.org/io/pointer"
 	"gioui.org/io/system"
+	"gioui.org/io/transfer"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/text"
@@ -78,6 +81,104 @@ func TestEditorHistory(t *testing.T) {
 	assertContents(t, e, text, start, end)
 }
 
+// TestEditorHistoryGroups ensures that typing is undone word by word, and
+// that transactions are undone as a single step.
+func TestEditorHistoryGroups(t *testing.T) {
+	e := new(Editor)
+	r := new(input.Router)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Constraints{Max: image.Pt(100, 100)},
+		Source:      r.Source(),
+		Locale:      english,
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	gtx.Execute(key.FocusCmd{Tag: e})
+	e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	r.Frame(gtx.Ops)
+	for i, c := range "hello world" {
+		r.Queue(
+			key.EditEvent{Range: key.Range{Start: i, End: i}, Text: string(c)},
+			key.SelectionEvent{Start: i + 1, End: i + 1},
+		)
+		e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	}
+	assertContents(t, e, "hello world", 11, 11)
+	if !e.CanUndo() || e.CanRedo() {
+		t.Errorf("CanUndo, CanRedo = %v, %v, want true, false", e.CanUndo(), e.CanRedo())
+	}
+	e.Undo()
+	assertContents(t, e, "hello ", 6, 6)
+	e.Undo()
+	assertContents(t, e, "", 0, 0)
+	if e.Undo() {
+		t.Error("Undo succeeded without history")
+	}
+	e.Redo()
+	assertContents(t, e, "hello ", 6, 0)
+	if !e.CanRedo() {
+		t.Error("CanRedo = false after Undo")
+	}
+
+	e.SetText("")
+	e.BeginTransaction()
+	e.Insert("a")
+	e.BeginTransaction()
+	e.Insert("b")
+	e.EndTransaction()
+	e.Insert("c")
+	e.EndTransaction()
+	e.Insert("d")
+	assertContents(t, e, "abcd", 4, 4)
+	e.Undo()
+	assertContents(t, e, "abc", 3, 3)
+	e.Undo()
+	assertContents(t, e, "", 0, 0)
+	e.Redo()
+	assertContents(t, e, "abc", 3, 0)
+}
+
+func TestEditorHistoryLimit(t *testing.T) {
+	e := new(Editor)
+	e.HistoryLimit = 2
+	for _, s := range []string{"a", "b", "c"} {
+		e.Insert(s)
+	}
+	e.BeginTransaction()
+	e.Insert("d")
+	e.Insert("e")
+	e.EndTransaction()
+	assertContents(t, e, "abcde", 5, 5)
+	for e.Undo() {
+	}
+	assertContents(t, e, "ab", 2, 2)
+	e.Redo()
+	e.Redo()
+	assertContents(t, e, "abcde", 5, 3)
+}
+
+func TestEditorHistoryRestore(t *testing.T) {
+	e := new(Editor)
+	e.Insert("hello")
+	e.Insert(" world")
+	e.Undo()
+	h := e.History()
+	if h.Next != 1 || len(h.Modifications) != 2 {
+		t.Fatalf("History() = %+v, want 2 modifications and Next 1", h)
+	}
+
+	e2 := new(Editor)
+	e2.SetText(e.Text())
+	e2.SetHistory(h)
+	if !e2.Redo() {
+		t.Fatal("Redo failed after SetHistory")
+	}
+	assertContents(t, e2, "hello world", 11, 5)
+	e2.Undo()
+	e2.Undo()
+	assertContents(t, e2, "", 0, 0)
+}
+
 func assertContents(t *testing.T, e *Editor, contents string, selectionStart, selectionEnd int) {
 	t.Helper()
 	actualContents := e.Text()
@@ -204,7 +305,7 @@ func TestEditorConfigurations(t *testing.T) {
 	// Ensure that both ends of the text are reachable in all permutations
 	// of settings that influence layout.
 	for _, singleLine := range []bool{true, false} {
-		for _, alignment := range []text.Alignment{text.Start, text.Middle, text.End} {
+		for _, alignment := range []text.Alignment{text.Start, text.Middle, text.End, text.Justify} {
 			for _, zeroMin := range []bool{true, false} {
 				t.Run(fmt.Sprintf("SingleLine: %v Alignment: %v ZeroMinConstraint: %v", singleLine, alignment, zeroMin), func(t *testing.T) {
 					defer func() {
@@ -567,7 +668,7 @@ func TestEditorCaretConsistency(t *testing.T) {
 	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
 	fontSize := unit.Sp(10)
 	font := font.Font{}
-	for _, a := range []text.Alignment{text.Start, text.Middle, text.End} {
+	for _, a := range []text.Alignment{text.Start, text.Middle, text.End, text.Justify} {
 		e := &Editor{}
 		e.Alignment = a
 		e.Layout(gtx, cache, font, fontSize, op.CallOp{}, op.CallOp{})
@@ -1283,3 +1384,507 @@ func textBaseline(e *Editor, lineNum int) float32 {
 	start := e.text.closestToLineCol(lineNum, 0)
 	return float32(start.y)
 }
+
+func TestEditorTabStops(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 100)),
+		Locale:      english,
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e := &Editor{TabWidth: 40}
+	e.SetText("a\tb\tc")
+	e.Layout(gtx, cache, font.Font{}, 10, op.CallOp{}, op.CallOp{})
+	for _, tc := range []struct {
+		caret int
+		x     float32
+	}{{2, 40}, {4, 80}} {
+		e.SetCaret(tc.caret, tc.caret)
+		if got := e.CaretCoords().X; got != tc.x {
+			t.Errorf("caret at rune %d at x %v, want %v", tc.caret, got, tc.x)
+		}
+	}
+	// Moving the caret by coordinates lands on the tab stop.
+	e.text.MoveCoord(image.Pt(41, 5))
+	if start, _ := e.Selection(); start != 2 {
+		t.Errorf("caret at rune %d after moving to the tab stop, want 2", start)
+	}
+}
+
+func TestEditorStyles(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 100)),
+		Locale:      english,
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e := new(Editor)
+	e.SetText("hello world")
+	bold := TextStyle{Weight: font.Bold}
+	e.SetStyles([]StyledRange{{Start: 6, End: 11, Style: bold}})
+	e.Insert(">> ")
+	want := []StyledRange{{Start: 9, End: 14, Style: bold}}
+	if got := e.Styles(); !slices.Equal(got, want) {
+		t.Errorf("got styles %v after insertion, want %v", got, want)
+	}
+	e.Layout(gtx, cache, font.Font{}, 10, op.CallOp{}, op.CallOp{})
+	if got := len(e.text.spans); got != 2 {
+		t.Errorf("got %d spans, want 2", got)
+	}
+	if got := e.text.index.glyphs; len(got) == 0 {
+		t.Error("styled text has no glyphs")
+	}
+	// Deleting the text of a range removes it.
+	e.SetCaret(8, 14)
+	e.Delete(1)
+	if got := e.Styles(); len(got) != 0 {
+		t.Errorf("got styles %v after deletion, want none", got)
+	}
+}
+
+// blockTokenizer styles the lines between "/*" and "*/" lines.
+type blockTokenizer struct {
+	calls int
+}
+
+func (b *blockTokenizer) Tokenize(line string, state any) ([]StyledRange, any) {
+	b.calls++
+	comment := state == true || line == "/*"
+	next := comment && line != "*/"
+	if !comment {
+		return nil, next
+	}
+	return []StyledRange{{End: utf8.RuneCountInString(line), Style: TextStyle{Style: font.Italic}}}, next
+}
+
+func TestEditorTokenizer(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 100)),
+		Locale:      english,
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e := new(Editor)
+	e.SetText("a\nb\nc\nd")
+	tok := new(blockTokenizer)
+	e.SetTokenizer(tok)
+	check := func(calls int, styles []StyledRange) {
+		t.Helper()
+		e.Layout(gtx, cache, font.Font{}, 10, op.CallOp{}, op.CallOp{})
+		if tok.calls != calls {
+			t.Errorf("tokenized %d lines, want %d", tok.calls, calls)
+		}
+		if !slices.Equal(e.text.styles, styles) {
+			t.Errorf("got styles %v, want %v", e.text.styles, styles)
+		}
+		tok.calls = 0
+	}
+	check(4, nil)
+	// Editing a line tokenizes only that line.
+	e.SetCaret(3, 3)
+	e.Insert("x")
+	check(1, nil)
+	// Changing the state of a line tokenizes the following lines.
+	e.SetCaret(0, 1)
+	e.Insert("/*")
+	italic := TextStyle{Style: font.Italic}
+	check(4, []StyledRange{
+		{Start: 0, End: 2, Style: italic},
+		{Start: 3, End: 5, Style: italic},
+		{Start: 6, End: 7, Style: italic},
+		{Start: 8, End: 9, Style: italic},
+	})
+	// Ending the comment stops at the line whose state is unchanged.
+	e.SetCaret(6, 7)
+	e.Insert("*/")
+	check(2, []StyledRange{
+		{Start: 0, End: 2, Style: italic},
+		{Start: 3, End: 5, Style: italic},
+		{Start: 6, End: 8, Style: italic},
+	})
+}
+
+// bytesSource is a TextSource backed by a byte slice.
+type bytesSource struct {
+	text    []byte
+	changed bool
+}
+
+func (s *bytesSource) ReadAt(p []byte, off int64) (int, error) {
+	return bytes.NewReader(s.text).ReadAt(p, off)
+}
+
+func (s *bytesSource) Size() int64 {
+	return int64(len(s.text))
+}
+
+func (s *bytesSource) Changed() bool {
+	c := s.changed
+	s.changed = false
+	return c
+}
+
+func (s *bytesSource) ReplaceRunes(off, n int64, r string) {
+	end := off
+	for ; n > 0; n-- {
+		_, w := utf8.DecodeRune(s.text[end:])
+		end += int64(w)
+	}
+	s.text = slices.Concat(s.text[:off], []byte(r), s.text[end:])
+	s.changed = true
+}
+
+func TestEditorLargeText(t *testing.T) {
+	defer func(size int64) { lazyLayoutSize = size }(lazyLayoutSize)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 100)),
+		Locale:      english,
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	var b bytes.Buffer
+	for i := range 1000 {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	txt := b.String()
+	runes := []int{0, 3, 2000, 5000, len(txt)}
+
+	// Lay out the whole text for reference.
+	lazyLayoutSize = int64(len(txt)) + 1
+	full := new(Editor)
+	full.SetText(txt)
+	full.Layout(gtx, cache, font.Font{}, 10, op.CallOp{}, op.CallOp{})
+	var want []combinedPos
+	for _, r := range runes {
+		want = append(want, full.text.closestToRune(r))
+	}
+
+	lazyLayoutSize = 1000
+	e := new(Editor)
+	e.SetSource(&bytesSource{text: []byte(txt)})
+	e.Layout(gtx, cache, font.Font{}, 10, op.CallOp{}, op.CallOp{})
+	if !e.text.lazy {
+		t.Fatal("large text is laid out in full")
+	}
+	if n, all := len(e.text.index.glyphs), len(full.text.index.glyphs); n*10 > all {
+		t.Errorf("laid out %d of %d glyphs", n, all)
+	}
+	if got, exp := e.Len(), full.Len(); got != exp {
+		t.Errorf("got length %d, want %d", got, exp)
+	}
+	for i, r := range runes {
+		if got := e.text.closestToRune(r); got != want[i] {
+			t.Errorf("rune %d: got position %+v, want %+v", r, got, want[i])
+		}
+	}
+	if got, exp := e.text.FullDimensions().Size.Y, full.text.FullDimensions().Size.Y; got != exp {
+		t.Errorf("got height %d, want %d", got, exp)
+	}
+
+	// The caret moves across the laid out paragraphs.
+	for range 400 {
+		e.text.MoveLines(1, selectionClear)
+	}
+	if line, _ := e.CaretPos(); line != 400 {
+		t.Errorf("got caret on line %d, want 400", line)
+	}
+	e.SetCaret(3000, 3000)
+	for range 2000 {
+		e.text.MoveCaret(-1, -1)
+	}
+	if start, _ := e.Selection(); start != 1000 {
+		t.Errorf("got caret at %d, want 1000", start)
+	}
+
+	// Scrolling lays out the visible paragraphs.
+	e.text.ScrollRel(0, 3000)
+	e.Layout(gtx, cache, font.Font{}, 10, op.CallOp{}, op.CallOp{})
+	if pos := e.text.closestToXY(0, 3000); pos.y+pos.descent.Ceil() < 3000 || pos.runes == 0 {
+		t.Errorf("got position %+v for the top of the viewport", pos)
+	}
+
+	// Edits update the paragraphs.
+	e.SetCaret(5000, 5000)
+	e.Insert("new\nlines\n")
+	e.SetCaret(10, 20)
+	e.Delete(1)
+	e.text.MoveTextEnd(selectionClear)
+	exp := strings.Count(e.Text(), "\n")
+	if line, _ := e.CaretPos(); line != exp {
+		t.Errorf("got caret on line %d after edits, want %d", line, exp)
+	}
+	var scanned paragraphIndex
+	scanned.scan(strings.NewReader(e.Text()), int64(len(e.Text())))
+	if got, exp := e.text.paras.paragraphs, scanned.paragraphs; len(got) != len(exp) {
+		t.Fatalf("got %d paragraphs after edits, want %d", len(got), len(exp))
+	}
+	for i, p := range scanned.paragraphs {
+		got := e.text.paras.paragraphs[i]
+		if got.runes != p.runes || got.bytes != p.bytes {
+			t.Errorf("paragraph %d: got %d runes and %d bytes, want %d and %d", i, got.runes, got.bytes, p.runes, p.bytes)
+		}
+	}
+	// The cached paragraph offsets match their sums.
+	paras := &e.text.paras
+	checkOffsets := func() {
+		t.Helper()
+		var off paragraphOffset
+		for i, p := range paras.paragraphs {
+			if got := paras.offset(i); got != off {
+				t.Errorf("paragraph %d: got offset %+v, want %+v", i, got, off)
+				break
+			}
+			off = paras.advance(off, p)
+		}
+	}
+	checkOffsets()
+	if got, exp := e.Len(), scanned.runes; got != exp {
+		t.Errorf("got length %d after edits, want %d", got, exp)
+	}
+	// Replacements update the cached offsets.
+	paras.replace(0, 0, 0, 0, "x\n")
+	checkOffsets()
+}
+
+func TestEditorMultipleCarets(t *testing.T) {
+	e := new(Editor)
+	e.SetText("one\ntwo\nthree")
+	e.text.AddCaret()
+	e.text.SetCaret(4, 4)
+	e.text.AddCaret()
+	e.text.SetCaret(8, 8)
+	e.Insert("> ")
+	if got, want := e.Text(), "> one\n> two\n> three"; got != want {
+		t.Fatalf("got %q after insertion, want %q", got, want)
+	}
+	e.Delete(-1)
+	if got, want := e.Text(), ">one\n>two\n>three"; got != want {
+		t.Errorf("got %q after deletion, want %q", got, want)
+	}
+	// Undo restores the text and the carets of all edits at once.
+	e.undo()
+	if got, want := e.Text(), "> one\n> two\n> three"; got != want {
+		t.Errorf("got %q after undo, want %q", got, want)
+	}
+	if n := e.text.Carets(); n != 3 {
+		t.Errorf("got %d carets after undo, want 3", n)
+	}
+	e.undo()
+	if got, want := e.Text(), "one\ntwo\nthree"; got != want {
+		t.Errorf("got %q after second undo, want %q", got, want)
+	}
+	e.redo()
+	if got, want := e.Text(), "> one\n> two\n> three"; got != want {
+		t.Errorf("got %q after redo, want %q", got, want)
+	}
+	// Carets merge when they meet.
+	e.text.eachCaret(func() {
+		e.text.MoveTextStart(selectionClear)
+	})
+	if n := e.text.Carets(); n != 1 {
+		t.Errorf("got %d carets after moving to the start, want 1", n)
+	}
+}
+
+func TestEditorNextOccurrence(t *testing.T) {
+	e := new(Editor)
+	e.SetText("foo bar foo baz foo")
+	e.SetCaret(1, 1)
+	for range 4 {
+		e.addNextOccurrence()
+	}
+	if n := e.text.Carets(); n != 3 {
+		t.Fatalf("got %d carets, want 3", n)
+	}
+	if got, want := e.copyText(), "foo\nfoo\nfoo"; got != want {
+		t.Errorf("copied %q, want %q", got, want)
+	}
+	e.paste("a\nb\nc")
+	if got, want := e.Text(), "a bar b baz c"; got != want {
+		t.Errorf("got %q after paste, want %q", got, want)
+	}
+	e.Insert("x")
+	if got, want := e.Text(), "ax bar bx baz cx"; got != want {
+		t.Errorf("got %q after insertion, want %q", got, want)
+	}
+
+	// Occurrences are found past the first chunk of the search.
+	e.SetText(strings.Repeat("é", 20000) + "foo")
+	if got, want := e.text.indexRunes([]byte("éfoo"), 10), 19999; got != want {
+		t.Errorf("found occurrence at %d, want %d", got, want)
+	}
+	if got := e.text.indexRunes([]byte("bar"), 0); got != -1 {
+		t.Errorf("found missing occurrence at %d", got)
+	}
+}
+
+func TestEditorSelectColumn(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 100)),
+		Locale:      english,
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e := new(Editor)
+	e.SetText("abcd\nef\nijkl")
+	// Use a monospace font for the columns to line up.
+	e.Layout(gtx, cache, font.Font{Typeface: "Go Mono"}, 10, op.CallOp{}, op.CallOp{})
+	a, b := e.text.closestToRune(1), e.text.closestToRune(11)
+	e.text.SelectColumn(image.Pt(a.x.Round(), a.y), image.Pt(b.x.Round(), b.y))
+	var got [][2]int
+	for _, c := range e.text.Selections() {
+		got = append(got, [2]int{c.end, c.start})
+	}
+	want := [][2]int{{1, 3}, {6, 7}, {9, 11}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got selections %v, want %v", got, want)
+	}
+	if start, _ := e.Selection(); start != 11 {
+		t.Errorf("got caret at %d, want 11", start)
+	}
+}
+
+func TestEditorDiagnostics(t *testing.T) {
+	e := new(Editor)
+	r := new(input.Router)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(60, 200)),
+		Source:      r.Source(),
+		Locale:      english,
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	layoutEditor := func() {
+		gtx.Ops.Reset()
+		e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+		e.PaintDiagnostics(gtx, op.CallOp{}, op.CallOp{}, op.CallOp{})
+		r.Frame(gtx.Ops)
+	}
+	e.SetText("a speling mistake that wraps")
+	e.SetDiagnostics([]Diagnostic{
+		{Start: 2, End: 9, Severity: SeverityError, Message: "spelling"},
+		{Start: 10, End: 28, Severity: SeverityWarning, Message: "long"},
+	})
+	layoutEditor()
+
+	e.SetCaret(0, 0)
+	e.Insert("x")
+	diags := e.Diagnostics()
+	if len(diags) != 2 || diags[0].Start != 3 || diags[0].End != 10 {
+		t.Fatalf("got diagnostics %+v after insertion, want the first at [3, 10)", diags)
+	}
+	e.SetCaret(5, 5)
+	if d, ok := e.CaretDiagnostic(); !ok || d.Message != "spelling" {
+		t.Errorf("got caret diagnostic %+v, %v, want spelling", d, ok)
+	}
+	layoutEditor()
+
+	// The second diagnostic wraps onto several lines.
+	regions := e.Regions(diags[1].Start, diags[1].End, nil)
+	if len(regions) < 2 {
+		t.Fatalf("got %d regions of wrapped diagnostic, want at least 2", len(regions))
+	}
+	pos := regions[len(regions)-1].Bounds.Min.Add(image.Pt(1, 1))
+	if d, ok := e.DiagnosticAt(pos); !ok || d.Message != "long" {
+		t.Errorf("got diagnostic %+v, %v at %v, want long", d, ok, pos)
+	}
+	if _, ok := e.DiagnosticAt(image.Pt(1, 1)); ok {
+		t.Error("got diagnostic before the first diagnostic")
+	}
+
+	r.Queue(pointer.Event{Kind: pointer.Move, Position: layout.FPt(pos)})
+	layoutEditor()
+	if d, hover, ok := e.HoveredDiagnostic(); !ok || d.Message != "long" || hover != pos {
+		t.Errorf("got hovered diagnostic %+v at %v, %v, want long at %v", d, hover, ok, pos)
+	}
+
+	// Deleting the text of a diagnostic removes it.
+	e.SetCaret(3, 10)
+	e.Delete(1)
+	if diags := e.Diagnostics(); len(diags) != 1 || diags[0].Message != "long" {
+		t.Errorf("got diagnostics %+v after deletion, want only long", diags)
+	}
+}
+
+func TestEditorPrimarySelection(t *testing.T) {
+	e := new(Editor)
+	r := new(input.Router)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Constraints{Max: image.Pt(100, 100)},
+		Source:      r.Source(),
+		Locale:      english,
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e.SetText("hello world")
+	gtx.Execute(key.FocusCmd{Tag: e})
+	e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	r.Frame(gtx.Ops)
+
+	// Selecting text writes it to the primary selection.
+	r.Queue(key.SelectionEvent{Start: 6, End: 11})
+	e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	data, ok := r.WritePrimary()
+	want := []input.ClipboardData{
+		{Type: "application/text", Data: []byte("world")},
+		{Type: "text/plain;charset=utf-8", Data: []byte("world")},
+	}
+	if !ok || !reflect.DeepEqual(data, want) {
+		t.Errorf("got primary selection %q, want %q", data, want)
+	}
+	if _, ok := r.WriteClipboardData(); ok {
+		t.Error("selection written to the clipboard")
+	}
+
+	middleClick := func() {
+		gtx.Ops.Reset()
+		e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+		r.Frame(gtx.Ops)
+		r.Queue(
+			pointer.Event{
+				Kind:     pointer.Press,
+				Source:   pointer.Mouse,
+				Buttons:  pointer.ButtonTertiary,
+				Position: f32.Pt(0, 5),
+			},
+			pointer.Event{
+				Kind:     pointer.Release,
+				Source:   pointer.Mouse,
+				Position: f32.Pt(0, 5),
+			},
+		)
+		e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	}
+
+	// A middle click is ignored on platforms without a primary selection.
+	middleClick()
+	if r.PrimaryRequested() {
+		t.Error("middle click requested an unsupported primary selection")
+	}
+	assertContents(t, e, "hello world", 6, 11)
+
+	// A middle click pastes the primary selection at the click position.
+	r.SetPrimarySupported(true)
+	middleClick()
+	if !r.PrimaryRequested() {
+		t.Fatal("middle click didn't request the primary selection")
+	}
+	if r.ClipboardRequested() {
+		t.Error("middle click requested the clipboard")
+	}
+	if got, want := r.PrimaryTypes(), []string{"application/text", "text/plain"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("middle click requested types %q, want %q", got, want)
+	}
+	r.Queue(transfer.DataEvent{
+		Type:    "text/plain",
+		Primary: true,
+		Open: func() io.ReadCloser {
+			return io.NopCloser(strings.NewReader("big "))
+		},
+	})
+	e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	assertContents(t, e, "big hello world", 4, 4)
+	if _, ok := r.WritePrimary(); ok {
+		t.Error("moving the caret wrote the primary selection")
+	}
+}
@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Formatter transforms the text of an Editor as it is edited.
+type Formatter interface {
+	// Format returns the formatted text and the rune offset of the caret
+	// in it, given the text and the rune offset of the caret after an edit.
+	// Formatting formatted text must not change it.
+	Format(text string, caret int) (string, int)
+}
+
+// FormatterFunc adapts a function to the Formatter interface.
+type FormatterFunc func(text string, caret int) (string, int)
+
+func (f FormatterFunc) Format(text string, caret int) (string, int) {
+	return f(text, caret)
+}
+
+// InputMask is a Formatter that lays out the characters entered into an
+// Editor according to a pattern, such as "(999) 999-9999" for phone
+// numbers or "99/99/9999" for dates. In the pattern,
+//
+//   - '9' matches a digit,
+//   - 'a' matches a letter,
+//   - '*' matches a letter or digit,
+//   - 'A' and '#' are like 'a' and '*', but convert letters to upper case,
+//   - '\' makes the character after it a literal.
+//
+// Other characters are literals, which the mask inserts as the text
+// reaches them. Entered characters that don't match the pattern are
+// dropped, as are characters beyond its end.
+type InputMask string
+
+func (m InputMask) Format(text string, caret int) (string, int) {
+	pattern := []rune(m)
+	var b strings.Builder
+	n, pos := 0, -1
+	p := 0
+	i := 0
+	for _, r := range text {
+		if i == caret {
+			pos = n
+		}
+		i++
+		for p < len(pattern) {
+			c, literal := pattern[p], true
+			if c == '\\' && p+1 < len(pattern) {
+				p++
+				c = pattern[p]
+			} else {
+				literal = !strings.ContainsRune("9aA*#", c)
+			}
+			if literal {
+				// Insert the literal, unless it was entered.
+				b.WriteRune(c)
+				n++
+				p++
+				if r == c {
+					break
+				}
+				continue
+			}
+			if r, ok := maskMatch(c, r); ok {
+				b.WriteRune(r)
+				n++
+				p++
+			}
+			// Drop characters that don't match.
+			break
+		}
+	}
+	if pos == -1 {
+		pos = n
+	}
+	return b.String(), pos
+}
+
+// maskMatch reports whether a rune matches an InputMask pattern
+// character, and converts it to upper case if the pattern requires.
+func maskMatch(c, r rune) (rune, bool) {
+	switch c {
+	case '9':
+		return r, unicode.IsDigit(r)
+	case 'a':
+		return r, unicode.IsLetter(r)
+	case 'A':
+		return unicode.ToUpper(r), unicode.IsLetter(r)
+	case '*':
+		return r, unicode.IsLetter(r) || unicode.IsDigit(r)
+	case '#':
+		return unicode.ToUpper(r), unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	return r, false
+}
+
+// NumberFormat is a Formatter for decimal numbers, such as amounts of
+// currency. It keeps a leading minus sign, the digits and one decimal
+// separator, and separates groups of three integer digits.
+type NumberFormat struct {
+	// Decimals is the maximum number of digits after the decimal
+	// separator. Zero means integers.
+	Decimals int
+	// Decimal is the decimal separator. If zero, '.' is used.
+	Decimal rune
+	// Group separates groups of integer digits. Zero means no grouping.
+	Group rune
+}
+
+func (f NumberFormat) decimal() rune {
+	if f.Decimal == 0 {
+		return '.'
+	}
+	return f.Decimal
+}
+
+func (f NumberFormat) Format(text string, caret int) (string, int) {
+	dec := f.decimal()
+	var (
+		neg, point bool
+		digits     []rune
+		decimals   int
+		// kept is the number of runes kept before the caret: the sign,
+		// digits and decimal separator.
+		kept = -1
+		n    int
+	)
+	i := 0
+	for _, r := range text {
+		if i == caret {
+			kept = n
+		}
+		i++
+		switch {
+		case r == '-' && n == 0 && !neg:
+			neg = true
+			n++
+		case r == dec && !point && f.Decimals > 0:
+			point = true
+			n++
+		case r >= '0' && r <= '9':
+			if point {
+				if decimals == f.Decimals {
+					continue
+				}
+				decimals++
+			}
+			digits = append(digits, r)
+			n++
+		}
+	}
+	if kept == -1 {
+		kept = n
+	}
+	intDigits := digits[:len(digits)-decimals]
+	var b strings.Builder
+	pos, out, sig := 0, 0, 0
+	emit := func(r rune, significant bool) {
+		b.WriteRune(r)
+		out++
+		if significant {
+			sig++
+			if sig == kept {
+				pos = out
+			}
+		}
+	}
+	if neg {
+		emit('-', true)
+	}
+	for i, d := range intDigits {
+		if f.Group != 0 && i > 0 && (len(intDigits)-i)%3 == 0 {
+			emit(f.Group, false)
+		}
+		emit(d, true)
+	}
+	if point {
+		emit(dec, true)
+	}
+	for _, d := range digits[len(intDigits):] {
+		emit(d, true)
+	}
+	return b.String(), pos
+}
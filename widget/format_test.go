@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/font"
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+)
+
+func TestInputMask(t *testing.T) {
+	tests := []struct {
+		mask        InputMask
+		text        string
+		caret       int
+		want        string
+		wantCaret   int
+		description string
+	}{
+		{"(999) 999-9999", "5", 1, "(5", 2, "leading literal"},
+		{"(999) 999-9999", "(5551", 5, "(555) 1", 7, "inserted literals"},
+		{"(999) 999-9999", "(555) ", 6, "(555) ", 6, "entered literals"},
+		{"(999) 999-9999", "5551234567890", 13, "(555) 123-4567", 14, "overflow"},
+		{"(999) 999-9999", "(55x5) 1", 4, "(555) 1", 3, "dropped rune"},
+		{"(999) 999-9999", "(555 123", 4, "(555) 123", 4, "deleted literal"},
+		{"(999) 999-9999", "(5595) 123", 4, "(559) 512-3", 4, "inserted digit"},
+		{"99/99/9999", "31121999", 8, "31/12/1999", 10, "date"},
+		{"AA99 #### ####", "de89370400", 10, "DE89 3704 00", 12, "upper case"},
+		{`\9a-9`, "9x1", 3, "9x-1", 4, "escaped literal"},
+	}
+	for _, test := range tests {
+		got, caret := test.mask.Format(test.text, test.caret)
+		if got != test.want || caret != test.wantCaret {
+			t.Errorf("%s: %q.Format(%q, %d) = %q, %d, want %q, %d", test.description, test.mask, test.text, test.caret, got, caret, test.want, test.wantCaret)
+		}
+		if again, _ := test.mask.Format(got, caret); again != got {
+			t.Errorf("%s: formatting %q again gave %q", test.description, got, again)
+		}
+	}
+}
+
+func TestNumberFormat(t *testing.T) {
+	currency := NumberFormat{Decimals: 2, Group: ','}
+	european := NumberFormat{Decimals: 2, Decimal: ',', Group: '.'}
+	tests := []struct {
+		format      NumberFormat
+		text        string
+		caret       int
+		want        string
+		wantCaret   int
+		description string
+	}{
+		{currency, "1234", 4, "1,234", 5, "grouping"},
+		{currency, "1,2345", 6, "12,345", 6, "regrouping"},
+		{currency, "1,234567", 2, "1,234,567", 1, "caret before group"},
+		{currency, "-1234.567", 9, "-1,234.56", 9, "decimals"},
+		{currency, "12a.3.4", 7, "12.34", 5, "invalid runes"},
+		{currency, "1-2", 3, "12", 2, "inner minus"},
+		{european, "1234,5", 6, "1.234,5", 7, "decimal comma"},
+		{NumberFormat{}, "12.5", 4, "125", 3, "integer"},
+	}
+	for _, test := range tests {
+		got, caret := test.format.Format(test.text, test.caret)
+		if got != test.want || caret != test.wantCaret {
+			t.Errorf("%s: Format(%q, %d) = %q, %d, want %q, %d", test.description, test.text, test.caret, got, caret, test.want, test.wantCaret)
+		}
+	}
+}
+
+func TestEditorFormatter(t *testing.T) {
+	e := &Editor{Formatter: InputMask("(999) 999-9999")}
+	r := new(input.Router)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Constraints{Max: image.Pt(100, 100)},
+		Source:      r.Source(),
+		Locale:      english,
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	gtx.Execute(key.FocusCmd{Tag: e})
+	e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	r.Frame(gtx.Ops)
+	for _, c := range "5551234" {
+		start, _ := e.Selection()
+		r.Queue(
+			key.EditEvent{Range: key.Range{Start: start, End: start}, Text: string(c)},
+			key.SelectionEvent{Start: start + 1, End: start + 1},
+		)
+		e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	}
+	assertContents(t, e, "(555) 123-4", 11, 11)
+
+	// Formatting is undone and redone with the typing that caused it.
+	e.Undo()
+	assertContents(t, e, "(555) 1", 7, 7)
+	e.Redo()
+	assertContents(t, e, "(555) 123-4", 10, 9)
+
+	// Filter doesn't apply to the formatted text.
+	e.SetText("")
+	e.Filter = "0123456789"
+	start, _ := e.Selection()
+	r.Queue(
+		key.EditEvent{Range: key.Range{Start: start, End: start}, Text: "55512"},
+		key.SelectionEvent{Start: start + 5, End: start + 5},
+	)
+	e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	assertContents(t, e, "(555) 12", 8, 8)
+
+	// Formatting and its cause form a single step of a limited history.
+	e.HistoryLimit = 1
+	r.Queue(
+		key.EditEvent{Range: key.Range{Start: 8, End: 8}, Text: "34"},
+		key.SelectionEvent{Start: 10, End: 10},
+	)
+	e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	assertContents(t, e, "(555) 123-4", 11, 11)
+	e.Undo()
+	assertContents(t, e, "(555) 12", 8, 8)
+	e.Undo()
+	assertContents(t, e, "(555) 12", 8, 8)
+}
+
+func TestNumberEditor(t *testing.T) {
+	e := &NumberEditor{Min: -1, Max: 100, Step: 0.5, Format: NumberFormat{Decimals: 1}}
+	r := new(input.Router)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Constraints{Max: image.Pt(100, 100)},
+		Source:      r.Source(),
+		Locale:      english,
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	gtx.Execute(key.FocusCmd{Tag: &e.Editor})
+	e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	r.Frame(gtx.Ops)
+	if _, ok := e.Value(); ok {
+		t.Error("empty NumberEditor has a value")
+	}
+
+	press := func(name key.Name, mods key.Modifiers) {
+		t.Helper()
+		r.Queue(key.Event{Name: name, Modifiers: mods, State: key.Press})
+		gtx.Ops.Reset()
+		e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+		r.Frame(gtx.Ops)
+	}
+	press(key.NameUpArrow, 0)
+	if got := e.Text(); got != "0.5" {
+		t.Errorf("got %q after up, want 0.5", got)
+	}
+	press(key.NameDownArrow, 0)
+	press(key.NameDownArrow, 0)
+	press(key.NameDownArrow, 0)
+	press(key.NameDownArrow, 0)
+	if v, ok := e.Value(); !ok || v != -1 {
+		t.Errorf("got value %v, %v after down, want -1", v, ok)
+	}
+	press(key.NameUpArrow, key.ModShift)
+	if got := e.Text(); got != "4.0" {
+		t.Errorf("got %q after shift+up, want 4.0", got)
+	}
+
+	e.SetValue(250)
+	if v, _ := e.Value(); v != 100 {
+		t.Errorf("got value %v, want clamped 100", v)
+	}
+}
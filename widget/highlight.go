@@ -0,0 +1,218 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"gioui.org/font"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+// TextStyle is the style of a range of editor text. Zero fields leave the
+// style of the editor text unchanged.
+type TextStyle struct {
+	// Material is the paint material of the glyphs.
+	Material op.CallOp
+	// Background, if set, is the paint material of the background.
+	Background op.CallOp
+	// Weight is the font weight.
+	Weight font.Weight
+	// Style is the font style.
+	Style font.Style
+	// Decoration is the set of lines drawn along the text.
+	Decoration text.Decoration
+}
+
+// StyledRange is a style applied to a range of runes.
+type StyledRange struct {
+	// Start and End are the rune offsets of the range.
+	Start, End int
+	Style      TextStyle
+}
+
+// Tokenizer styles text line by line, such as for syntax highlighting.
+type Tokenizer interface {
+	// Tokenize returns the styled ranges of a line of text without its
+	// newline, relative to the start of the line. The state is the state
+	// returned for the previous line, or nil for the first line. The
+	// returned state is passed to the next line, so that constructs such
+	// as block comments may span lines. States must be comparable; a
+	// line is tokenized again only if it is edited or its state changes.
+	Tokenize(line string, state any) (ranges []StyledRange, next any)
+}
+
+// merge returns s with the fields set in o replaced.
+func (s TextStyle) merge(o TextStyle) TextStyle {
+	if o.Material != (op.CallOp{}) {
+		s.Material = o.Material
+	}
+	if o.Background != (op.CallOp{}) {
+		s.Background = o.Background
+	}
+	if o.Weight != 0 {
+		s.Weight = o.Weight
+	}
+	if o.Style != 0 {
+		s.Style = o.Style
+	}
+	s.Decoration |= o.Decoration
+	return s
+}
+
+// tokenCache tracks the styles computed by a Tokenizer for the lines of a
+// text.
+type tokenCache struct {
+	tokenizer Tokenizer
+	lines     []tokenLine
+}
+
+// tokenLine is a line of text and its tokens.
+type tokenLine struct {
+	// runes is the length of the line, including its newline.
+	runes int
+	// dirty is set for lines that are edited since tokenized.
+	dirty bool
+	// state and end are the tokenizer states at the start and end of the
+	// line.
+	state, end any
+	// ranges are the styled ranges of the line, relative to its start.
+	ranges []StyledRange
+}
+
+// reset splits txt into dirty lines.
+func (c *tokenCache) reset(txt string) {
+	c.lines = c.lines[:0]
+	for _, l := range strings.SplitAfter(txt, "\n") {
+		c.lines = append(c.lines, tokenLine{runes: utf8.RuneCountInString(l), dirty: true})
+	}
+}
+
+// replace updates the lines for the replacement of the runes between start
+// and end with s.
+func (c *tokenCache) replace(start, end int, s string) {
+	if c.tokenizer == nil {
+		return
+	}
+	// Find the lines i and j containing start and end, and the offset of
+	// line i.
+	i, off := 0, 0
+	for i < len(c.lines)-1 && off+c.lines[i].runes <= start {
+		off += c.lines[i].runes
+		i++
+	}
+	j, endOff := i, off
+	for j < len(c.lines)-1 && endOff+c.lines[j].runes <= end {
+		endOff += c.lines[j].runes
+		j++
+	}
+	pre := start - off
+	post := endOff + c.lines[j].runes - end
+	parts := strings.SplitAfter(s, "\n")
+	repl := make([]tokenLine, len(parts))
+	for k, p := range parts {
+		repl[k] = tokenLine{runes: utf8.RuneCountInString(p), dirty: true}
+	}
+	repl[0].runes += pre
+	repl[len(repl)-1].runes += post
+	c.lines = slices.Replace(c.lines, i, j+1, repl...)
+}
+
+// update tokenizes the dirty lines and the lines whose state changed, and
+// appends the tokens to ranges. Line returns the text of a line.
+func (c *tokenCache) update(line func(start, n int) string, ranges []StyledRange) []StyledRange {
+	var state any
+	off := 0
+	for i := range c.lines {
+		l := &c.lines[i]
+		if l.dirty || l.state != state {
+			n := l.runes
+			if i < len(c.lines)-1 {
+				// Strip the newline.
+				n--
+			}
+			l.ranges, l.end = c.tokenizer.Tokenize(line(off, n), state)
+			l.state = state
+			l.dirty = false
+		}
+		for _, r := range l.ranges {
+			r.Start = min(max(r.Start, 0), l.runes) + off
+			r.End = min(max(r.End, 0), l.runes) + off
+			ranges = append(ranges, r)
+		}
+		state = l.end
+		off += l.runes
+	}
+	return ranges
+}
+
+// shiftRanges adjusts ranges for the replacement of the runes between start
+// and end with n runes, and removes the ranges that become empty.
+func shiftRanges(ranges []StyledRange, start, end, n int) []StyledRange {
+	kept := ranges[:0]
+	for _, r := range ranges {
+		r.Start = shiftOffset(r.Start, start, end, n)
+		r.End = shiftOffset(r.End, start, end, n)
+		if r.Start < r.End {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// shiftOffset adjusts the rune offset pos for the replacement of the runes
+// between start and end with n runes.
+func shiftOffset(pos, start, end, n int) int {
+	newEnd := start + n
+	switch {
+	case newEnd < pos && pos <= end:
+		pos = newEnd
+	case end < pos:
+		pos += newEnd - end
+	}
+	return pos
+}
+
+// flattenStyles returns the styles of ranges as sorted, non-overlapping
+// ranges. Where ranges overlap, the fields set by later ranges take
+// precedence.
+func flattenStyles(ranges []StyledRange) []StyledRange {
+	type boundary struct {
+		pos, idx int
+	}
+	bounds := make([]boundary, 0, 2*len(ranges))
+	for i, r := range ranges {
+		if r.Start < r.End {
+			bounds = append(bounds, boundary{r.Start, i}, boundary{r.End, i})
+		}
+	}
+	slices.SortFunc(bounds, func(a, b boundary) int { return a.pos - b.pos })
+	var flat []StyledRange
+	// active are the indices of the ranges covering the current position,
+	// in order.
+	var active []int
+	for k, b := range bounds {
+		if idx, found := slices.BinarySearch(active, b.idx); found {
+			active = slices.Delete(active, idx, idx+1)
+		} else {
+			active = slices.Insert(active, idx, b.idx)
+		}
+		if k+1 == len(bounds) || bounds[k+1].pos == b.pos || len(active) == 0 {
+			continue
+		}
+		var s TextStyle
+		for _, i := range active {
+			s = s.merge(ranges[i].Style)
+		}
+		end := bounds[k+1].pos
+		if n := len(flat); n > 0 && flat[n-1].End == b.pos && flat[n-1].Style == s {
+			flat[n-1].End = end
+		} else {
+			flat = append(flat, StyledRange{Start: b.pos, End: end, Style: s})
+		}
+	}
+	return flat
+}
@@ -30,6 +30,8 @@ type glyphIndex struct {
 	// lines contains metadata about the size and position of each line of
 	// text.
 	lines []lineInfo
+	// firstLine is the line number of the first line.
+	firstLine int
 
 	// currentLineMin and currentLineMax track the dimensions of the line
 	// that is being indexed.
@@ -56,6 +58,7 @@ func (g *glyphIndex) reset() {
 	g.glyphs = g.glyphs[:0]
 	g.positions = g.positions[:0]
 	g.lines = g.lines[:0]
+	g.firstLine = 0
 	g.currentLineMin = 0
 	g.currentLineMax = 0
 	g.currentLineGlyphs = 0
@@ -347,6 +350,61 @@ type Region struct {
 	Baseline int
 }
 
+// LineInfo describes the position of a line of text, which may wrap onto
+// several screen lines.
+type LineInfo struct {
+	// Line is the zero-based number of the line, counted by newlines.
+	Line int
+	// Start and End are the rune offsets of the line, including its
+	// newline.
+	Start, End int
+	// Top and Bottom are the vertical extent of the line relative to the
+	// containing widget.
+	Top, Bottom int
+	// Baseline is the baseline of the first screen line relative to the
+	// containing widget.
+	Baseline int
+}
+
+// logicalLines returns the lines of text that intersect viewport vertically.
+// The indexed text starts at line number line and rune offset runes. If
+// the lines parameter is non-nil, logicalLines will use it to return
+// results instead of allocating, provided that there is enough capacity.
+func (g *glyphIndex) logicalLines(viewport image.Rectangle, line, runes int, lines []LineInfo) []LineInfo {
+	lines = lines[:0]
+	cur := LineInfo{Line: line, Start: runes}
+	glyph := 0
+	first := true
+	for i, l := range g.lines {
+		if first {
+			cur.Top = l.yOff - l.ascent.Ceil()
+			cur.Baseline = l.yOff
+			first = false
+		}
+		cur.Bottom = l.yOff + l.descent.Ceil()
+		brk := false
+		for _, gl := range g.glyphs[glyph : glyph+l.glyphs] {
+			runes += int(gl.Runes)
+			brk = brk || gl.Flags&text.FlagParagraphBreak != 0
+		}
+		glyph += l.glyphs
+		if !brk && i < len(g.lines)-1 {
+			// The line wraps.
+			continue
+		}
+		cur.End = runes
+		if cur.Bottom > viewport.Min.Y && cur.Top < viewport.Max.Y {
+			cur.Top -= viewport.Min.Y
+			cur.Bottom -= viewport.Min.Y
+			cur.Baseline -= viewport.Min.Y
+			lines = append(lines, cur)
+		}
+		cur = LineInfo{Line: cur.Line + 1, Start: runes}
+		first = true
+	}
+	return lines
+}
+
 // locate returns highlight regions covering the glyphs that represent the runes in
 // [startRune,endRune). If the rects parameter is non-nil, locate will use it to
 // return results instead of allocating, provided that there is enough capacity.
@@ -360,7 +418,7 @@ func (g *glyphIndex) locate(viewport image.Rectangle, startRune, endRune int, re
 	caretStart, _ := g.closestToRune(startRune)
 	caretEnd, _ := g.closestToRune(endRune)
 
-	for lineIdx := caretStart.lineCol.line; lineIdx < len(g.lines); lineIdx++ {
+	for lineIdx := caretStart.lineCol.line; lineIdx < g.firstLine+len(g.lines); lineIdx++ {
 		if lineIdx > caretEnd.lineCol.line {
 			break
 		}
@@ -371,7 +429,7 @@ func (g *glyphIndex) locate(viewport image.Rectangle, startRune, endRune int, re
 		if int(pos.y)-pos.ascent.Ceil() > viewport.Max.Y {
 			break
 		}
-		line := g.lines[lineIdx]
+		line := g.lines[lineIdx-g.firstLine]
 		if lineIdx > caretStart.lineCol.line && lineIdx < caretEnd.lineCol.line {
 			startX := line.xOff
 			endX := startX + line.width
@@ -4,10 +4,12 @@ package widget
 
 import (
 	"image"
+	"strings"
 
 	"gioui.org/f32"
 	"gioui.org/font"
 	"gioui.org/io/semantic"
+	"gioui.org/io/system"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/op/clip"
@@ -36,6 +38,15 @@ type Label struct {
 	// LineHeightScale applies a scaling factor to the LineHeight. If zero, a
 	// sensible default will be used.
 	LineHeightScale float32
+	// Decoration is the set of lines drawn along the text.
+	Decoration text.Decoration
+	// LetterSpacing is added between the characters of the text.
+	LetterSpacing unit.Sp
+	// WordSpacing is added to the spaces between words.
+	WordSpacing unit.Sp
+	// TabWidth is the interval between tab stops. If zero, tabs are as
+	// wide as their glyph.
+	TabWidth unit.Sp
 }
 
 // Layout the label with the given shaper, font, size, text, and material.
@@ -44,6 +55,102 @@ func (l Label) Layout(gtx layout.Context, lt *text.Shaper, font font.Font, size
 	return dims
 }
 
+// SpanStyle describes a span of rich text.
+type SpanStyle struct {
+	// Content is the text of the span.
+	Content string
+	// Font is the font of the span, including its weight and style.
+	Font font.Font
+	// Size is the size of the span text. If zero, the size given to
+	// LayoutSpans is used.
+	Size unit.Sp
+	// Material is the paint material for the span glyphs.
+	Material op.CallOp
+	// Background, if set, is the paint material for the background of
+	// the span.
+	Background op.CallOp
+	// Interactive marks spans such as hyperlinks and mentions that can be
+	// clicked when laid out by a RichText.
+	Interactive bool
+	// Payload is reported by the clicks of interactive spans.
+	Payload any
+	// Decoration is the set of lines drawn along the span text.
+	Decoration text.Decoration
+}
+
+// LayoutSpans lays out and draws rich text made of spans. The spans are
+// shaped together, so lines wrap across span boundaries.
+func (l Label) LayoutSpans(gtx layout.Context, lt *text.Shaper, size unit.Sp, spans []SpanStyle) (layout.Dimensions, TextInfo) {
+	var info TextInfo
+	dims := layoutText(gtx, func(gtx layout.Context) layout.Dimensions {
+		var dims layout.Dimensions
+		dims, info = l.layoutSpans(gtx, lt, size, spans, nil)
+		return dims
+	})
+	return dims, info
+}
+
+// layoutSpans is LayoutSpans that also adds the visible glyphs to index, if
+// non-nil.
+func (l Label) layoutSpans(gtx layout.Context, lt *text.Shaper, size unit.Sp, spans []SpanStyle, index *glyphIndex) (layout.Dimensions, TextInfo) {
+	cs := gtx.Constraints
+	tspans := make([]text.Span, len(spans))
+	var content strings.Builder
+	for i, sp := range spans {
+		tspans[i] = text.Span{
+			Text:    sp.Content,
+			Font:    sp.Font,
+			PxPerEm: fixed.I(gtx.Sp(sp.Size)),
+		}
+		content.WriteString(sp.Content)
+	}
+	lt.LayoutSpans(text.Parameters{
+		PxPerEm:         fixed.I(gtx.Sp(size)),
+		MaxLines:        l.MaxLines,
+		Truncator:       l.Truncator,
+		Alignment:       l.Alignment,
+		WrapPolicy:      l.WrapPolicy,
+		MaxWidth:        cs.Max.X,
+		MinWidth:        cs.Min.X,
+		Locale:          gtx.Locale,
+		LineHeight:      fixed.I(gtx.Sp(l.LineHeight)),
+		LineHeightScale: l.LineHeightScale,
+		LetterSpacing:   fixed.I(gtx.Sp(l.LetterSpacing)),
+		WordSpacing:     fixed.I(gtx.Sp(l.WordSpacing)),
+		TabWidth:        fixed.I(gtx.Sp(l.TabWidth)),
+	}, tspans)
+	m := op.Record(gtx.Ops)
+	viewport := image.Rectangle{Max: cs.Max}
+	it := textIterator{
+		viewport:   viewport,
+		maxLines:   l.MaxLines,
+		spans:      spans,
+		decoration: l.Decoration,
+	}
+	semantic.LabelOp(content.String()).Add(gtx.Ops)
+	var glyphs [32]text.Glyph
+	line := glyphs[:0]
+	for g, ok := lt.NextGlyph(); ok; g, ok = lt.NextGlyph() {
+		if index != nil {
+			index.Glyph(g)
+		}
+		var ok bool
+		if line, ok = it.paintGlyph(gtx, lt, g, line); !ok {
+			break
+		}
+	}
+	call := m.Stop()
+	viewport.Min = viewport.Min.Add(it.padding.Min)
+	viewport.Max = viewport.Max.Add(it.padding.Max)
+	clipStack := clip.Rect(viewport).Push(gtx.Ops)
+	call.Add(gtx.Ops)
+	dims := layout.Dimensions{Size: it.bounds.Size()}
+	dims.Size = cs.Constrain(dims.Size)
+	dims.Baseline = dims.Size.Y - it.baseline
+	clipStack.Pop()
+	return dims, TextInfo{Truncated: it.truncated}
+}
+
 // TextInfo provides metadata about shaped text.
 type TextInfo struct {
 	// Truncated contains the number of runes of text that are represented by a truncator
@@ -53,6 +160,39 @@ type TextInfo struct {
 
 // Layout the label with the given shaper, font, size, text, and material, returning metadata about the shaped text.
 func (l Label) LayoutDetailed(gtx layout.Context, lt *text.Shaper, font font.Font, size unit.Sp, txt string, textMaterial op.CallOp) (layout.Dimensions, TextInfo) {
+	var info TextInfo
+	dims := layoutText(gtx, func(gtx layout.Context) layout.Dimensions {
+		var dims layout.Dimensions
+		dims, info = l.layoutDetailed(gtx, lt, font, size, txt, textMaterial)
+		return dims
+	})
+	return dims, info
+}
+
+// layoutText lays out text with w. Vertical text is laid out by w as
+// horizontal lines within the transposed constraints, and rotated 90°
+// clockwise so that the lines become columns progressing from right to
+// left.
+func layoutText(gtx layout.Context, w layout.Widget) layout.Dimensions {
+	if gtx.Locale.Direction.Axis() != system.Vertical {
+		return w(gtx)
+	}
+	cs := gtx.Constraints
+	gtx.Constraints = layout.Constraints{
+		Min: image.Pt(cs.Min.Y, cs.Min.X),
+		Max: image.Pt(cs.Max.Y, cs.Max.X),
+	}
+	m := op.Record(gtx.Ops)
+	dims := w(gtx)
+	call := m.Stop()
+	sz := dims.Size
+	defer op.Affine(f32.NewAffine2D(0, -1, float32(sz.Y), 1, 0, 0)).Push(gtx.Ops).Pop()
+	call.Add(gtx.Ops)
+	return layout.Dimensions{Size: image.Pt(sz.Y, sz.X)}
+}
+
+// layoutDetailed is LayoutDetailed for horizontal lines.
+func (l Label) layoutDetailed(gtx layout.Context, lt *text.Shaper, font font.Font, size unit.Sp, txt string, textMaterial op.CallOp) (layout.Dimensions, TextInfo) {
 	cs := gtx.Constraints
 	textSize := fixed.I(gtx.Sp(size))
 	lineHeight := fixed.I(gtx.Sp(l.LineHeight))
@@ -68,13 +208,17 @@ func (l Label) LayoutDetailed(gtx layout.Context, lt *text.Shaper, font font.Fon
 		Locale:          gtx.Locale,
 		LineHeight:      lineHeight,
 		LineHeightScale: l.LineHeightScale,
+		LetterSpacing:   fixed.I(gtx.Sp(l.LetterSpacing)),
+		WordSpacing:     fixed.I(gtx.Sp(l.WordSpacing)),
+		TabWidth:        fixed.I(gtx.Sp(l.TabWidth)),
 	}, txt)
 	m := op.Record(gtx.Ops)
 	viewport := image.Rectangle{Max: cs.Max}
 	it := textIterator{
-		viewport: viewport,
-		maxLines: l.MaxLines,
-		material: textMaterial,
+		viewport:   viewport,
+		maxLines:   l.MaxLines,
+		material:   textMaterial,
+		decoration: l.Decoration,
 	}
 	semantic.LabelOp(txt).Add(gtx.Ops)
 	var glyphs [32]text.Glyph
@@ -108,6 +252,12 @@ type textIterator struct {
 	// the color of the glyphs is undefined and may change unpredictably if the
 	// text contains color glyphs.
 	material op.CallOp
+	// spans, if set, provides the materials of rich text glyphs by span
+	// index.
+	spans []SpanStyle
+	// decoration is the set of lines drawn along the glyphs. The decorations
+	// of spans are added to it.
+	decoration text.Decoration
 	// truncated tracks the count of truncated runes in the text.
 	truncated int
 	// linesSeen tracks the quantity of line endings this iterator has seen.
@@ -204,23 +354,71 @@ func fixedToFloat(i fixed.Int26_6) float32 {
 func (it *textIterator) paintGlyph(gtx layout.Context, shaper *text.Shaper, glyph text.Glyph, line []text.Glyph) ([]text.Glyph, bool) {
 	visibleOrBefore := it.processGlyph(glyph, true)
 	if it.visible {
+		if len(line) > 0 && line[0].Span != glyph.Span {
+			// Paint the glyphs of the previous span with their material.
+			line = it.paintLine(gtx, shaper, line)
+		}
 		if len(line) == 0 {
 			it.lineOff = f32.Point{X: fixedToFloat(glyph.X), Y: float32(glyph.Y)}.Sub(layout.FPt(it.viewport.Min))
 		}
 		line = append(line, glyph)
 	}
 	if glyph.Flags&text.FlagLineBreak != 0 || cap(line)-len(line) == 0 || !visibleOrBefore {
-		t := op.Affine(f32.Affine2D{}.Offset(it.lineOff)).Push(gtx.Ops)
-		path := shaper.Shape(line)
-		outline := clip.Outline{Path: path}.Op().Push(gtx.Ops)
-		it.material.Add(gtx.Ops)
-		paint.PaintOp{}.Add(gtx.Ops)
-		outline.Pop()
-		if call := shaper.Bitmaps(line); call != (op.CallOp{}) {
-			call.Add(gtx.Ops)
-		}
-		t.Pop()
-		line = line[:0]
+		line = it.paintLine(gtx, shaper, line)
 	}
 	return line, visibleOrBefore
 }
+
+// paintLine paints the buffered glyphs of a line, which must all belong to
+// the same span, and returns the emptied buffer.
+func (it *textIterator) paintLine(gtx layout.Context, shaper *text.Shaper, line []text.Glyph) []text.Glyph {
+	material, decoration := it.material, it.decoration
+	var background op.CallOp
+	if len(line) > 0 && line[0].Span < len(it.spans) {
+		sp := it.spans[line[0].Span]
+		// Spans without a material use the material of the text.
+		if sp.Material != (op.CallOp{}) {
+			material = sp.Material
+		}
+		background = sp.Background
+		decoration |= sp.Decoration
+	}
+	t := op.Affine(f32.Affine2D{}.Offset(it.lineOff)).Push(gtx.Ops)
+	if background != (op.CallOp{}) {
+		// Glyphs are in logical order, so find the visual extent of the
+		// span relative to the first glyph.
+		first := line[0]
+		start, end := first.X, first.X+first.Advance
+		for _, g := range line[1:] {
+			if g.X < start {
+				start = g.X
+			}
+			if e := g.X + g.Advance; e > end {
+				end = e
+			}
+		}
+		bg := clip.Rect{
+			Min: image.Pt((start - first.X).Floor(), -first.Ascent.Ceil()),
+			Max: image.Pt((end - first.X).Ceil(), first.Descent.Ceil()),
+		}.Push(gtx.Ops)
+		background.Add(gtx.Ops)
+		paint.PaintOp{}.Add(gtx.Ops)
+		bg.Pop()
+	}
+	path := shaper.Shape(line)
+	outline := clip.Outline{Path: path}.Op().Push(gtx.Ops)
+	material.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+	outline.Pop()
+	if decoration != 0 && len(line) > 0 {
+		deco := clip.Outline{Path: shaper.Decorations(line, decoration)}.Op().Push(gtx.Ops)
+		material.Add(gtx.Ops)
+		paint.PaintOp{}.Add(gtx.Ops)
+		deco.Pop()
+	}
+	if call := shaper.Bitmaps(line); call != (op.CallOp{}) {
+		call.Add(gtx.Ops)
+	}
+	t.Pop()
+	return line[:0]
+}
@@ -5,6 +5,10 @@ import (
 	"math"
 	"testing"
 
+	"gioui.org/font"
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
 	"gioui.org/text"
 	"golang.org/x/image/math/fixed"
 )
@@ -227,3 +231,37 @@ func TestGlyphIteratorPadding(t *testing.T) {
 		})
 	}
 }
+
+func TestLabelSpans(t *testing.T) {
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Constraints{Max: image.Pt(100, 1000)},
+	}
+	const txt = "Lorem ipsum dolor sit amet, consectetur adipiscing elit"
+	var l Label
+	want := l.Layout(gtx, shaper, font.Font{}, 10, txt, op.CallOp{})
+	// Equally styled spans lay out like plain text.
+	got, _ := l.LayoutSpans(gtx, shaper, 10, []SpanStyle{
+		{Content: txt[:12]},
+		{Content: txt[12:30], Background: op.Record(gtx.Ops).Stop()},
+		{Content: txt[30:]},
+	})
+	if got != want {
+		t.Errorf("spans dimensions %v, want %v", got, want)
+	}
+	// A larger span increases the line height.
+	got, _ = l.LayoutSpans(gtx, shaper, 10, []SpanStyle{
+		{Content: txt[:12]},
+		{Content: txt[12:30], Size: 20, Font: font.Font{Weight: font.Bold}},
+		{Content: txt[30:]},
+	})
+	if got.Size.Y <= want.Size.Y {
+		t.Errorf("larger span height %d, want more than %d", got.Size.Y, want.Size.Y)
+	}
+	l.MaxLines = 1
+	_, info := l.LayoutSpans(gtx, shaper, 10, []SpanStyle{{Content: txt[:12]}, {Content: txt[12:]}})
+	if info.Truncated == 0 {
+		t.Error("spans not truncated to a single line")
+	}
+}
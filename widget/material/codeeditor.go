@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package material
+
+import (
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/font"
+	"gioui.org/internal/f32color"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+)
+
+// CodeEditorStyle draws a widget.CodeEditor with a gutter of line numbers,
+// fold markers and annotation markers.
+type CodeEditorStyle struct {
+	Font     font.Font
+	TextSize unit.Sp
+	// TabWidth is the interval between tab stops. If zero, tabs are as
+	// wide as their glyph.
+	TabWidth unit.Sp
+	// Color is the text color.
+	Color color.NRGBA
+	// SelectionColor is the color of the background for selected text.
+	SelectionColor color.NRGBA
+	// LineColor is the color of the background of the caret line.
+	LineColor color.NRGBA
+	// MatchColor is the color of the background of matching brackets.
+	MatchColor color.NRGBA
+	// NumberColor is the color of the line numbers, and MarkerColor the
+	// color of the fold markers.
+	NumberColor, MarkerColor color.NRGBA
+	// InfoColor, WarningColor and ErrorColor are the colors of the
+	// annotation markers and the underlines of diagnostics.
+	InfoColor, WarningColor, ErrorColor color.NRGBA
+	Editor                              *widget.CodeEditor
+
+	shaper *text.Shaper
+}
+
+func CodeEditor(th *Theme, editor *widget.CodeEditor) CodeEditorStyle {
+	return CodeEditorStyle{
+		Editor:         editor,
+		Font:           font.Font{Typeface: "Go Mono, monospace"},
+		TextSize:       th.TextSize,
+		Color:          th.Palette.Fg,
+		SelectionColor: f32color.MulAlpha(th.Palette.ContrastBg, 0x60),
+		LineColor:      f32color.MulAlpha(th.Palette.Fg, 0x0c),
+		MatchColor:     f32color.MulAlpha(th.Palette.ContrastBg, 0x40),
+		NumberColor:    f32color.MulAlpha(th.Palette.Fg, 0x80),
+		MarkerColor:    f32color.MulAlpha(th.Palette.Fg, 0x80),
+		InfoColor:      th.Palette.ContrastBg,
+		WarningColor:   rgb(0xf9a825),
+		ErrorColor:     rgb(0xd32f2f),
+		shaper:         th.Shaper,
+	}
+}
+
+func (c CodeEditorStyle) Layout(gtx layout.Context) layout.Dimensions {
+	textColor := colorMaterial(gtx.Ops, c.Color)
+	selectionColor := colorMaterial(gtx.Ops, blendDisabledColor(!gtx.Enabled(), c.SelectionColor))
+	lineColor := colorMaterial(gtx.Ops, c.LineColor)
+	matchColor := colorMaterial(gtx.Ops, c.MatchColor)
+
+	// The gutter has room for an annotation marker, the line numbers of
+	// the last layout and a fold marker.
+	marker := gtx.Sp(c.TextSize)
+	digits := 3
+	if lines := c.Editor.Lines(); len(lines) > 0 {
+		digits = max(digits, len(strconv.Itoa(lines[len(lines)-1].Line+1)))
+	}
+	numbers := widget.Label{MaxLines: 1, Alignment: text.End}
+	macro := op.Record(gtx.Ops)
+	ngtx := gtx
+	ngtx.Constraints.Min.X = 0
+	numberWidth := numbers.Layout(ngtx, c.shaper, c.Font, c.TextSize, strings.Repeat("0", digits), op.CallOp{}).Size.X
+	macro.Stop()
+	gutterWidth := 2*marker + numberWidth + marker/2
+
+	numberColor := colorMaterial(gtx.Ops, c.NumberColor)
+	gutter := func(gtx layout.Context) layout.Dimensions {
+		caret, hasCaret := c.Editor.CaretLine()
+		for _, l := range c.Editor.Lines() {
+			if anns := c.Editor.Annotations(l.Line); len(anns) > 0 {
+				sev := anns[0].Severity
+				for _, a := range anns {
+					if a.Severity > sev {
+						sev = a.Severity
+					}
+				}
+				col := c.InfoColor
+				switch sev {
+				case widget.SeverityWarning:
+					col = c.WarningColor
+				case widget.SeverityError:
+					col = c.ErrorColor
+				}
+				d := marker * 2 / 3
+				at := image.Pt((marker-d)/2, l.Baseline-d)
+				paint.FillShape(gtx.Ops, col, clip.Ellipse{Min: at, Max: at.Add(image.Pt(d, d))}.Op(gtx.Ops))
+			}
+
+			ngtx := gtx
+			ngtx.Constraints = layout.Exact(image.Pt(numberWidth, gtx.Constraints.Max.Y))
+			col := numberColor
+			if hasCaret && l.Line == caret.Line {
+				col = textColor
+			}
+			macro := op.Record(gtx.Ops)
+			dims := numbers.Layout(ngtx, c.shaper, c.Font, c.TextSize, strconv.Itoa(l.Line+1), col)
+			call := macro.Stop()
+			// Align the baselines of the number and the line.
+			y := l.Baseline - (dims.Size.Y - dims.Baseline)
+			off := op.Offset(image.Pt(marker, y)).Push(gtx.Ops)
+			call.Add(gtx.Ops)
+			off.Pop()
+
+			if f, ok := c.Editor.FoldAt(l.Line); ok {
+				c.foldMarker(gtx, image.Pt(marker+numberWidth+marker/2, l.Baseline), marker, f.Folded)
+			}
+		}
+		return layout.Dimensions{Size: gtx.Constraints.Min}
+	}
+
+	c.Editor.TabWidth = c.TabWidth
+	dims := c.Editor.Layout(gtx, c.shaper, c.Font, c.TextSize, gutterWidth, gutter, textColor, selectionColor, lineColor, matchColor)
+	off := op.Offset(image.Pt(gutterWidth, 0)).Push(gtx.Ops)
+	c.Editor.PaintDiagnostics(gtx, colorMaterial(gtx.Ops, c.InfoColor), colorMaterial(gtx.Ops, c.WarningColor), colorMaterial(gtx.Ops, c.ErrorColor))
+	off.Pop()
+	return dims
+}
+
+// foldMarker draws a triangle with its base on the baseline at pos,
+// pointing right if folded and down otherwise.
+func (c CodeEditorStyle) foldMarker(gtx layout.Context, pos image.Point, size int, folded bool) {
+	s := float32(size) / 2
+	o := f32.Point{X: float32(pos.X) + s/2, Y: float32(pos.Y) - s}
+	var p clip.Path
+	p.Begin(gtx.Ops)
+	if folded {
+		p.MoveTo(o.Add(f32.Pt(s/4, 0)))
+		p.LineTo(o.Add(f32.Pt(s*3/4, s/2)))
+		p.LineTo(o.Add(f32.Pt(s/4, s)))
+	} else {
+		p.MoveTo(o.Add(f32.Pt(0, s/4)))
+		p.LineTo(o.Add(f32.Pt(s, s/4)))
+		p.LineTo(o.Add(f32.Pt(s/2, s*3/4)))
+	}
+	p.Close()
+	paint.FillShape(gtx.Ops, c.MarkerColor, clip.Outline{Path: p.End()}.Op())
+}
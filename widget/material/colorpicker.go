@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package material
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/f32"
+	"gioui.org/internal/f32color"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+)
+
+type ColorPickerStyle struct {
+	Picker *widget.ColorPicker
+	Hex    EditorStyle
+	// Color is the color of the slider thumbs and the swatch outlines.
+	Color color.NRGBA
+	// SquareSize is the height of the saturation and value square.
+	SquareSize unit.Dp
+	// BarSize is the height of the hue and alpha sliders.
+	BarSize unit.Dp
+	// SwatchSize is the size of the color swatches.
+	SwatchSize unit.Dp
+}
+
+// ColorPicker displays a saturation and value square, hue and alpha
+// sliders, a hexadecimal input and the swatches of picker.
+func ColorPicker(th *Theme, picker *widget.ColorPicker) ColorPickerStyle {
+	return ColorPickerStyle{
+		Picker:     picker,
+		Hex:        Editor(th, &picker.Hex, "#RRGGBB"),
+		Color:      th.Palette.Fg,
+		SquareSize: 160,
+		BarSize:    16,
+		SwatchSize: 24,
+	}
+}
+
+func (p ColorPickerStyle) Layout(gtx layout.Context) layout.Dimensions {
+	p.Picker.Update(gtx)
+	gap := layout.Spacer{Height: 8}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(p.layoutSquare),
+		layout.Rigid(gap.Layout),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return p.layoutBar(gtx, &p.Picker.Hue, p.paintHue)
+		}),
+		layout.Rigid(gap.Layout),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return p.layoutBar(gtx, &p.Picker.Alpha, p.paintAlpha)
+		}),
+		layout.Rigid(gap.Layout),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return p.layoutSwatch(gtx, p.Picker.Color())
+				}),
+				layout.Rigid(layout.Spacer{Width: 8}.Layout),
+				layout.Flexed(1, p.Hex.Layout),
+			)
+		}),
+		layout.Rigid(gap.Layout),
+		layout.Rigid(p.layoutSwatches),
+	)
+}
+
+func (p ColorPickerStyle) layoutSquare(gtx layout.Context) layout.Dimensions {
+	size := image.Pt(gtx.Constraints.Max.X, gtx.Dp(p.SquareSize))
+	size.Y = min(max(size.Y, gtx.Constraints.Min.Y), gtx.Constraints.Max.Y)
+	r := image.Rectangle{Max: size}
+	fsize := layout.FPt(size)
+	h := p.Picker.Hue.Value
+	paint.FillShape(gtx.Ops, f32color.HSVToNRGBA(h, 1, 1, 0xff), clip.Rect(r).Op())
+	// Saturation decreases towards white on the left, value decreases
+	// towards black at the bottom.
+	gradient(gtx.Ops, r,
+		f32.Point{}, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+		f32.Pt(fsize.X, 0), color.NRGBA{R: 0xff, G: 0xff, B: 0xff},
+	)
+	gradient(gtx.Ops, r,
+		f32.Point{}, color.NRGBA{},
+		f32.Pt(0, fsize.Y), color.NRGBA{A: 0xff},
+	)
+	gtx.Constraints = layout.Exact(size)
+	p.Picker.LayoutSaturationValue(gtx)
+	s, v := p.Picker.SaturationValue()
+	pos := image.Pt(int(s*fsize.X+.5), int((1-v)*fsize.Y+.5))
+	thumb := p.Color
+	if v < .5 {
+		thumb = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	}
+	rr := gtx.Dp(6)
+	cl := clip.Rect(r).Push(gtx.Ops)
+	paint.FillShape(gtx.Ops, thumb, clip.Stroke{
+		Path:  clip.Ellipse{Min: pos.Sub(image.Pt(rr, rr)), Max: pos.Add(image.Pt(rr, rr))}.Path(gtx.Ops),
+		Width: float32(gtx.Dp(2)),
+	}.Op())
+	cl.Pop()
+	return layout.Dimensions{Size: size}
+}
+
+// layoutBar lays out a horizontal slider for f, with a track painted by
+// track.
+func (p ColorPickerStyle) layoutBar(gtx layout.Context, f *widget.Float, track func(ops *op.Ops, r image.Rectangle)) layout.Dimensions {
+	size := image.Pt(gtx.Constraints.Max.X, gtx.Dp(p.BarSize))
+	r := image.Rectangle{Max: size}
+	rr := size.Y / 2
+	cl := clip.UniformRRect(r, rr).Push(gtx.Ops)
+	track(gtx.Ops, r)
+	cl.Pop()
+	// Inset the slider so the thumb stays inside the track.
+	off := op.Offset(image.Pt(rr, 0)).Push(gtx.Ops)
+	gtx.Constraints = layout.Exact(image.Pt(max(size.X-2*rr, 0), size.Y))
+	f.Layout(gtx, layout.Horizontal, 0)
+	x := int(f.Value*float32(gtx.Constraints.Min.X) + .5)
+	thumb := image.Rect(x-rr, 0, x+rr, size.Y)
+	paint.FillShape(gtx.Ops, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, clip.Stroke{
+		Path:  clip.Ellipse(thumb.Inset(gtx.Dp(1))).Path(gtx.Ops),
+		Width: float32(gtx.Dp(2)),
+	}.Op())
+	paint.FillShape(gtx.Ops, p.Color, clip.Stroke{
+		Path:  clip.Ellipse(thumb).Path(gtx.Ops),
+		Width: float32(gtx.Dp(1)),
+	}.Op())
+	off.Pop()
+	return layout.Dimensions{Size: size}
+}
+
+// paintHue paints the hue spectrum as a gradient for each of its six
+// segments.
+func (p ColorPickerStyle) paintHue(ops *op.Ops, r image.Rectangle) {
+	const n = 6
+	w := float32(r.Dx())
+	for i := range n {
+		x0, x1 := w*float32(i)/n, w*float32(i+1)/n
+		seg := image.Rect(int(x0), r.Min.Y, int(x1+1), r.Max.Y)
+		gradient(ops, seg,
+			f32.Pt(x0, 0), f32color.HSVToNRGBA(float32(i)/n, 1, 1, 0xff),
+			f32.Pt(x1, 0), f32color.HSVToNRGBA(float32(i+1)/n, 1, 1, 0xff),
+		)
+	}
+}
+
+// paintAlpha paints the selected color from transparent to opaque over a
+// checkerboard.
+func (p ColorPickerStyle) paintAlpha(ops *op.Ops, r image.Rectangle) {
+	checkerboard(ops, r, r.Dy()/2)
+	c := p.Picker.Color()
+	c.A = 0xff
+	gradient(ops, r,
+		f32.Point{}, f32color.MulAlpha(c, 0),
+		f32.Pt(float32(r.Dx()), 0), c,
+	)
+}
+
+func (p ColorPickerStyle) layoutSwatch(gtx layout.Context, c color.NRGBA) layout.Dimensions {
+	sz := gtx.Dp(p.SwatchSize)
+	r := image.Rect(0, 0, sz, sz)
+	rr := gtx.Dp(4)
+	cl := clip.UniformRRect(r, rr).Push(gtx.Ops)
+	if c.A != 0xff {
+		checkerboard(gtx.Ops, r, sz/4)
+	}
+	paint.ColorOp{Color: c}.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+	cl.Pop()
+	paint.FillShape(gtx.Ops, f32color.MulAlpha(p.Color, 0x40), clip.Stroke{
+		Path:  clip.UniformRRect(r, rr).Path(gtx.Ops),
+		Width: float32(gtx.Dp(1)),
+	}.Op())
+	return layout.Dimensions{Size: r.Max}
+}
+
+func (p ColorPickerStyle) layoutSwatches(gtx layout.Context) layout.Dimensions {
+	children := make([]layout.FlexChild, 0, 2*len(p.Picker.Swatches))
+	for i, c := range p.Picker.Swatches {
+		if i > 0 {
+			children = append(children, layout.Rigid(layout.Spacer{Width: 4}.Layout))
+		}
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return p.Picker.Swatch(i).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutSwatch(gtx, c)
+			})
+		}))
+	}
+	return layout.Flex{}.Layout(gtx, children...)
+}
+
+// gradient fills r with a linear gradient from c1 at p1 to c2 at p2.
+func gradient(ops *op.Ops, r image.Rectangle, p1 f32.Point, c1 color.NRGBA, p2 f32.Point, c2 color.NRGBA) {
+	defer clip.Rect(r).Push(ops).Pop()
+	paint.LinearGradientOp{Stop1: p1, Color1: c1, Stop2: p2, Color2: c2}.Add(ops)
+	paint.PaintOp{}.Add(ops)
+}
+
+// checkerboard fills r with a light and dark checkerboard with squares of
+// size sz.
+func checkerboard(ops *op.Ops, r image.Rectangle, sz int) {
+	paint.FillShape(ops, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, clip.Rect(r).Op())
+	if sz <= 0 {
+		return
+	}
+	dark := color.NRGBA{R: 0xcc, G: 0xcc, B: 0xcc, A: 0xff}
+	for y := r.Min.Y; y < r.Max.Y; y += sz {
+		for x := r.Min.X + (y-r.Min.Y)/sz%2*sz; x < r.Max.X; x += 2 * sz {
+			sq := image.Rect(x, y, x+sz, y+sz).Intersect(r)
+			paint.FillShape(ops, dark, clip.Rect(sq).Op())
+		}
+	}
+}
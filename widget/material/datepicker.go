@@ -0,0 +1,265 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package material
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"gioui.org/font"
+	"gioui.org/internal/f32color"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+)
+
+type DatePickerStyle struct {
+	Picker *widget.DatePicker
+	// Color is the text color.
+	Color color.NRGBA
+	// Background is the color of the picker surface.
+	Background color.NRGBA
+	// SelectedColor and SelectedTextColor are the background and text
+	// colors of selected days.
+	SelectedColor     color.NRGBA
+	SelectedTextColor color.NRGBA
+	// RangeColor is the background color of days inside a selected range.
+	RangeColor   color.NRGBA
+	Font         font.Font
+	TextSize     unit.Sp
+	CornerRadius unit.Dp
+	// CellSize is the size of the day cells.
+	CellSize unit.Dp
+	shaper   *text.Shaper
+}
+
+type TimePickerStyle struct {
+	Picker *widget.TimePicker
+	// Color is the text color.
+	Color color.NRGBA
+	// FocusColor is the background color of the focused field.
+	FocusColor color.NRGBA
+	Font       font.Font
+	TextSize   unit.Sp
+	Inset      layout.Inset
+	shaper     *text.Shaper
+}
+
+type DateFieldStyle struct {
+	Field  *widget.DateField
+	Editor EditorStyle
+	Picker DatePickerStyle
+	// Color is the color of the popup toggle.
+	Color  color.NRGBA
+	shaper *text.Shaper
+}
+
+// DatePicker displays a calendar month for picking dates.
+func DatePicker(th *Theme, picker *widget.DatePicker) DatePickerStyle {
+	p := DatePickerStyle{
+		Picker:            picker,
+		Color:             th.Palette.Fg,
+		Background:        th.Palette.Bg,
+		SelectedColor:     th.Palette.ContrastBg,
+		SelectedTextColor: th.Palette.ContrastFg,
+		RangeColor:        f32color.MulAlpha(th.Palette.ContrastBg, 0x30),
+		TextSize:          th.TextSize * 14.0 / 16.0,
+		CornerRadius:      4,
+		CellSize:          36,
+		shaper:            th.Shaper,
+	}
+	p.Font.Typeface = th.Face
+	return p
+}
+
+// TimePicker displays the fields of a time of day.
+func TimePicker(th *Theme, picker *widget.TimePicker) TimePickerStyle {
+	p := TimePickerStyle{
+		Picker:     picker,
+		Color:      th.Palette.Fg,
+		FocusColor: f32color.MulAlpha(th.Palette.ContrastBg, 0x40),
+		TextSize:   th.TextSize,
+		Inset:      layout.UniformInset(4),
+		shaper:     th.Shaper,
+	}
+	p.Font.Typeface = th.Face
+	return p
+}
+
+// DateField displays an editor for a date with a popup date picker.
+func DateField(th *Theme, field *widget.DateField, hint string) DateFieldStyle {
+	return DateFieldStyle{
+		Field:  field,
+		Editor: Editor(th, &field.Editor, hint),
+		Picker: DatePicker(th, &field.Picker),
+		Color:  th.Palette.ContrastBg,
+		shaper: th.Shaper,
+	}
+}
+
+func (p DatePickerStyle) label(txt string, col color.NRGBA) LabelStyle {
+	return LabelStyle{
+		Text:      txt,
+		Color:     col,
+		Font:      p.Font,
+		TextSize:  p.TextSize,
+		MaxLines:  1,
+		Alignment: text.Middle,
+		Shaper:    p.shaper,
+	}
+}
+
+// Layout the month title, the weekday names and the grid of days.
+func (p DatePickerStyle) Layout(gtx layout.Context) layout.Dimensions {
+	p.Picker.Update(gtx)
+	cell := gtx.Dp(p.CellSize)
+	gtx.Constraints.Min = image.Point{}
+	gtx.Constraints.Max.X = min(gtx.Constraints.Max.X, 7*cell)
+	macro := op.Record(gtx.Ops)
+	dims := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(p.layoutTitle),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			days := p.Picker.Weekdays(gtx.Locale)
+			var children [7]layout.FlexChild
+			for i, d := range days {
+				children[i] = layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+					gtx.Constraints.Min.X = gtx.Constraints.Max.X
+					return layout.UniformInset(4).Layout(gtx,
+						p.label(widget.WeekdayName(gtx.Locale, d), f32color.MulAlpha(p.Color, 0x99)).Layout)
+				})
+			}
+			return layout.Flex{}.Layout(gtx, children[:]...)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return p.Picker.Layout(gtx, p.layoutDay)
+		}),
+	)
+	call := macro.Stop()
+	rr := gtx.Dp(p.CornerRadius)
+	paint.FillShape(gtx.Ops, p.Background, clip.UniformRRect(image.Rectangle{Max: dims.Size}, rr).Op(gtx.Ops))
+	call.Add(gtx.Ops)
+	return dims
+}
+
+func (p DatePickerStyle) layoutTitle(gtx layout.Context) layout.Dimensions {
+	month := p.Picker.Month()
+	arrow := func(c *widget.Clickable, txt string) layout.FlexChild {
+		return layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return c.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				gtx.Constraints.Min.X = gtx.Dp(p.CellSize)
+				return layout.UniformInset(8).Layout(gtx, p.label(txt, p.Color).Layout)
+			})
+		})
+	}
+	return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+		arrow(&p.Picker.Prev, "‹"),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min.X = gtx.Constraints.Max.X
+			title := fmt.Sprintf("%s %d", widget.MonthName(gtx.Locale, month.Month()), month.Year())
+			return p.label(title, p.Color).Layout(gtx)
+		}),
+		arrow(&p.Picker.Next, "›"),
+	)
+}
+
+func (p DatePickerStyle) layoutDay(gtx layout.Context, c widget.DateCell) layout.Dimensions {
+	size := image.Pt(gtx.Constraints.Max.X, gtx.Dp(p.CellSize))
+	if gtx.Constraints.Min.Y > 0 {
+		size.Y = gtx.Constraints.Min.Y
+	}
+	fg := p.Color
+	switch {
+	case c.Selected:
+		fg = p.SelectedTextColor
+		paint.FillShape(gtx.Ops, p.SelectedColor, clip.Ellipse{Max: size}.Op(gtx.Ops))
+	case c.InRange:
+		paint.FillShape(gtx.Ops, p.RangeColor, clip.Rect{Max: size}.Op())
+	case c.Hovered && !c.Disabled:
+		paint.FillShape(gtx.Ops, f32color.MulAlpha(p.SelectedColor, 0x20), clip.Ellipse{Max: size}.Op(gtx.Ops))
+	}
+	if c.Cursor || (c.Today && !c.Selected) {
+		w := float32(gtx.Dp(1))
+		if c.Cursor {
+			w *= 2
+		}
+		paint.FillShape(gtx.Ops, p.SelectedColor, clip.Stroke{
+			Path:  clip.Ellipse{Min: image.Pt(1, 1), Max: size.Sub(image.Pt(1, 1))}.Path(gtx.Ops),
+			Width: w,
+		}.Op())
+	}
+	if c.Outside {
+		fg = f32color.MulAlpha(fg, 0x80)
+	}
+	if c.Disabled {
+		fg = f32color.Disabled(fg)
+	}
+	gtx.Constraints = layout.Exact(size)
+	layout.Center.Layout(gtx, p.label(fmt.Sprint(c.Date.Day()), fg).Layout)
+	return layout.Dimensions{Size: size}
+}
+
+// Layout the fields of the time, separated by colons.
+func (p TimePickerStyle) Layout(gtx layout.Context) layout.Dimensions {
+	return p.Picker.Layout(gtx, func(gtx layout.Context, c widget.TimeCell) layout.Dimensions {
+		lbl := LabelStyle{
+			Color:    p.Color,
+			Font:     p.Font,
+			TextSize: p.TextSize,
+			MaxLines: 1,
+			Shaper:   p.shaper,
+		}
+		return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				switch c.Field {
+				case widget.TimeMinute:
+					lbl.Text = ":"
+				case widget.TimePeriod:
+					lbl.Text = " "
+				default:
+					return layout.Dimensions{}
+				}
+				return lbl.Layout(gtx)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				macro := op.Record(gtx.Ops)
+				lbl.Text = c.Text
+				dims := p.Inset.Layout(gtx, lbl.Layout)
+				call := macro.Stop()
+				if c.Focused {
+					paint.FillShape(gtx.Ops, p.FocusColor, clip.UniformRRect(image.Rectangle{Max: dims.Size}, gtx.Dp(2)).Op(gtx.Ops))
+				}
+				call.Add(gtx.Ops)
+				return dims
+			}),
+		)
+	})
+}
+
+// Layout the editor with a button for toggling the popup picker.
+func (f DateFieldStyle) Layout(gtx layout.Context) layout.Dimensions {
+	return f.Field.Layout(gtx,
+		func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+				layout.Flexed(1, f.Editor.Layout),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return f.Field.Button.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+						return layout.UniformInset(4).Layout(gtx, LabelStyle{
+							Text:     "▾",
+							Color:    f.Color,
+							Font:     f.Editor.Font,
+							TextSize: f.Editor.TextSize,
+							MaxLines: 1,
+							Shaper:   f.shaper,
+						}.Layout)
+					})
+				}),
+			)
+		},
+		f.Picker.Layout,
+	)
+}
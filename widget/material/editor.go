@@ -26,13 +26,25 @@ type EditorStyle struct {
 	TextSize        unit.Sp
 	// Color is the text color.
 	Color color.NRGBA
+	// Decoration is the set of lines drawn along the text.
+	Decoration text.Decoration
+	// LetterSpacing is added between the characters of the text.
+	LetterSpacing unit.Sp
+	// WordSpacing is added to the spaces between words.
+	WordSpacing unit.Sp
+	// TabWidth is the interval between tab stops. If zero, tabs are as
+	// wide as their glyph.
+	TabWidth unit.Sp
 	// Hint contains the text displayed when the editor is empty.
 	Hint string
 	// HintColor is the color of hint text.
 	HintColor color.NRGBA
 	// SelectionColor is the color of the background for selected text.
 	SelectionColor color.NRGBA
-	Editor         *widget.Editor
+	// InfoColor, WarningColor and ErrorColor are the colors of the
+	// underlines of diagnostics.
+	InfoColor, WarningColor, ErrorColor color.NRGBA
+	Editor                              *widget.Editor
 
 	shaper *text.Shaper
 }
@@ -49,6 +61,9 @@ func Editor(th *Theme, editor *widget.Editor, hint string) EditorStyle {
 		Hint:           hint,
 		HintColor:      f32color.MulAlpha(th.Palette.Fg, 0xbb),
 		SelectionColor: f32color.MulAlpha(th.Palette.ContrastBg, 0x60),
+		InfoColor:      th.Palette.ContrastBg,
+		WarningColor:   rgb(0xf9a825),
+		ErrorColor:     rgb(0xd32f2f),
 	}
 }
 
@@ -75,6 +90,9 @@ func (e EditorStyle) Layout(gtx layout.Context) layout.Dimensions {
 		MaxLines:        maxlines,
 		LineHeight:      e.LineHeight,
 		LineHeightScale: e.LineHeightScale,
+		LetterSpacing:   e.LetterSpacing,
+		WordSpacing:     e.WordSpacing,
+		TabWidth:        e.TabWidth,
 	}
 	dims := tl.Layout(gtx, e.shaper, e.Font, e.TextSize, e.Hint, hintColor)
 	call := macro.Stop()
@@ -87,10 +105,15 @@ func (e EditorStyle) Layout(gtx layout.Context) layout.Dimensions {
 	}
 	e.Editor.LineHeight = e.LineHeight
 	e.Editor.LineHeightScale = e.LineHeightScale
+	e.Editor.Decoration = e.Decoration
+	e.Editor.LetterSpacing = e.LetterSpacing
+	e.Editor.WordSpacing = e.WordSpacing
+	e.Editor.TabWidth = e.TabWidth
 	dims = e.Editor.Layout(gtx, e.shaper, e.Font, e.TextSize, textColor, selectionColor)
 	if e.Editor.Len() == 0 {
 		call.Add(gtx.Ops)
 	}
+	e.Editor.PaintDiagnostics(gtx, colorMaterial(gtx.Ops, e.InfoColor), colorMaterial(gtx.Ops, e.WarningColor), colorMaterial(gtx.Ops, e.ErrorColor))
 	return dims
 }
 
@@ -44,6 +44,15 @@ type LabelStyle struct {
 	// LineHeightScale applies a scaling factor to the LineHeight. If zero, a
 	// sensible default will be used.
 	LineHeightScale float32
+	// Decoration is the set of lines drawn along the text.
+	Decoration text.Decoration
+	// LetterSpacing is added between the characters of the text.
+	LetterSpacing unit.Sp
+	// WordSpacing is added to the spaces between words.
+	WordSpacing unit.Sp
+	// TabWidth is the interval between tab stops. If zero, tabs are as
+	// wide as their glyph.
+	TabWidth unit.Sp
 
 	// Shaper is the text shaper used to display this labe. This field is automatically
 	// set using by all constructor functions. If constructing a LabelStyle literal, you
@@ -140,6 +149,10 @@ func (l LabelStyle) Layout(gtx layout.Context) layout.Dimensions {
 		l.State.WrapPolicy = l.WrapPolicy
 		l.State.LineHeight = l.LineHeight
 		l.State.LineHeightScale = l.LineHeightScale
+		l.State.Decoration = l.Decoration
+		l.State.LetterSpacing = l.LetterSpacing
+		l.State.WordSpacing = l.WordSpacing
+		l.State.TabWidth = l.TabWidth
 		return l.State.Layout(gtx, l.Shaper, l.Font, l.TextSize, textColor, selectColor)
 	}
 	tl := widget.Label{
@@ -149,6 +162,10 @@ func (l LabelStyle) Layout(gtx layout.Context) layout.Dimensions {
 		WrapPolicy:      l.WrapPolicy,
 		LineHeight:      l.LineHeight,
 		LineHeightScale: l.LineHeightScale,
+		Decoration:      l.Decoration,
+		LetterSpacing:   l.LetterSpacing,
+		WordSpacing:     l.WordSpacing,
+		TabWidth:        l.TabWidth,
 	}
 	return tl.Layout(gtx, l.Shaper, l.Font, l.TextSize, l.Text, textColor)
 }
@@ -0,0 +1,245 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package material
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/font"
+	"gioui.org/internal/f32color"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+)
+
+// MenuStyle draws a widget.Menu as a material surface with a row for every
+// item.
+type MenuStyle struct {
+	Menu *widget.Menu
+	// Color is the text color.
+	Color color.NRGBA
+	// AcceleratorColor is the color of the accelerator descriptions.
+	AcceleratorColor color.NRGBA
+	// Background is the color of the menu surface.
+	Background color.NRGBA
+	// HighlightColor is the background color of the highlighted item.
+	HighlightColor color.NRGBA
+	// SeparatorColor is the color of separator lines.
+	SeparatorColor color.NRGBA
+	Font           font.Font
+	TextSize       unit.Sp
+	CornerRadius   unit.Dp
+	Inset          layout.Inset
+	// MaxWidth limits the width of the menu. Zero means the width of the
+	// constraints.
+	MaxWidth unit.Dp
+	shaper   *text.Shaper
+}
+
+// MenuBarStyle draws a widget.MenuBar and its open drop-down menu.
+type MenuBarStyle struct {
+	Bar *widget.MenuBar
+	// Color is the text color of the bar items.
+	Color color.NRGBA
+	// Background is the color of the bar.
+	Background color.NRGBA
+	// HighlightColor is the background color of the item whose menu is open.
+	HighlightColor color.NRGBA
+	Font           font.Font
+	TextSize       unit.Sp
+	Inset          layout.Inset
+	// Menu is the style of the drop-down menus. Its Menu field is ignored.
+	Menu   MenuStyle
+	shaper *text.Shaper
+}
+
+// Menu displays an open menu and its open submenus as an overlay on top of
+// other content.
+func Menu(th *Theme, menu *widget.Menu) MenuStyle {
+	m := MenuStyle{
+		Menu:             menu,
+		Color:            th.Palette.Fg,
+		AcceleratorColor: f32color.MulAlpha(th.Palette.Fg, 0x99),
+		Background:       th.Palette.Bg,
+		HighlightColor:   f32color.MulAlpha(th.Palette.ContrastBg, 0x30),
+		SeparatorColor:   f32color.MulAlpha(th.Palette.Fg, 0x30),
+		TextSize:         th.TextSize * 14.0 / 16.0,
+		CornerRadius:     4,
+		Inset: layout.Inset{
+			Top: 8, Bottom: 8,
+			Left: 16, Right: 16,
+		},
+		shaper: th.Shaper,
+	}
+	m.Font.Typeface = th.Face
+	return m
+}
+
+// MenuBar displays a row of items with drop-down menus.
+func MenuBar(th *Theme, bar *widget.MenuBar) MenuBarStyle {
+	b := MenuBarStyle{
+		Bar:            bar,
+		Color:          th.Palette.Fg,
+		Background:     th.Palette.Bg,
+		HighlightColor: f32color.MulAlpha(th.Palette.ContrastBg, 0x30),
+		TextSize:       th.TextSize * 14.0 / 16.0,
+		Inset: layout.Inset{
+			Top: 6, Bottom: 6,
+			Left: 10, Right: 10,
+		},
+		Menu:   Menu(th, nil),
+		shaper: th.Shaper,
+	}
+	b.Font.Typeface = th.Face
+	return b
+}
+
+// Layout the menu at its position. The menu is deferred to be drawn on top
+// of the content laid out after it, and takes up no space. Call Update on
+// the menu before Layout to receive the activated items.
+func (m MenuStyle) Layout(gtx layout.Context) layout.Dimensions {
+	m.Menu.Update(gtx)
+	if !m.Menu.Opened() {
+		return layout.Dimensions{}
+	}
+	macro := op.Record(gtx.Ops)
+	m.layoutMenu(gtx)
+	op.Defer(gtx.Ops, macro.Stop())
+	return layout.Dimensions{}
+}
+
+// layoutMenu lays out the menu and, recursively, its open submenus.
+func (m MenuStyle) layoutMenu(gtx layout.Context) {
+	gtx.Constraints.Min = image.Point{}
+	if m.MaxWidth != 0 {
+		gtx.Constraints.Max.X = min(gtx.Constraints.Max.X, gtx.Dp(m.MaxWidth))
+	}
+	off := op.Offset(m.Menu.Position()).Push(gtx.Ops)
+	macro := op.Record(gtx.Ops)
+	dims := m.Menu.Layout(gtx, m.layoutItem)
+	call := macro.Stop()
+	rr := gtx.Dp(m.CornerRadius)
+	cl := clip.UniformRRect(image.Rectangle{Max: dims.Size}, rr).Push(gtx.Ops)
+	paint.Fill(gtx.Ops, m.Background)
+	cl.Pop()
+	call.Add(gtx.Ops)
+	off.Pop()
+	for _, it := range m.Menu.Items {
+		if sub := it.Submenu; sub != nil && sub.Opened() {
+			m.Menu = sub
+			m.layoutMenu(gtx)
+		}
+	}
+}
+
+func (m MenuStyle) layoutItem(gtx layout.Context, index int) layout.Dimensions {
+	it := m.Menu.Items[index]
+	if it.Separator {
+		height := gtx.Dp(1)
+		pad := gtx.Dp(4)
+		size := image.Pt(gtx.Constraints.Min.X, height+2*pad)
+		defer op.Offset(image.Pt(0, pad)).Push(gtx.Ops).Pop()
+		paint.FillShape(gtx.Ops, m.SeparatorColor, clip.Rect{Max: image.Pt(size.X, height)}.Op())
+		return layout.Dimensions{Size: size}
+	}
+	fg, accel := m.Color, m.AcceleratorColor
+	if it.Disabled {
+		fg, accel = f32color.Disabled(fg), f32color.Disabled(accel)
+	}
+	macro := op.Record(gtx.Ops)
+	dims := m.Inset.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Spacing: layout.SpaceBetween, Alignment: layout.Middle}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						// Reserve the space of a check mark for every item to
+						// align the labels.
+						l := m.label("✓", fg)
+						gtx.Constraints.Min.X = 0
+						macro := op.Record(gtx.Ops)
+						dims := l.Layout(gtx)
+						call := macro.Stop()
+						if it.Checkable && it.Checked {
+							call.Add(gtx.Ops)
+						}
+						dims.Size.X += gtx.Dp(8)
+						return dims
+					}),
+					layout.Rigid(m.label(it.Label, fg).Layout),
+				)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				gtx.Constraints.Min.X = 0
+				return layout.Inset{Left: 24}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					switch {
+					case it.Submenu != nil:
+						return m.label("›", fg).Layout(gtx)
+					case it.Key != "":
+						return m.label(it.Accelerator(), accel).Layout(gtx)
+					}
+					return layout.Dimensions{}
+				})
+			}),
+		)
+	})
+	call := macro.Stop()
+	if index == m.Menu.Highlighted() && !it.Disabled {
+		paint.FillShape(gtx.Ops, m.HighlightColor, clip.Rect{Max: dims.Size}.Op())
+	}
+	call.Add(gtx.Ops)
+	return dims
+}
+
+func (m MenuStyle) label(txt string, col color.NRGBA) LabelStyle {
+	return LabelStyle{
+		Text:     txt,
+		Color:    col,
+		Font:     m.Font,
+		TextSize: m.TextSize,
+		MaxLines: 1,
+		Shaper:   m.shaper,
+	}
+}
+
+// Layout the menu bar and its open drop-down menu. Call Update on the menu
+// bar before Layout to receive the activated items.
+func (b MenuBarStyle) Layout(gtx layout.Context) layout.Dimensions {
+	b.Bar.Update(gtx)
+	macro := op.Record(gtx.Ops)
+	dims := b.Bar.Layout(gtx, func(gtx layout.Context, index int) layout.Dimensions {
+		it := b.Bar.Items[index]
+		fg := b.Color
+		if it.Disabled {
+			fg = f32color.Disabled(fg)
+		}
+		macro := op.Record(gtx.Ops)
+		dims := b.Inset.Layout(gtx, LabelStyle{
+			Text:     it.Label,
+			Color:    fg,
+			Font:     b.Font,
+			TextSize: b.TextSize,
+			MaxLines: 1,
+			Shaper:   b.shaper,
+		}.Layout)
+		call := macro.Stop()
+		if i, ok := b.Bar.Opened(); (ok && i == index) || (it.Hovered() && !it.Disabled) {
+			paint.FillShape(gtx.Ops, b.HighlightColor, clip.Rect{Max: dims.Size}.Op())
+		}
+		call.Add(gtx.Ops)
+		return dims
+	})
+	call := macro.Stop()
+	paint.FillShape(gtx.Ops, b.Background, clip.Rect{Max: dims.Size}.Op())
+	call.Add(gtx.Ops)
+	if i, ok := b.Bar.Opened(); ok {
+		m := b.Menu
+		m.Menu = b.Bar.Items[i].Submenu
+		m.Layout(gtx)
+	}
+	return dims
+}
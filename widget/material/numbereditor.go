@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package material
+
+import (
+	"gioui.org/layout"
+	"gioui.org/widget"
+)
+
+// NumberEditorStyle draws a widget.NumberEditor like an Editor.
+type NumberEditorStyle struct {
+	EditorStyle
+	NumberEditor *widget.NumberEditor
+}
+
+func NumberEditor(th *Theme, editor *widget.NumberEditor, hint string) NumberEditorStyle {
+	return NumberEditorStyle{
+		EditorStyle:  Editor(th, &editor.Editor, hint),
+		NumberEditor: editor,
+	}
+}
+
+func (n NumberEditorStyle) Layout(gtx layout.Context) layout.Dimensions {
+	// Step the value before the Editor processes the remaining events.
+	for {
+		_, ok := n.NumberEditor.Update(gtx)
+		if !ok {
+			break
+		}
+	}
+	return n.EditorStyle.Layout(gtx)
+}
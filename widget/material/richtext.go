@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package material
+
+import (
+	"image/color"
+
+	"gioui.org/font"
+	"gioui.org/internal/f32color"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+)
+
+// SpanStyle configures the presentation of a span of rich text.
+type SpanStyle struct {
+	// Content is the text of the span.
+	Content string
+	// Font defines the text style of the span.
+	Font font.Font
+	// Size is the size of the span text. If zero, the TextSize of the
+	// RichTextStyle is used.
+	Size unit.Sp
+	// Color is the text color.
+	Color color.NRGBA
+	// Background is the color behind the span. It is not drawn if
+	// transparent.
+	Background color.NRGBA
+	// Interactive marks clickable spans such as hyperlinks and mentions.
+	// Interactive spans are underlined when hovered. They require the
+	// State of the RichTextStyle to be set.
+	Interactive bool
+	// Payload is reported by the clicks of interactive spans.
+	Payload any
+	// Decoration is the set of lines drawn along the span text.
+	Decoration text.Decoration
+}
+
+// RichTextStyle configures the presentation of text made of differently
+// styled spans.
+type RichTextStyle struct {
+	Spans []SpanStyle
+	// TextSize is the size of spans without a Size.
+	TextSize unit.Sp
+	// Alignment specify the text alignment.
+	Alignment text.Alignment
+	// MaxLines limits the number of lines. Zero means no limit.
+	MaxLines int
+	// WrapPolicy configures how displayed text will be broken into lines.
+	WrapPolicy text.WrapPolicy
+	// Truncator is the text that will be shown at the end of the final
+	// line if MaxLines is exceeded. Defaults to "…" if empty.
+	Truncator string
+	// LineHeight controls the distance between the baselines of lines of text.
+	// If zero, a sensible default will be used.
+	LineHeight unit.Sp
+	// LineHeightScale applies a scaling factor to the LineHeight. If zero, a
+	// sensible default will be used.
+	LineHeightScale float32
+	Shaper          *text.Shaper
+	// State provides the interaction state of the interactive spans. If
+	// not set, the spans are not interactive.
+	State *widget.RichText
+}
+
+// Span returns a span with the text color and typeface of the theme.
+func Span(th *Theme, txt string) SpanStyle {
+	s := SpanStyle{
+		Content: txt,
+		Color:   th.Palette.Fg,
+	}
+	s.Font.Typeface = th.Face
+	return s
+}
+
+// Link returns an interactive span in the contrast color of the theme, for
+// hyperlinks and mentions.
+func Link(th *Theme, txt string, payload any) SpanStyle {
+	s := Span(th, txt)
+	s.Color = th.Palette.ContrastBg
+	s.Interactive = true
+	s.Payload = payload
+	return s
+}
+
+// RichText displays the spans in the text size of the theme.
+func RichText(th *Theme, spans ...SpanStyle) RichTextStyle {
+	return RichTextStyle{
+		Spans:    spans,
+		TextSize: th.TextSize,
+		Shaper:   th.Shaper,
+	}
+}
+
+func (r RichTextStyle) Layout(gtx layout.Context) layout.Dimensions {
+	spans := make([]widget.SpanStyle, len(r.Spans))
+	for i, s := range r.Spans {
+		spans[i] = widget.SpanStyle{
+			Content:     s.Content,
+			Font:        s.Font,
+			Size:        s.Size,
+			Material:    colorMaterial(gtx.Ops, s.Color),
+			Interactive: s.Interactive,
+			Payload:     s.Payload,
+			Decoration:  s.Decoration,
+		}
+		if s.Background.A != 0 {
+			spans[i].Background = colorMaterial(gtx.Ops, s.Background)
+		}
+		if s.Interactive && r.State != nil && r.State.Hovered(i) {
+			spans[i].Decoration |= text.Underline
+		}
+	}
+	if r.State == nil {
+		tl := widget.Label{
+			Alignment:       r.Alignment,
+			MaxLines:        r.MaxLines,
+			Truncator:       r.Truncator,
+			WrapPolicy:      r.WrapPolicy,
+			LineHeight:      r.LineHeight,
+			LineHeightScale: r.LineHeightScale,
+		}
+		dims, _ := tl.LayoutSpans(gtx, r.Shaper, r.TextSize, spans)
+		return dims
+	}
+	r.State.Alignment = r.Alignment
+	r.State.MaxLines = r.MaxLines
+	r.State.Truncator = r.Truncator
+	r.State.WrapPolicy = r.WrapPolicy
+	r.State.LineHeight = r.LineHeight
+	r.State.LineHeightScale = r.LineHeightScale
+	dims := r.State.Layout(gtx, r.Shaper, r.TextSize, spans)
+	for i, s := range r.Spans {
+		if !s.Interactive {
+			continue
+		}
+		if !r.State.Focused(gtx, i) {
+			continue
+		}
+		for _, reg := range r.State.Regions(i) {
+			paint.FillShape(gtx.Ops, f32color.MulAlpha(s.Color, 0x80), clip.Stroke{
+				Path:  clip.UniformRRect(reg.Bounds, gtx.Dp(2)).Path(gtx.Ops),
+				Width: float32(gtx.Dp(1)),
+			}.Op())
+		}
+	}
+	return dims
+}
+
+// colorMaterial records a paint material of color c.
+func colorMaterial(ops *op.Ops, c color.NRGBA) op.CallOp {
+	m := op.Record(ops)
+	paint.ColorOp{Color: c}.Add(ops)
+	return m.Stop()
+}
@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package material
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/internal/f32color"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+)
+
+type SplitStyle struct {
+	Split *widget.Split
+	// Color is the color of the divider line.
+	Color color.NRGBA
+	// ActiveColor is the color of the divider line while it is dragged
+	// or focused.
+	ActiveColor color.NRGBA
+	// Thickness is the thickness of the divider line.
+	Thickness unit.Dp
+}
+
+// Split displays two widgets separated by a resizable divider.
+func Split(th *Theme, split *widget.Split) SplitStyle {
+	return SplitStyle{
+		Split:       split,
+		Color:       f32color.MulAlpha(th.Palette.Fg, 0x30),
+		ActiveColor: th.Palette.ContrastBg,
+		Thickness:   1,
+	}
+}
+
+func (s SplitStyle) Layout(gtx layout.Context, first, second layout.Widget) layout.Dimensions {
+	return s.Split.Layout(gtx, first, second, func(gtx layout.Context) layout.Dimensions {
+		size := gtx.Constraints.Min
+		col := s.Color
+		thickness := gtx.Dp(s.Thickness)
+		if s.Split.Dragging() || s.Split.Focused() {
+			col = s.ActiveColor
+			thickness *= 2
+		}
+		axis := s.Split.Axis
+		bar := axis.Convert(size)
+		lo := (bar.X - thickness) / 2
+		line := image.Rectangle{
+			Min: axis.Convert(image.Pt(lo, 0)),
+			Max: axis.Convert(image.Pt(lo+thickness, bar.Y)),
+		}
+		paint.FillShape(gtx.Ops, col, clip.Rect(line).Op())
+		return layout.Dimensions{Size: size}
+	})
+}
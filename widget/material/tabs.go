@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package material
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/font"
+	"gioui.org/internal/f32color"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+)
+
+type TabsStyle struct {
+	Tabs *widget.Tabs
+	// Color is the text color of unselected tabs.
+	Color color.NRGBA
+	// SelectedColor is the text color of the selected tab.
+	SelectedColor color.NRGBA
+	// IndicatorColor is the color of the selection indicator.
+	IndicatorColor color.NRGBA
+	// IndicatorHeight is the thickness of the selection indicator.
+	IndicatorHeight unit.Dp
+	Font            font.Font
+	TextSize        unit.Sp
+	Inset           layout.Inset
+	shaper          *text.Shaper
+}
+
+// Tabs displays a strip of tabs with an animated selection indicator.
+func Tabs(th *Theme, tabs *widget.Tabs) TabsStyle {
+	t := TabsStyle{
+		Tabs:            tabs,
+		Color:           f32color.MulAlpha(th.Palette.Fg, 0xaa),
+		SelectedColor:   th.Palette.ContrastBg,
+		IndicatorColor:  th.Palette.ContrastBg,
+		IndicatorHeight: 2,
+		TextSize:        th.TextSize * 14.0 / 16.0,
+		Inset: layout.Inset{
+			Top: 12, Bottom: 12,
+			Left: 16, Right: 16,
+		},
+		shaper: th.Shaper,
+	}
+	t.Font.Typeface = th.Face
+	t.Font.Weight = font.Medium
+	return t
+}
+
+// Layout the tabs with the given titles.
+func (t TabsStyle) Layout(gtx layout.Context, titles ...string) layout.Dimensions {
+	t.Tabs.Update(gtx)
+	gtx.Constraints.Min.X = 0
+	dims := t.Tabs.Layout(gtx, len(titles), func(gtx layout.Context, index int) layout.Dimensions {
+		fg := t.Color
+		if index == t.Tabs.Selected {
+			fg = t.SelectedColor
+		}
+		if !gtx.Enabled() {
+			fg = f32color.Disabled(fg)
+		}
+		macro := op.Record(gtx.Ops)
+		dims := t.Inset.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+				layout.Rigid(LabelStyle{
+					Text:     titles[index],
+					Color:    fg,
+					Font:     t.Font,
+					TextSize: t.TextSize,
+					MaxLines: 1,
+					Shaper:   t.shaper,
+				}.Layout),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					if !t.Tabs.Closable {
+						return layout.Dimensions{}
+					}
+					return layout.Inset{Left: 8}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+						return t.Tabs.CloseButton(index).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+							return LabelStyle{
+								Text:     "×",
+								Color:    fg,
+								Font:     t.Font,
+								TextSize: t.TextSize,
+								MaxLines: 1,
+								Shaper:   t.shaper,
+							}.Layout(gtx)
+						})
+					})
+				}),
+			)
+		})
+		call := macro.Stop()
+		if (t.Tabs.Hovered(index) || (t.Tabs.Focused() && index == t.Tabs.Selected)) && gtx.Enabled() {
+			paint.FillShape(gtx.Ops, f32color.MulAlpha(t.IndicatorColor, 0x20), clip.Rect{Max: dims.Size}.Op())
+		}
+		call.Add(gtx.Ops)
+		return dims
+	})
+	if r, ok := t.Tabs.Indicator(gtx); ok {
+		h := gtx.Dp(t.IndicatorHeight)
+		r.Min.Y, r.Max.Y = dims.Size.Y-h, dims.Size.Y
+		r = r.Intersect(image.Rectangle{Max: dims.Size})
+		paint.FillShape(gtx.Ops, t.IndicatorColor, clip.Rect(r).Op())
+	}
+	return dims
+}
@@ -0,0 +1,650 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+
+	"gioui.org/gesture"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/io/semantic"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+)
+
+// MenuItem is an entry of a Menu.
+type MenuItem struct {
+	// Label is the text of the item.
+	Label string
+	// Key and Modifiers describe the keyboard accelerator that activates the
+	// item even when its menu is closed. An empty Key means no accelerator.
+	Key       key.Name
+	Modifiers key.Modifiers
+	// Separator marks the item as a visual separator between groups of
+	// items. Separators can't be highlighted or activated.
+	Separator bool
+	// Checkable items toggle Checked when activated.
+	Checkable bool
+	Checked   bool
+	// Disabled items are displayed, but can't be activated.
+	Disabled bool
+	// Submenu is opened next to the item when the item is activated
+	// or hovered.
+	Submenu *Menu
+
+	click  gesture.Click
+	bounds image.Rectangle
+}
+
+// Menu holds the state of a list of items displayed as an in-window
+// overlay, such as a context menu or a drop-down menu of a MenuBar.
+//
+// A Menu is opened by Open and closed when an item is activated, when
+// Escape is pressed, or when the pointer is pressed outside the menu.
+type Menu struct {
+	Items []*MenuItem
+
+	open bool
+	pos  image.Point
+	// highlight is the index of the highlighted item, or -1.
+	highlight int
+	// requestFocus is set when the menu should acquire the keyboard
+	// focus.
+	requestFocus bool
+	// parent is the menu that opened m as a submenu.
+	parent *Menu
+	// bar is the menu bar that opened m as a drop-down menu.
+	bar *MenuBar
+	// hovered is the index of the item under the pointer, or -1.
+	hovered int
+	// scrim is the tag of the area outside the menu.
+	scrim struct{}
+}
+
+// Accelerator returns a textual description of the item's keyboard
+// accelerator, or the empty string if the item has none.
+func (it *MenuItem) Accelerator() string {
+	if it.Key == "" {
+		return ""
+	}
+	if it.Modifiers == 0 {
+		return string(it.Key)
+	}
+	return it.Modifiers.String() + "-" + string(it.Key)
+}
+
+// Hovered reports whether a pointer is over the item.
+func (it *MenuItem) Hovered() bool {
+	return it.click.Hovered()
+}
+
+func (it *MenuItem) activatable() bool {
+	return !it.Separator && !it.Disabled
+}
+
+// Open the menu with its upper left corner at pos. The position is relative to
+// the coordinate system of the subsequent call to Layout. Any submenus are closed.
+func (m *Menu) Open(pos image.Point) {
+	m.closeSubmenus()
+	m.open = true
+	m.pos = pos
+	m.highlight = -1
+	m.hovered = -1
+	m.requestFocus = true
+}
+
+// Close the menu and its submenus.
+func (m *Menu) Close() {
+	m.closeSubmenus()
+	m.open = false
+}
+
+func (m *Menu) closeSubmenus() {
+	for _, it := range m.Items {
+		if sub := it.Submenu; sub != nil && sub.open {
+			sub.Close()
+		}
+	}
+}
+
+// closeAll closes the menu hierarchy m is part of.
+func (m *Menu) closeAll() {
+	for m.parent != nil {
+		m = m.parent
+	}
+	m.Close()
+}
+
+// Opened reports whether the menu is open.
+func (m *Menu) Opened() bool {
+	return m.open
+}
+
+// Position returns the position of the upper left corner of the menu, as
+// specified by Open.
+func (m *Menu) Position() image.Point {
+	return m.pos
+}
+
+// Highlighted returns the index of the highlighted item, or -1 if no item is
+// highlighted.
+func (m *Menu) Highlighted() int {
+	if !m.open {
+		return -1
+	}
+	return m.highlight
+}
+
+// Update the menu state and return the item activated by the user, if any.
+// Update processes the events of open submenus as well as the keyboard
+// accelerators of every item, whether the menu is open or not.
+func (m *Menu) Update(gtx layout.Context) (*MenuItem, bool) {
+	if m.parent == nil {
+		if it, ok := m.updateAccelerators(gtx); ok {
+			return it, true
+		}
+	}
+	if !m.open {
+		return nil, false
+	}
+	if m.requestFocus {
+		gtx.Execute(key.FocusCmd{Tag: m})
+		// The focus can't move to a menu not yet laid out; try again
+		// in the next frame.
+		m.requestFocus = !gtx.Focused(m)
+	}
+	if m.parent == nil {
+		for {
+			ev, ok := gtx.Event(pointer.Filter{Target: &m.scrim, Kinds: pointer.Press})
+			if !ok {
+				break
+			}
+			if _, ok := ev.(pointer.Event); ok {
+				m.Close()
+				return nil, false
+			}
+		}
+	}
+	for i, it := range m.Items {
+		for {
+			e, ok := it.click.Update(gtx.Source)
+			if !ok {
+				break
+			}
+			if e.Kind != gesture.KindClick {
+				continue
+			}
+			if it, ok := m.activate(gtx, i); ok {
+				return it, true
+			}
+		}
+		switch hovered := it.click.Hovered(); {
+		case hovered && m.hovered != i:
+			m.hovered = i
+			if !it.activatable() || m.highlight == i {
+				break
+			}
+			m.highlight = i
+			m.closeSubmenus()
+			if it.Submenu != nil {
+				m.openSubmenu(i, false)
+			}
+		case !hovered && m.hovered == i:
+			m.hovered = -1
+		}
+	}
+	for {
+		ev, ok := gtx.Event(
+			key.FocusFilter{Target: m},
+			key.Filter{Focus: m, Name: key.NameUpArrow},
+			key.Filter{Focus: m, Name: key.NameDownArrow},
+			key.Filter{Focus: m, Name: key.NameLeftArrow},
+			key.Filter{Focus: m, Name: key.NameRightArrow},
+			key.Filter{Focus: m, Name: key.NameHome},
+			key.Filter{Focus: m, Name: key.NameEnd},
+			key.Filter{Focus: m, Name: key.NameReturn},
+			key.Filter{Focus: m, Name: key.NameEnter},
+			key.Filter{Focus: m, Name: key.NameSpace},
+			key.Filter{Focus: m, Name: key.NameEscape},
+		)
+		if !ok {
+			break
+		}
+		e, ok := ev.(key.Event)
+		if !ok || e.State != key.Press {
+			continue
+		}
+		switch e.Name {
+		case key.NameUpArrow:
+			m.moveHighlight(-1)
+		case key.NameDownArrow:
+			m.moveHighlight(+1)
+		case key.NameHome:
+			m.highlight = -1
+			m.moveHighlight(+1)
+		case key.NameEnd:
+			m.highlight = len(m.Items)
+			m.moveHighlight(-1)
+		case key.NameRightArrow:
+			if h := m.highlight; h >= 0 && m.Items[h].Submenu != nil && m.Items[h].activatable() {
+				m.openSubmenu(h, true)
+			} else if m.bar != nil {
+				m.bar.step(gtx, +1)
+			}
+		case key.NameLeftArrow:
+			if p := m.parent; p != nil {
+				m.Close()
+				gtx.Execute(key.FocusCmd{Tag: p})
+			} else if m.bar != nil {
+				m.bar.step(gtx, -1)
+			}
+		case key.NameReturn, key.NameEnter, key.NameSpace:
+			if h := m.highlight; h >= 0 {
+				if it, ok := m.activate(gtx, h); ok {
+					return it, true
+				}
+			}
+		case key.NameEscape:
+			m.Close()
+			if p := m.parent; p != nil {
+				gtx.Execute(key.FocusCmd{Tag: p})
+			} else if m.bar != nil {
+				m.bar.focus(gtx, m)
+			}
+		}
+	}
+	for _, it := range m.Items {
+		if sub := it.Submenu; sub != nil && sub.open {
+			if it, ok := sub.Update(gtx); ok {
+				return it, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// updateAccelerators processes the keyboard accelerators of every item
+// in the menu hierarchy.
+func (m *Menu) updateAccelerators(gtx layout.Context) (*MenuItem, bool) {
+	var filters []event.Filter
+	var items []*MenuItem
+	var collect func(m *Menu)
+	collect = func(m *Menu) {
+		for _, it := range m.Items {
+			if it.Submenu != nil {
+				collect(it.Submenu)
+				continue
+			}
+			if it.Key == "" || !it.activatable() {
+				continue
+			}
+			filters = append(filters, key.Filter{Name: it.Key, Required: it.Modifiers})
+			items = append(items, it)
+		}
+	}
+	collect(m)
+	if len(filters) == 0 {
+		return nil, false
+	}
+	for {
+		ev, ok := gtx.Event(filters...)
+		if !ok {
+			break
+		}
+		e, ok := ev.(key.Event)
+		if !ok || e.State != key.Press {
+			continue
+		}
+		for _, it := range items {
+			if it.Key == e.Name && it.Modifiers == e.Modifiers {
+				if it.Checkable {
+					it.Checked = !it.Checked
+				}
+				m.Close()
+				return it, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// activate the item at index i and report the item if it is a leaf.
+func (m *Menu) activate(gtx layout.Context, i int) (*MenuItem, bool) {
+	it := m.Items[i]
+	if !it.activatable() {
+		return nil, false
+	}
+	m.highlight = i
+	if it.Submenu != nil {
+		m.openSubmenu(i, true)
+		return nil, false
+	}
+	if it.Checkable {
+		it.Checked = !it.Checked
+	}
+	m.closeAll()
+	return it, true
+}
+
+// openSubmenu opens the submenu of the item at index i next to the item.
+func (m *Menu) openSubmenu(i int, focus bool) {
+	it := m.Items[i]
+	sub := it.Submenu
+	sub.parent = m
+	if !sub.open {
+		sub.Open(m.pos.Add(image.Pt(it.bounds.Max.X, it.bounds.Min.Y)))
+	}
+	sub.requestFocus = focus
+	if focus && sub.highlight == -1 {
+		sub.moveHighlight(+1)
+	}
+}
+
+// moveHighlight moves the highlight to the next activatable item in
+// direction dir, wrapping around the ends of the menu.
+func (m *Menu) moveHighlight(dir int) {
+	n := len(m.Items)
+	if n == 0 {
+		return
+	}
+	h := m.highlight
+	for range n {
+		h += dir
+		switch {
+		case h < 0:
+			h = n - 1
+		case h >= n:
+			h = 0
+		}
+		if m.Items[h].activatable() {
+			m.highlight = h
+			return
+		}
+	}
+}
+
+// Layout the items of the menu in a vertical list, if the menu is open.
+// Every item is laid out with the same width, the width of the widest
+// item. The caller is responsible for positioning the menu at Position.
+// Layout calls Update and discards the activated item, so Update must be
+// called before Layout for activations to be reported.
+func (m *Menu) Layout(gtx layout.Context, item func(gtx layout.Context, index int) layout.Dimensions) layout.Dimensions {
+	m.Update(gtx)
+	if !m.open {
+		return layout.Dimensions{}
+	}
+	if m.parent == nil {
+		// Cover the window to detect presses outside the menu.
+		const inf = 1e6
+		scrim := clip.Rect{Min: image.Pt(-inf, -inf), Max: image.Pt(inf, inf)}.Push(gtx.Ops)
+		if m.bar != nil {
+			// Let the menu bar observe the pointer while its menus are open.
+			pass := pointer.PassOp{}.Push(gtx.Ops)
+			event.Op(gtx.Ops, &m.scrim)
+			pass.Pop()
+		} else {
+			event.Op(gtx.Ops, &m.scrim)
+		}
+		scrim.Pop()
+	}
+	gtx.Constraints.Min = image.Point{}
+	// Measure the items to determine the menu width.
+	width := 0
+	for i := range m.Items {
+		rec := op.Record(gtx.Ops)
+		dims := item(gtx, i)
+		rec.Stop()
+		width = max(width, dims.Size.X)
+	}
+	gtx.Constraints.Min.X = width
+	gtx.Constraints.Max.X = max(gtx.Constraints.Max.X, width)
+	items := op.Record(gtx.Ops)
+	y := 0
+	for i, it := range m.Items {
+		rec := op.Record(gtx.Ops)
+		dims := item(gtx, i)
+		call := rec.Stop()
+		it.bounds = image.Rectangle{
+			Min: image.Pt(0, y),
+			Max: image.Pt(width, y+dims.Size.Y),
+		}
+		off := op.Offset(it.bounds.Min).Push(gtx.Ops)
+		cl := clip.Rect{Max: it.bounds.Size()}.Push(gtx.Ops)
+		if it.activatable() {
+			it.click.Add(gtx.Ops)
+		}
+		semantic.EnabledOp(!it.Disabled).Add(gtx.Ops)
+		if it.Checkable {
+			semantic.SelectedOp(it.Checked).Add(gtx.Ops)
+		}
+		if it.Label != "" {
+			semantic.LabelOp(it.Label).Add(gtx.Ops)
+		}
+		call.Add(gtx.Ops)
+		cl.Pop()
+		off.Pop()
+		y += dims.Size.Y
+	}
+	call := items.Stop()
+	size := image.Pt(width, y)
+	defer clip.Rect{Max: size}.Push(gtx.Ops).Pop()
+	event.Op(gtx.Ops, m)
+	call.Add(gtx.Ops)
+	return layout.Dimensions{Size: size}
+}
+
+// ContextArea detects requests for a context menu, in the form of
+// secondary button presses.
+type ContextArea struct {
+	// pos is the position of the most recent request.
+	pos image.Point
+}
+
+// Update the area state and report the position of the most recent context
+// menu request, if any.
+func (c *ContextArea) Update(gtx layout.Context) (image.Point, bool) {
+	requested := false
+	for {
+		ev, ok := gtx.Event(pointer.Filter{Target: c, Kinds: pointer.Press})
+		if !ok {
+			break
+		}
+		e, ok := ev.(pointer.Event)
+		if !ok {
+			continue
+		}
+		if e.Buttons == pointer.ButtonSecondary {
+			c.pos = e.Position.Round()
+			requested = true
+		}
+	}
+	return c.pos, requested
+}
+
+// Layout the area around w. Update must be called before Layout for
+// requests to be detected.
+func (c *ContextArea) Layout(gtx layout.Context, w layout.Widget) layout.Dimensions {
+	dims := w(gtx)
+	defer clip.Rect{Max: dims.Size}.Push(gtx.Ops).Pop()
+	// Let the content receive pointer events as well.
+	defer pointer.PassOp{}.Push(gtx.Ops).Pop()
+	event.Op(gtx.Ops, c)
+	return dims
+}
+
+// MenuBar is a horizontal row of items whose submenus drop down when
+// the item is activated, as commonly found at the top of desktop windows.
+type MenuBar struct {
+	// Items of the menu bar. Every item is expected to have a Submenu.
+	Items []*MenuItem
+
+	tags []menuBarTag
+}
+
+type menuBarTag struct {
+	bar *MenuBar
+	// hovered tracks whether the pointer is over the item.
+	hovered bool
+}
+
+// Update the menu bar and its menus and return the item activated by
+// the user, if any.
+func (b *MenuBar) Update(gtx layout.Context) (*MenuItem, bool) {
+	b.sync()
+	wasOpen := b.openMenu()
+	for i, it := range b.Items {
+		if it.Submenu == nil {
+			continue
+		}
+		if it, ok := it.Submenu.Update(gtx); ok {
+			return it, true
+		}
+		for {
+			e, ok := it.click.Update(gtx.Source)
+			if !ok {
+				break
+			}
+			if e.Kind != gesture.KindPress || !it.activatable() {
+				continue
+			}
+			if wasOpen == i {
+				it.Submenu.Close()
+			} else {
+				b.openAt(i, e.Source != pointer.Mouse)
+			}
+		}
+		tag := &b.tags[i]
+		hovered := it.click.Hovered()
+		if o := b.openMenu(); hovered && !tag.hovered && o != -1 && o != i && it.activatable() {
+			// Switch menus when the pointer moves across the bar.
+			b.openAt(i, false)
+		}
+		tag.hovered = hovered
+		for {
+			ev, ok := gtx.Event(
+				key.FocusFilter{Target: tag},
+				key.Filter{Focus: tag, Name: key.NameReturn},
+				key.Filter{Focus: tag, Name: key.NameEnter},
+				key.Filter{Focus: tag, Name: key.NameSpace},
+				key.Filter{Focus: tag, Name: key.NameDownArrow},
+			)
+			if !ok {
+				break
+			}
+			if e, ok := ev.(key.Event); ok && e.State == key.Press && it.activatable() {
+				b.openAt(i, true)
+			}
+		}
+	}
+	return nil, false
+}
+
+// sync initializes the per-item state of the bar.
+func (b *MenuBar) sync() {
+	if len(b.tags) != len(b.Items) {
+		b.tags = make([]menuBarTag, len(b.Items))
+		for i := range b.tags {
+			b.tags[i].bar = b
+		}
+	}
+	for _, it := range b.Items {
+		if it.Submenu != nil {
+			it.Submenu.bar = b
+		}
+	}
+}
+
+// openMenu returns the index of the open drop-down menu, or -1.
+func (b *MenuBar) openMenu() int {
+	for i, it := range b.Items {
+		if it.Submenu != nil && it.Submenu.open {
+			return i
+		}
+	}
+	return -1
+}
+
+// openAt opens the drop-down menu of the item at index i below the item.
+func (b *MenuBar) openAt(i int, focus bool) {
+	for j, it := range b.Items {
+		if j != i && it.Submenu != nil {
+			it.Submenu.Close()
+		}
+	}
+	it := b.Items[i]
+	it.Submenu.Open(image.Pt(it.bounds.Min.X, it.bounds.Max.Y))
+	if focus {
+		it.Submenu.moveHighlight(+1)
+	}
+}
+
+// focus moves the keyboard focus to the bar item of the drop-down menu m.
+func (b *MenuBar) focus(gtx layout.Context, m *Menu) {
+	for i, it := range b.Items {
+		if it.Submenu == m {
+			gtx.Execute(key.FocusCmd{Tag: &b.tags[i]})
+		}
+	}
+}
+
+// step opens the drop-down menu dir items away from the open menu.
+func (b *MenuBar) step(gtx layout.Context, dir int) {
+	n := len(b.Items)
+	i := b.openMenu()
+	if i == -1 {
+		return
+	}
+	for range n {
+		i = (i + dir + n) % n
+		if it := b.Items[i]; it.Submenu != nil && it.activatable() {
+			b.openAt(i, true)
+			return
+		}
+	}
+}
+
+// Opened returns the index of the item whose menu is open, if any.
+func (b *MenuBar) Opened() (int, bool) {
+	i := b.openMenu()
+	return i, i != -1
+}
+
+// Layout the items of the menu bar in a horizontal row. The drop-down
+// menus are positioned relative to the bar's coordinate system.
+// Layout calls Update and discards the activated item, so Update must be
+// called before Layout for activations to be reported.
+func (b *MenuBar) Layout(gtx layout.Context, item func(gtx layout.Context, index int) layout.Dimensions) layout.Dimensions {
+	b.Update(gtx)
+	gtx.Constraints.Min.X = 0
+	x, height := 0, 0
+	calls := make([]op.CallOp, len(b.Items))
+	for i, it := range b.Items {
+		rec := op.Record(gtx.Ops)
+		dims := item(gtx, i)
+		calls[i] = rec.Stop()
+		it.bounds = image.Rectangle{
+			Min: image.Pt(x, 0),
+			Max: image.Pt(x+dims.Size.X, dims.Size.Y),
+		}
+		x += dims.Size.X
+		height = max(height, dims.Size.Y)
+	}
+	for i, it := range b.Items {
+		it.bounds.Max.Y = height
+		off := op.Offset(it.bounds.Min).Push(gtx.Ops)
+		cl := clip.Rect{Max: it.bounds.Size()}.Push(gtx.Ops)
+		if it.activatable() {
+			it.click.Add(gtx.Ops)
+		}
+		event.Op(gtx.Ops, &b.tags[i])
+		semantic.EnabledOp(!it.Disabled).Add(gtx.Ops)
+		semantic.LabelOp(it.Label).Add(gtx.Ops)
+		calls[i].Add(gtx.Ops)
+		cl.Pop()
+		off.Pop()
+	}
+	return layout.Dimensions{Size: gtx.Constraints.Constrain(image.Pt(x, height))}
+}
@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget_test
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/widget"
+)
+
+func layoutMenuItem(gtx layout.Context, index int) layout.Dimensions {
+	return layout.Dimensions{Size: image.Pt(100, 20)}
+}
+
+// layoutMenu lays out m and its open submenus at their positions.
+func layoutMenu(gtx layout.Context, m *widget.Menu) {
+	off := op.Offset(m.Position()).Push(gtx.Ops)
+	m.Layout(gtx, layoutMenuItem)
+	off.Pop()
+	for _, it := range m.Items {
+		if sub := it.Submenu; sub != nil && sub.Opened() {
+			layoutMenu(gtx, sub)
+		}
+	}
+}
+
+func TestMenuAccelerator(t *testing.T) {
+	var r input.Router
+	save := &widget.MenuItem{Label: "Save", Key: "S", Modifiers: key.ModShortcut}
+	wrap := &widget.MenuItem{Label: "Wrap", Key: "W", Modifiers: key.ModCtrl, Checkable: true}
+	m := &widget.Menu{Items: []*widget.MenuItem{
+		{Label: "Edit", Submenu: &widget.Menu{Items: []*widget.MenuItem{wrap}}},
+		save,
+	}}
+	gtx := layout.Context{Ops: new(op.Ops), Source: r.Source()}
+	m.Layout(gtx, layoutMenuItem)
+	r.Frame(gtx.Ops)
+	r.Queue(key.Event{Name: "S", Modifiers: key.ModShortcut, State: key.Press})
+	if it, ok := m.Update(gtx); !ok || it != save {
+		t.Errorf("accelerator activated %v, %v; want %v", it, ok, save)
+	}
+	r.Queue(key.Event{Name: "W", Modifiers: key.ModCtrl, State: key.Press})
+	if it, ok := m.Update(gtx); !ok || it != wrap {
+		t.Errorf("submenu accelerator activated %v, %v; want %v", it, ok, wrap)
+	}
+	if !wrap.Checked {
+		t.Error("accelerator didn't toggle checkable item")
+	}
+	if m.Opened() {
+		t.Error("accelerators opened the menu")
+	}
+}
+
+func TestMenuKeyboard(t *testing.T) {
+	var r input.Router
+	sub := &widget.MenuItem{Label: "Recent"}
+	m := &widget.Menu{Items: []*widget.MenuItem{
+		{Label: "Disabled", Disabled: true},
+		{Label: "Open"},
+		{Separator: true},
+		{Label: "More", Submenu: &widget.Menu{Items: []*widget.MenuItem{sub}}},
+	}}
+	gtx := layout.Context{Ops: new(op.Ops), Source: r.Source()}
+	frame := func() {
+		gtx.Reset()
+		layoutMenu(gtx, m)
+		r.Frame(gtx.Ops)
+	}
+	m.Open(image.Pt(10, 10))
+	frame()
+	frame()
+	if !gtx.Focused(m) {
+		t.Fatal("menu didn't acquire focus")
+	}
+	press := func(n key.Name) {
+		r.Queue(key.Event{Name: n, State: key.Press})
+	}
+	press(key.NameDownArrow)
+	frame()
+	if h := m.Highlighted(); h != 1 {
+		t.Errorf("highlighted %d after down, want 1", h)
+	}
+	press(key.NameDownArrow)
+	frame()
+	if h := m.Highlighted(); h != 3 {
+		t.Errorf("highlighted %d after down, want 3", h)
+	}
+	press(key.NameDownArrow)
+	frame()
+	if h := m.Highlighted(); h != 1 {
+		t.Errorf("highlighted %d after wrapping down, want 1", h)
+	}
+	press(key.NameEnd)
+	press(key.NameRightArrow)
+	frame()
+	frame()
+	subMenu := m.Items[3].Submenu
+	if !subMenu.Opened() {
+		t.Fatal("right arrow didn't open submenu")
+	}
+	if !gtx.Focused(subMenu) {
+		t.Error("submenu didn't acquire focus")
+	}
+	press(key.NameReturn)
+	if it, ok := m.Update(gtx); !ok || it != sub {
+		t.Errorf("return activated %v, %v; want %v", it, ok, sub)
+	}
+	if m.Opened() || subMenu.Opened() {
+		t.Error("activation didn't close the menus")
+	}
+}
+
+func TestMenuPointer(t *testing.T) {
+	var (
+		r    input.Router
+		area widget.ContextArea
+	)
+	m := &widget.Menu{Items: []*widget.MenuItem{
+		{Label: "Cut"},
+		{Label: "Copy"},
+	}}
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Source:      r.Source(),
+		Constraints: layout.Exact(image.Pt(400, 400)),
+	}
+	frame := func() {
+		gtx.Reset()
+		area.Update(gtx)
+		area.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return layout.Dimensions{Size: gtx.Constraints.Max}
+		})
+		layoutMenu(gtx, m)
+		r.Frame(gtx.Ops)
+	}
+	frame()
+	r.Queue(pointer.Event{
+		Kind:     pointer.Press,
+		Source:   pointer.Mouse,
+		Buttons:  pointer.ButtonSecondary,
+		Position: f32.Pt(50, 60),
+	})
+	pos, ok := area.Update(gtx)
+	if !ok || pos != image.Pt(50, 60) {
+		t.Fatalf("context area reported %v, %v; want (50,60), true", pos, ok)
+	}
+	r.Queue(pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: f32.Pt(50, 60)})
+	m.Open(pos)
+	frame()
+	r.Queue(pointer.Event{Kind: pointer.Move, Source: pointer.Mouse, Position: f32.Pt(60, 90)})
+	frame()
+	if h := m.Highlighted(); h != 1 {
+		t.Errorf("highlighted %d after hover, want 1", h)
+	}
+	r.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Pt(60, 90)},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: f32.Pt(60, 90)},
+	)
+	if it, ok := m.Update(gtx); !ok || it != m.Items[1] {
+		t.Errorf("click activated %v, %v; want %v", it, ok, m.Items[1])
+	}
+	m.Open(pos)
+	frame()
+	r.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Pt(300, 300)},
+	)
+	frame()
+	if m.Opened() {
+		t.Error("press outside the menu didn't close it")
+	}
+}
+
+func TestMenuBar(t *testing.T) {
+	var r input.Router
+	b := &widget.MenuBar{Items: []*widget.MenuItem{
+		{Label: "File", Submenu: &widget.Menu{Items: []*widget.MenuItem{{Label: "Open"}}}},
+		{Label: "Edit", Submenu: &widget.Menu{Items: []*widget.MenuItem{{Label: "Copy"}}}},
+	}}
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Source:      r.Source(),
+		Constraints: layout.Exact(image.Pt(400, 400)),
+	}
+	frame := func() {
+		gtx.Reset()
+		b.Layout(gtx, func(gtx layout.Context, index int) layout.Dimensions {
+			return layout.Dimensions{Size: image.Pt(50, 20)}
+		})
+		for _, it := range b.Items {
+			if it.Submenu.Opened() {
+				layoutMenu(gtx, it.Submenu)
+			}
+		}
+		r.Frame(gtx.Ops)
+	}
+	frame()
+	r.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Pt(10, 10)},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: f32.Pt(10, 10)},
+	)
+	frame()
+	if i, ok := b.Opened(); !ok || i != 0 {
+		t.Fatalf("opened menu %d, %v; want 0", i, ok)
+	}
+	if got, want := b.Items[0].Submenu.Position(), image.Pt(0, 20); got != want {
+		t.Errorf("menu position %v, want %v", got, want)
+	}
+	frame()
+	r.Queue(key.Event{Name: key.NameRightArrow, State: key.Press})
+	frame()
+	if i, ok := b.Opened(); !ok || i != 1 {
+		t.Errorf("opened menu %d, %v after right arrow; want 1", i, ok)
+	}
+	frame()
+	r.Queue(key.Event{Name: key.NameEscape, State: key.Press})
+	frame()
+	if _, ok := b.Opened(); ok {
+		t.Error("escape didn't close the menu")
+	}
+}
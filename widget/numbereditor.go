@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"gioui.org/font"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+)
+
+// NumberEditor is a single line Editor for numbers. The up and down keys
+// step the value, ten steps at a time with the shift key.
+type NumberEditor struct {
+	Editor
+	// Min and Max limit the value, if Min is less than Max.
+	Min, Max float64
+	// Step is the amount added or subtracted by the up and down keys. If
+	// zero, 1 is used.
+	Step float64
+	// Format formats the text while it is edited, and replaces the
+	// Formatter of the Editor.
+	Format NumberFormat
+}
+
+// Value returns the value of the text, limited to Min and Max. It reports
+// false if the text is not a number.
+func (n *NumberEditor) Value() (float64, bool) {
+	s := n.Text()
+	if g := n.Format.Group; g != 0 {
+		s = strings.ReplaceAll(s, string(g), "")
+	}
+	s = strings.ReplaceAll(s, string(n.Format.decimal()), ".")
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n.clamp(v), true
+}
+
+// SetValue replaces the text with a value, limited to Min and Max and
+// rounded to the decimals of Format.
+func (n *NumberEditor) SetValue(v float64) {
+	s := strconv.FormatFloat(n.clamp(v), 'f', n.Format.Decimals, 64)
+	s = strings.Replace(s, ".", string(n.Format.decimal()), 1)
+	s, _ = n.Format.Format(s, 0)
+	// The formatted value is not subject to Filter and MaxLen.
+	n.formatting = true
+	n.SetText(s)
+	n.formatting = false
+	end := n.Len()
+	n.SetCaret(end, end)
+}
+
+func (n *NumberEditor) clamp(v float64) float64 {
+	if n.Min < n.Max {
+		v = math.Max(n.Min, math.Min(n.Max, v))
+	}
+	return v
+}
+
+// Update the state of the editor in response to input events. It is like
+// Editor.Update, but also steps the value with the up and down keys.
+func (n *NumberEditor) Update(gtx layout.Context) (EditorEvent, bool) {
+	n.initBuffer()
+	n.SingleLine = true
+	n.Formatter = n.Format
+	if n.InputHint == key.HintAny {
+		n.InputHint = key.HintNumeric
+	}
+	for !n.ReadOnly {
+		ev, ok := gtx.Event(
+			key.Filter{Focus: &n.Editor, Name: key.NameUpArrow, Optional: key.ModShift},
+			key.Filter{Focus: &n.Editor, Name: key.NameDownArrow, Optional: key.ModShift},
+		)
+		if !ok {
+			break
+		}
+		ke, ok := ev.(key.Event)
+		if !ok || ke.State != key.Press {
+			continue
+		}
+		steps := 1
+		if ke.Modifiers.Contain(key.ModShift) {
+			steps = 10
+		}
+		if ke.Name == key.NameDownArrow {
+			steps = -steps
+		}
+		if n.step(steps) {
+			return ChangeEvent{}, true
+		}
+	}
+	return n.Editor.Update(gtx)
+}
+
+// step adds a number of steps to the value, and reports whether the value
+// changed.
+func (n *NumberEditor) step(steps int) bool {
+	step := n.Step
+	if step == 0 {
+		step = 1
+	}
+	v, ok := n.Value()
+	if !ok {
+		v = n.clamp(0)
+	}
+	old := n.Text()
+	n.SetValue(v + float64(steps)*step)
+	// Consume the change reported by SetValue.
+	n.text.Changed()
+	return n.Text() != old
+}
+
+// Layout lays out the editor. See Editor.Layout.
+func (n *NumberEditor) Layout(gtx layout.Context, lt *text.Shaper, font font.Font, size unit.Sp, textMaterial, selectMaterial op.CallOp) layout.Dimensions {
+	for {
+		_, ok := n.Update(gtx)
+		if !ok {
+			break
+		}
+	}
+	return n.Editor.Layout(gtx, lt, font, size, textMaterial, selectMaterial)
+}
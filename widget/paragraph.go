@@ -0,0 +1,439 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/text"
+	"golang.org/x/image/math/fixed"
+)
+
+// lazyLayoutSize is the size in bytes of the texts for which a textView
+// lays out only the paragraphs around its viewport.
+var lazyLayoutSize int64 = 1 << 20
+
+// paragraph describes a paragraph of a large text.
+type paragraph struct {
+	// runes and bytes are the length of the paragraph, including its
+	// newline.
+	runes int
+	bytes int64
+	// lines is the number of lines of the paragraph, estimated as one until
+	// the paragraph is laid out.
+	lines int
+	// height is the distance between the first baseline of the paragraph
+	// and the first baseline of the next paragraph. It is valid only if
+	// measured is set.
+	height   int
+	measured bool
+}
+
+// paragraphOffset is the position of the start of a paragraph.
+type paragraphOffset struct {
+	runes int
+	bytes int64
+	line  int
+	// y is the distance from the first baseline of the text to the first
+	// baseline of the paragraph.
+	y int
+}
+
+// paragraphKey is the subset of the layout parameters that affect the
+// size of paragraphs.
+type paragraphKey struct {
+	font                       font.Font
+	pxPerEm, lineHeight        fixed.Int26_6
+	letterSpacing, wordSpacing fixed.Int26_6
+	tabWidth                   fixed.Int26_6
+	lineHeightScale            float32
+	maxWidth                   int
+	wrapPolicy                 text.WrapPolicy
+	mask                       rune
+}
+
+// paragraphIndex tracks the paragraphs of a large text, and the window of
+// paragraphs that is laid out.
+type paragraphIndex struct {
+	paragraphs []paragraph
+	// offsets caches the offsets of the paragraphs, followed by the offset
+	// of the end of the text. Only the first valid offsets are up to date.
+	offsets []paragraphOffset
+	valid   int
+	// scanned is set if paragraphs describe the text.
+	scanned bool
+	// runes is the length of the text.
+	runes int
+	// key is the parameters of the measured paragraphs.
+	key paragraphKey
+	// spacing is the distance between lines, base the first baseline of the
+	// text, descent the descent of its lines and width the width of the
+	// widest measured line.
+	spacing, base, descent, width int
+	// start and end are the indices of the paragraphs of the window, and
+	// first and last are their offsets.
+	start, end  int
+	first, last paragraphOffset
+}
+
+// scan splits the text of src into paragraphs.
+func (t *paragraphIndex) scan(src io.ReaderAt, size int64) {
+	t.paragraphs = t.paragraphs[:0]
+	t.runes = 0
+	p := paragraph{lines: 1}
+	buf := make([]byte, 32*1024)
+	// n is the number of bytes in buf.
+	n := 0
+	for off := int64(0); ; {
+		m, err := src.ReadAt(buf[n:], off)
+		off += int64(m)
+		n += m
+		eof := err != nil || off >= size
+		i := 0
+		for i < n {
+			c, w := buf[i], 1
+			if c >= utf8.RuneSelf {
+				if !eof && !utf8.FullRune(buf[i:n]) {
+					// Decode the rune with the next read.
+					break
+				}
+				_, w = utf8.DecodeRune(buf[i:n])
+			}
+			i += w
+			p.runes++
+			p.bytes += int64(w)
+			if c == '\n' {
+				t.paragraphs = append(t.paragraphs, p)
+				t.runes += p.runes
+				p = paragraph{lines: 1}
+			}
+		}
+		n = copy(buf, buf[i:n])
+		if eof {
+			break
+		}
+	}
+	t.paragraphs = append(t.paragraphs, p)
+	t.runes += p.runes
+	t.scanned = true
+	t.valid = 0
+}
+
+// replace updates the paragraphs for the replacement of the runes between
+// start and end with s. startOff and endOff are the byte offsets of start
+// and end.
+func (t *paragraphIndex) replace(start, end int, startOff, endOff int64, s string) {
+	if !t.scanned {
+		return
+	}
+	i, off := t.find(func(o paragraphOffset) bool { return o.runes > start })
+	j, endParagraph := t.find(func(o paragraphOffset) bool { return o.runes > end })
+	parts := strings.SplitAfter(s, "\n")
+	repl := make([]paragraph, len(parts))
+	for k, p := range parts {
+		repl[k] = paragraph{runes: utf8.RuneCountInString(p), bytes: int64(len(p)), lines: 1}
+	}
+	repl[0].runes += start - off.runes
+	repl[0].bytes += startOff - off.bytes
+	last := &repl[len(repl)-1]
+	last.runes += endParagraph.runes + t.paragraphs[j].runes - end
+	last.bytes += endParagraph.bytes + t.paragraphs[j].bytes - endOff
+	t.paragraphs = append(t.paragraphs[:i], append(repl, t.paragraphs[j+1:]...)...)
+	t.runes += utf8.RuneCountInString(s) - (end - start)
+	t.invalidate(i)
+}
+
+// forget discards the measurements of the paragraphs.
+func (t *paragraphIndex) forget() {
+	for i := range t.paragraphs {
+		p := &t.paragraphs[i]
+		p.lines = 1
+		p.measured = false
+	}
+	t.width = 0
+	t.invalidate(0)
+}
+
+// invalidate discards the cached offsets that depend on the i'th
+// paragraph.
+func (t *paragraphIndex) invalidate(i int) {
+	t.valid = min(t.valid, i+1)
+}
+
+// height returns the height of p, or its estimate.
+func (t *paragraphIndex) height(p paragraph) int {
+	if p.measured {
+		return p.height
+	}
+	return p.lines * t.spacing
+}
+
+// advance returns the offset of the paragraph after p at off.
+func (t *paragraphIndex) advance(off paragraphOffset, p paragraph) paragraphOffset {
+	return paragraphOffset{
+		runes: off.runes + p.runes,
+		bytes: off.bytes + p.bytes,
+		line:  off.line + p.lines,
+		y:     off.y + t.height(p),
+	}
+}
+
+// find returns the index and offset of the first paragraph for which
+// inside reports true for the offset of its end, or the last paragraph.
+// Offsets are increasing, so inside must report true for every offset
+// after the first for which it does.
+func (t *paragraphIndex) find(inside func(end paragraphOffset) bool) (int, paragraphOffset) {
+	last := len(t.paragraphs) - 1
+	t.offset(last)
+	i := sort.Search(last, func(i int) bool { return inside(t.offsets[i+1]) })
+	return i, t.offsets[i]
+}
+
+// offset returns the offset of the i'th paragraph, or the end of the text
+// for i equal to the number of paragraphs.
+func (t *paragraphIndex) offset(i int) paragraphOffset {
+	if t.valid == 0 {
+		t.offsets = append(t.offsets[:0], paragraphOffset{})
+		t.valid = 1
+	}
+	t.offsets = t.offsets[:t.valid]
+	for k := t.valid; k <= i; k++ {
+		t.offsets = append(t.offsets, t.advance(t.offsets[k-1], t.paragraphs[k-1]))
+	}
+	t.valid = len(t.offsets)
+	return t.offsets[i]
+}
+
+// bottom returns the distance from the top of the text to the bottom of
+// the line whose baseline is y below the first baseline.
+func (t *paragraphIndex) bottom(y int) int {
+	return t.base + y + t.descent
+}
+
+// containsRune reports whether the window holds the rune position r and its
+// neighbours.
+func (t *paragraphIndex) containsRune(r int) bool {
+	return (r > t.first.runes || t.start == 0) && (r < t.last.runes || t.end == len(t.paragraphs))
+}
+
+// containsLine is like containsRune for line numbers.
+func (t *paragraphIndex) containsLine(line int) bool {
+	return (line > t.first.line || t.start == 0) && (line < t.last.line-1 || t.end == len(t.paragraphs))
+}
+
+// containsY is like containsRune for vertical coordinates.
+func (t *paragraphIndex) containsY(y int) bool {
+	return (y >= t.base+t.first.y || t.start == 0) && (y < t.base+t.last.y-t.spacing || t.end == len(t.paragraphs))
+}
+
+// showRune lays out the paragraph that contains rune position r if it
+// is outside the window of a large text.
+func (e *textView) showRune(r int) {
+	if !e.lazy || e.paras.containsRune(r) {
+		return
+	}
+	i, _ := e.paras.find(func(o paragraphOffset) bool { return o.runes > r })
+	e.layoutWindow(i)
+}
+
+// showLine is like showRune for line numbers.
+func (e *textView) showLine(line int) {
+	if !e.lazy || e.paras.containsLine(line) {
+		return
+	}
+	i, _ := e.paras.find(func(o paragraphOffset) bool { return o.line > line })
+	e.layoutWindow(i)
+}
+
+// showY is like showRune for vertical coordinates.
+func (e *textView) showY(y int) {
+	if !e.lazy || e.paras.containsY(y) {
+		return
+	}
+	e.layoutWindow(e.paragraphAt(y))
+}
+
+// showViewport lays out the paragraphs of the viewport of a large text, if
+// they are outside the window.
+func (e *textView) showViewport() {
+	e.makeValid()
+	if !e.lazy {
+		return
+	}
+	if !e.paras.containsY(e.scrollOff.Y) || !e.paras.containsY(e.scrollOff.Y+e.viewSize.Y) {
+		e.layoutWindow(-1)
+	}
+}
+
+// paragraphAt returns the index of the paragraph at the vertical
+// coordinate y.
+func (e *textView) paragraphAt(y int) int {
+	t := &e.paras
+	i, _ := t.find(func(o paragraphOffset) bool { return t.bottom(o.y-t.spacing) >= y })
+	return i
+}
+
+// layoutWindow shapes the paragraphs around the paragraph anchor of a
+// large text, or around the viewport if anchor is negative. The heights of
+// the shaped paragraphs replace their estimates.
+func (e *textView) layoutWindow(anchor int) {
+	t := &e.paras
+	if !t.scanned {
+		t.scan(e.rr, e.rr.Size())
+	}
+	key := paragraphKey{
+		font:            e.params.Font,
+		pxPerEm:         e.params.PxPerEm,
+		lineHeight:      e.params.LineHeight,
+		letterSpacing:   e.params.LetterSpacing,
+		wordSpacing:     e.params.WordSpacing,
+		tabWidth:        e.params.TabWidth,
+		lineHeightScale: e.params.LineHeightScale,
+		maxWidth:        e.params.MaxWidth,
+		wrapPolicy:      e.params.WrapPolicy,
+		mask:            e.Mask,
+	}
+	if key != t.key || t.spacing == 0 {
+		t.key = key
+		t.forget()
+		// Estimate the line spacing the way the shaper computes it.
+		lh, scale := e.params.LineHeight, e.params.LineHeightScale
+		if lh == 0 {
+			lh = e.params.PxPerEm
+		}
+		if scale == 0 {
+			scale = 1.2
+		}
+		t.spacing = max(fixed.Int26_6(float32(lh)*scale).Round(), 1)
+		t.base = lh.Ceil()
+		t.descent = t.spacing - t.base
+	}
+	// Keep the paragraph at the top of the viewport in place.
+	top := e.paragraphAt(e.scrollOff.Y)
+	topY := t.offset(top).y
+	if anchor < 0 {
+		anchor = top
+	}
+
+	// Lay out a viewport of paragraphs before the anchor and two after it,
+	// and at least one paragraph on either side.
+	view := max(e.viewSize.Y, t.spacing)
+	anchorOff := t.offset(anchor)
+	start, first := anchor, anchorOff
+	for start > 0 && (start == anchor || anchorOff.y-first.y < view) {
+		start--
+		p := t.paragraphs[start]
+		first.runes -= p.runes
+		first.bytes -= p.bytes
+		first.line -= p.lines
+		first.y -= t.height(p)
+	}
+	end, last := anchor, anchorOff
+	for end < len(t.paragraphs) && (end < anchor+2 || last.y-anchorOff.y < 2*view) {
+		last = t.advance(last, t.paragraphs[end])
+		end++
+	}
+
+	var r io.Reader = io.NewSectionReader(e.rr, first.bytes, last.bytes-first.bytes)
+	if e.Mask != 0 {
+		e.maskReader.Reset(r, e.Mask)
+		r = &e.maskReader
+	}
+	e.index.reset()
+	e.index.pos.runes = first.runes
+	e.index.pos.lineCol.line = first.line
+	e.index.firstLine = first.line
+	e.spans = e.spans[:0]
+	if len(e.styles) > 0 {
+		e.shaper.LayoutSpans(e.params, e.styledSpans(r, first.runes))
+	} else {
+		e.shaper.Layout(e.params, r)
+	}
+	it := textIterator{viewport: image.Rectangle{Max: image.Point{X: math.MaxInt, Y: math.MaxInt}}}
+	// i is the paragraph being laid out and y its first baseline, both
+	// relative to the window.
+	i, y := start, 0
+	lines := 0
+	newParagraph := true
+	var dy int32
+	spacing := t.spacing
+	for {
+		g, ok := e.shaper.NextGlyph()
+		if !ok {
+			break
+		}
+		if newParagraph {
+			if i > start {
+				p := &t.paragraphs[i-1]
+				p.lines = max(lines, 1)
+				p.height = int(g.Y) - y
+				p.measured = true
+				t.spacing = max(p.height/p.lines, 1)
+				t.invalidate(i - 1)
+			}
+			if i == end {
+				// Skip the empty line after the final newline of the
+				// window.
+				break
+			}
+			if i == 0 {
+				t.base = int(g.Y)
+			}
+			if i == start {
+				dy = int32(t.base + first.y - int(g.Y))
+			}
+			y = int(g.Y)
+			lines = 0
+			newParagraph = false
+		}
+		if g.Flags&text.FlagLineBreak != 0 {
+			lines++
+		}
+		if g.Flags&text.FlagParagraphBreak != 0 {
+			newParagraph = true
+			i++
+		}
+		t.descent = g.Descent.Ceil()
+		g.Y += dy
+		it.processGlyph(g, true)
+		e.index.Glyph(g)
+	}
+	if !newParagraph && i < end {
+		// Measure the lines of the paragraph that ends the text.
+		t.paragraphs[i].lines = max(lines, 1)
+		t.invalidate(i)
+	}
+	if t.spacing != spacing {
+		// The estimated heights of the paragraphs changed.
+		t.invalidate(0)
+	}
+	t.start, t.end = start, end
+	t.first = first
+	t.last = t.offset(end)
+	t.width = max(t.width, it.bounds.Dx())
+	e.scrollOff.Y += t.offset(top).y - topY
+
+	e.paragraphReader.SetSource(io.NewSectionReader(e.rr, first.bytes, last.bytes-first.bytes))
+	e.paragraphReader.runeOffset = first.runes
+	e.graphemes = e.graphemes[:0]
+	for g := e.paragraphReader.Graphemes(); len(g) > 0; g = e.paragraphReader.Graphemes() {
+		if len(e.graphemes) > 0 && g[0] == e.graphemes[len(e.graphemes)-1] {
+			g = g[1:]
+		}
+		e.graphemes = append(e.graphemes, g...)
+	}
+
+	lastParagraph := len(t.paragraphs) - 1
+	lastY := t.offset(lastParagraph).y + (t.paragraphs[lastParagraph].lines-1)*t.spacing
+	dims := layout.Dimensions{Size: image.Pt(t.width, t.bottom(lastY))}
+	dims.Baseline = dims.Size.Y - t.base
+	e.dims = dims
+	e.valid = true
+}
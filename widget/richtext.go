@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"unicode/utf8"
+
+	"gioui.org/gesture"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/io/semantic"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/text"
+	"gioui.org/unit"
+)
+
+// RichText lays out rich text with interactive spans, such as hyperlinks
+// and mentions. Interactive spans show a pointer cursor when hovered, can
+// be focused with the keyboard and are clicked by the pointer or by the
+// Return and Space keys.
+type RichText struct {
+	// Alignment specifies the text alignment.
+	Alignment text.Alignment
+	// MaxLines limits the number of lines. Zero means no limit.
+	MaxLines int
+	// Truncator is the text that will be shown at the end of the final
+	// line if MaxLines is exceeded. Defaults to "…" if empty.
+	Truncator string
+	// WrapPolicy configures how displayed text will be broken into lines.
+	WrapPolicy text.WrapPolicy
+	// LineHeight controls the distance between the baselines of lines of text.
+	// If zero, a sensible default will be used.
+	LineHeight unit.Sp
+	// LineHeightScale applies a scaling factor to the LineHeight. If zero, a
+	// sensible default will be used.
+	LineHeightScale float32
+
+	index glyphIndex
+	spans []richSpan
+}
+
+// richSpan is the state of a span laid out by a RichText.
+type richSpan struct {
+	interactive bool
+	payload     any
+	click       gesture.Click
+	pressedKey  key.Name
+	// regions covers the visible glyphs of the span.
+	regions []Region
+}
+
+// SpanClick represents a click on an interactive span.
+type SpanClick struct {
+	// Span is the index of the clicked span.
+	Span int
+	// Payload is the Payload of the clicked span.
+	Payload   any
+	Modifiers key.Modifiers
+	NumClicks int
+}
+
+// Hovered reports whether a pointer is over the ith span.
+func (r *RichText) Hovered(i int) bool {
+	return i < len(r.spans) && r.spans[i].click.Hovered()
+}
+
+// Focused reports whether the ith span has the keyboard focus.
+func (r *RichText) Focused(gtx layout.Context, i int) bool {
+	return i < len(r.spans) && gtx.Focused(&r.spans[i])
+}
+
+// Regions returns the areas covered by the visible glyphs of the ith span
+// during the most recent Layout. It is valid only for interactive spans.
+func (r *RichText) Regions(i int) []Region {
+	if i >= len(r.spans) {
+		return nil
+	}
+	return r.spans[i].regions
+}
+
+// Update the state of the interactive spans and return the next click,
+// if any.
+func (r *RichText) Update(gtx layout.Context) (SpanClick, bool) {
+	for i := range r.spans {
+		s := &r.spans[i]
+		if !s.interactive {
+			continue
+		}
+		for {
+			e, ok := s.click.Update(gtx.Source)
+			if !ok {
+				break
+			}
+			switch e.Kind {
+			case gesture.KindClick:
+				return SpanClick{
+					Span:      i,
+					Payload:   s.payload,
+					Modifiers: e.Modifiers,
+					NumClicks: e.NumClicks,
+				}, true
+			case gesture.KindPress:
+				if e.Source == pointer.Mouse {
+					gtx.Execute(key.FocusCmd{Tag: s})
+				}
+			}
+		}
+		for {
+			e, ok := gtx.Event(
+				key.FocusFilter{Target: s},
+				key.Filter{Focus: s, Name: key.NameReturn},
+				key.Filter{Focus: s, Name: key.NameSpace},
+			)
+			if !ok {
+				break
+			}
+			switch e := e.(type) {
+			case key.FocusEvent:
+				s.pressedKey = ""
+			case key.Event:
+				switch e.State {
+				case key.Press:
+					s.pressedKey = e.Name
+				case key.Release:
+					if s.pressedKey != e.Name {
+						break
+					}
+					s.pressedKey = ""
+					return SpanClick{
+						Span:      i,
+						Payload:   s.payload,
+						Modifiers: e.Modifiers,
+						NumClicks: 1,
+					}, true
+				}
+			}
+		}
+	}
+	return SpanClick{}, false
+}
+
+// Layout the spans with the given shaper and default text size. The
+// interactive spans are hit tested by the positions of their glyphs.
+func (r *RichText) Layout(gtx layout.Context, lt *text.Shaper, size unit.Sp, spans []SpanStyle) layout.Dimensions {
+	if len(r.spans) != len(spans) {
+		r.spans = make([]richSpan, len(spans))
+	}
+	for i, sp := range spans {
+		r.spans[i].interactive = sp.Interactive
+		r.spans[i].payload = sp.Payload
+	}
+	for {
+		if _, ok := r.Update(gtx); !ok {
+			break
+		}
+	}
+	r.index.reset()
+	l := Label{
+		Alignment:       r.Alignment,
+		MaxLines:        r.MaxLines,
+		Truncator:       r.Truncator,
+		WrapPolicy:      r.WrapPolicy,
+		LineHeight:      r.LineHeight,
+		LineHeightScale: r.LineHeightScale,
+	}
+	dims, _ := l.layoutSpans(gtx, lt, size, spans, &r.index)
+	viewport := image.Rectangle{Max: gtx.Constraints.Max}
+	start := 0
+	for i, sp := range spans {
+		s := &r.spans[i]
+		end := start + utf8.RuneCountInString(sp.Content)
+		s.regions = s.regions[:0]
+		if sp.Interactive && end > start {
+			s.regions = r.index.locate(viewport, start, end, s.regions)
+		}
+		for j, reg := range s.regions {
+			cl := clip.Rect(reg.Bounds).Push(gtx.Ops)
+			if j == 0 {
+				semantic.Link.Add(gtx.Ops)
+				semantic.LabelOp(sp.Content).Add(gtx.Ops)
+				semantic.EnabledOp(gtx.Enabled()).Add(gtx.Ops)
+			}
+			pointer.CursorPointer.Add(gtx.Ops)
+			s.click.Add(gtx.Ops)
+			event.Op(gtx.Ops, s)
+			cl.Pop()
+		}
+		start = end
+	}
+	return dims
+}
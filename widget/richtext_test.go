@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget_test
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/io/semantic"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/widget"
+)
+
+func TestRichText(t *testing.T) {
+	var (
+		r     input.Router
+		rt    widget.RichText
+		cache = text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Source:      r.Source(),
+		Constraints: layout.Constraints{Max: image.Pt(60, 1000)},
+	}
+	spans := []widget.SpanStyle{
+		{Content: "Read the "},
+		{Content: "manual pages", Interactive: true, Payload: "https://gioui.org"},
+		{Content: " now."},
+	}
+	frame := func() {
+		gtx.Reset()
+		rt.Layout(gtx, cache, 10, spans)
+		r.Frame(gtx.Ops)
+	}
+	frame()
+	regs := rt.Regions(1)
+	if len(regs) != 2 {
+		t.Fatalf("link covers %d regions, want 2 for a wrapped link", len(regs))
+	}
+	if len(rt.Regions(0)) != 0 {
+		t.Error("plain span has regions")
+	}
+	click := func(pos f32.Point) {
+		r.Queue(
+			pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: pos},
+			pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: pos},
+		)
+	}
+	// Click the second line of the link.
+	click(layout.FPt(regs[1].Bounds.Min.Add(regs[1].Bounds.Max).Div(2)))
+	c, ok := rt.Update(gtx)
+	if !ok || c.Span != 1 || c.Payload != "https://gioui.org" {
+		t.Errorf("link click %+v, %v", c, ok)
+	}
+	if _, ok := rt.Update(gtx); ok {
+		t.Error("spurious click")
+	}
+	if got := r.Cursor(); got != pointer.CursorPointer {
+		t.Errorf("cursor %v over link, want %v", got, pointer.CursorPointer)
+	}
+	frame()
+	if !rt.Focused(gtx, 1) {
+		t.Error("clicking the link didn't focus it")
+	}
+	// Clicking the plain text is not reported.
+	click(f32.Pt(1, 5))
+	if _, ok := rt.Update(gtx); ok {
+		t.Error("plain text click reported")
+	}
+
+	frame()
+	gtx.Execute(key.FocusCmd{Tag: nil})
+	frame()
+	r.MoveFocus(key.FocusForward)
+	frame()
+	if !rt.Focused(gtx, 1) {
+		t.Fatal("link not reachable by keyboard focus")
+	}
+	r.Queue(
+		key.Event{Name: key.NameReturn, State: key.Press},
+		key.Event{Name: key.NameReturn, State: key.Release},
+	)
+	if c, ok := rt.Update(gtx); !ok || c.Span != 1 {
+		t.Errorf("keyboard click %+v, %v", c, ok)
+	}
+
+	links := 0
+	for _, n := range r.AppendSemantics(nil) {
+		if n.Desc.Class == semantic.Link {
+			links++
+			if n.Desc.Label != "manual pages" {
+				t.Errorf("link label %q, want %q", n.Desc.Label, "manual pages")
+			}
+		}
+	}
+	if links != 1 {
+		t.Errorf("%d semantic links, want 1", links)
+	}
+}
@@ -0,0 +1,255 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// Search describes what to search for in text.
+type Search struct {
+	// Pattern is the text to search for, or a regular expression in the
+	// syntax of package regexp if Regexp is set.
+	Pattern string
+	// Regexp interprets Pattern as a regular expression.
+	Regexp bool
+	// IgnoreCase matches letters regardless of their case.
+	IgnoreCase bool
+	// WholeWord matches only text that doesn't start or end within a word.
+	WholeWord bool
+}
+
+// Match is a range of matching text.
+type Match struct {
+	// Start and End are the rune offsets of the match.
+	Start, End int
+}
+
+// Searchable is text that a Finder can search, such as an Editor or a
+// Selectable.
+type Searchable interface {
+	Text() string
+	Selection() (start, end int)
+	SetCaret(start, end int)
+	ScrollToCaret()
+	Regions(start, end int, regions []Region) []Region
+}
+
+// Finder searches the text of a Searchable, and navigates and replaces its
+// matches.
+type Finder struct {
+	Search
+
+	matches []Match
+	regions []Region
+}
+
+// searchMatch is a match and the byte offsets of its regular expression
+// submatches.
+type searchMatch struct {
+	Match
+	submatches []int
+}
+
+// compile returns the regular expression of the search.
+func (s Search) compile() (*regexp.Regexp, error) {
+	expr := s.Pattern
+	if !s.Regexp {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if s.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// FindAll returns the matches of the search in txt.
+func (s Search) FindAll(txt string) ([]Match, error) {
+	ms, _, err := s.find(txt)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]Match, len(ms))
+	for i, m := range ms {
+		matches[i] = m.Match
+	}
+	return matches, nil
+}
+
+// find returns the matches of the search in txt, and its regular
+// expression.
+func (s Search) find(txt string) ([]searchMatch, *regexp.Regexp, error) {
+	if s.Pattern == "" {
+		return nil, nil, nil
+	}
+	re, err := s.compile()
+	if err != nil {
+		return nil, nil, err
+	}
+	var matches []searchMatch
+	// pos and runes track the rune offset of a byte offset.
+	pos, runes := 0, 0
+	for _, idx := range re.FindAllStringSubmatchIndex(txt, -1) {
+		start, end := idx[0], idx[1]
+		if start == end {
+			continue
+		}
+		if s.WholeWord && (!wordBoundary(txt, start) || !wordBoundary(txt, end)) {
+			continue
+		}
+		runes += utf8.RuneCountInString(txt[pos:start])
+		m := searchMatch{submatches: idx}
+		m.Start = runes
+		runes += utf8.RuneCountInString(txt[start:end])
+		m.End = runes
+		pos = end
+		matches = append(matches, m)
+	}
+	return matches, re, nil
+}
+
+// wordBoundary reports whether the byte offset off of txt is not within a
+// word.
+func wordBoundary(txt string, off int) bool {
+	before, _ := utf8.DecodeLastRuneInString(txt[:off])
+	after, _ := utf8.DecodeRuneInString(txt[off:])
+	return !isWordRune(before) || !isWordRune(after)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Find searches the text of t and returns an error if the pattern is not a
+// valid regular expression. Call Find again when the search or the text
+// changes.
+func (f *Finder) Find(t Searchable) error {
+	matches, err := f.FindAll(t.Text())
+	f.matches = matches
+	return err
+}
+
+// Matches returns the matches found by Find.
+func (f *Finder) Matches() []Match {
+	return f.matches
+}
+
+// Next selects the first match after the selection of t, wrapping around
+// to the first match, and scrolls it into view. It returns false if there
+// are no matches.
+func (f *Finder) Next(t Searchable) bool {
+	if len(f.matches) == 0 {
+		return false
+	}
+	start, end := t.Selection()
+	pos := max(start, end)
+	next := f.matches[0]
+	for _, m := range f.matches {
+		if m.Start >= pos {
+			next = m
+			break
+		}
+	}
+	f.selectMatch(t, next)
+	return true
+}
+
+// Previous is like Next, but selects the last match before the selection.
+func (f *Finder) Previous(t Searchable) bool {
+	if len(f.matches) == 0 {
+		return false
+	}
+	start, end := t.Selection()
+	pos := min(start, end)
+	prev := f.matches[len(f.matches)-1]
+	for i := len(f.matches) - 1; i >= 0; i-- {
+		if m := f.matches[i]; m.End <= pos {
+			prev = m
+			break
+		}
+	}
+	f.selectMatch(t, prev)
+	return true
+}
+
+func (f *Finder) selectMatch(t Searchable, m Match) {
+	t.SetCaret(m.End, m.Start)
+	t.ScrollToCaret()
+}
+
+// Paint fills the visible matches with material. It must be called with
+// the transformation of the layout of t, such as right after laying it out.
+func (f *Finder) Paint(gtx layout.Context, t Searchable, material op.CallOp) {
+	for _, m := range f.matches {
+		f.regions = t.Regions(m.Start, m.End, f.regions)
+		for _, r := range f.regions {
+			area := clip.Rect(r.Bounds).Push(gtx.Ops)
+			material.Add(gtx.Ops)
+			paint.PaintOp{}.Add(gtx.Ops)
+			area.Pop()
+		}
+	}
+}
+
+// Replace replaces the selected match of e with repl, and selects the next
+// match. Regular expression submatches such as $1 in repl are expanded.
+// If no match is selected, Replace only selects the next match. It returns
+// whether a match was replaced.
+func (f *Finder) Replace(e *Editor, repl string) (bool, error) {
+	txt := e.Text()
+	matches, re, err := f.find(txt)
+	if err != nil {
+		return false, err
+	}
+	start, end := e.Selection()
+	start, end = min(start, end), max(start, end)
+	replaced := false
+	for _, m := range matches {
+		if m.Start == start && m.End == end {
+			n := e.replace(m.Start, m.End, f.expand(re, txt, m, repl), true)
+			e.SetCaret(m.Start+n, m.Start+n)
+			replaced = true
+			break
+		}
+	}
+	if err := f.Find(e); err != nil {
+		return replaced, err
+	}
+	f.Next(e)
+	return replaced, nil
+}
+
+// ReplaceAll replaces all matches in e with repl, as a single undo step,
+// and returns the number of replaced matches. Regular expression
+// submatches such as $1 in repl are expanded.
+func (f *Finder) ReplaceAll(e *Editor, repl string) (int, error) {
+	txt := e.Text()
+	matches, re, err := f.find(txt)
+	if err != nil || len(matches) == 0 {
+		return 0, err
+	}
+	e.BeginTransaction()
+	// Replace from the end to keep the offsets of the remaining matches.
+	for i := len(matches) - 1; i >= 0; i-- {
+		m := matches[i]
+		e.replace(m.Start, m.End, f.expand(re, txt, m, repl), true)
+	}
+	e.EndTransaction()
+	e.SetCaret(matches[0].Start, matches[0].Start)
+	return len(matches), f.Find(e)
+}
+
+// expand returns the replacement of the match m in txt.
+func (f *Finder) expand(re *regexp.Regexp, txt string, m searchMatch, repl string) string {
+	if !f.Regexp {
+		return repl
+	}
+	return string(re.ExpandString(nil, repl, txt, m.submatches))
+}
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSearchFindAll(t *testing.T) {
+	const txt = "Föö foo_bar fOo foo."
+	tests := []struct {
+		search Search
+		want   []Match
+	}{
+		{Search{Pattern: "foo"}, []Match{{4, 7}, {16, 19}}},
+		{Search{Pattern: "föö", IgnoreCase: true}, []Match{{0, 3}}},
+		{Search{Pattern: "foo", IgnoreCase: true, WholeWord: true}, []Match{{12, 15}, {16, 19}}},
+		{Search{Pattern: `f\w+`, Regexp: true}, []Match{{4, 11}, {12, 15}, {16, 19}}},
+		{Search{Pattern: "."}, []Match{{19, 20}}},
+		{Search{}, nil},
+	}
+	for _, test := range tests {
+		got, err := test.search.FindAll(txt)
+		if err != nil {
+			t.Errorf("%+v: %v", test.search, err)
+			continue
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%+v: got %v, want %v", test.search, got, test.want)
+		}
+	}
+	if _, err := (Search{Pattern: "(", Regexp: true}).FindAll(txt); err == nil {
+		t.Error("invalid regular expression found no error")
+	}
+}
+
+func TestFinderNavigation(t *testing.T) {
+	var s Selectable
+	s.SetText("one two one two one")
+	f := &Finder{Search: Search{Pattern: "one"}}
+	if err := f.Find(&s); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(f.Matches()); n != 3 {
+		t.Fatalf("got %d matches, want 3", n)
+	}
+	steps := []struct {
+		next       bool
+		start, end int
+	}{
+		{true, 0, 3},
+		{true, 8, 11},
+		{true, 16, 19},
+		{true, 0, 3},
+		{false, 16, 19},
+		{false, 8, 11},
+	}
+	for i, step := range steps {
+		if step.next {
+			f.Next(&s)
+		} else {
+			f.Previous(&s)
+		}
+		if end, start := s.Selection(); start != step.start || end != step.end {
+			t.Errorf("step %d: got selection [%d,%d), want [%d,%d)", i, start, end, step.start, step.end)
+		}
+	}
+}
+
+func TestFinderReplace(t *testing.T) {
+	e := new(Editor)
+	e.SetText("a1 b2 a3")
+	f := &Finder{Search: Search{Pattern: `a(\d)`, Regexp: true}}
+	if err := f.Find(e); err != nil {
+		t.Fatal(err)
+	}
+	// Without a selected match, Replace selects the first match.
+	if replaced, _ := f.Replace(e, "x"); replaced {
+		t.Error("replaced without a selected match")
+	}
+	if replaced, _ := f.Replace(e, "<$1>"); !replaced {
+		t.Error("selected match not replaced")
+	}
+	if got, want := e.Text(), "<1> b2 a3"; got != want {
+		t.Errorf("got %q after replace, want %q", got, want)
+	}
+	if got, want := e.SelectedText(), "a3"; got != want {
+		t.Errorf("got selection %q after replace, want %q", got, want)
+	}
+
+	f.Search = Search{Pattern: `\w(\d)`, Regexp: true}
+	n, err := f.ReplaceAll(e, "#$1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := e.Text(), "<1> #2 #3"; n != 2 || got != want {
+		t.Errorf("got %q after replacing %d matches, want %q", got, n, want)
+	}
+	if len(f.Matches()) != 0 {
+		t.Errorf("got matches %v after replacing all", f.Matches())
+	}
+	// Replacing all is a single undo step.
+	e.undo()
+	if got, want := e.Text(), "<1> b2 a3"; got != want {
+		t.Errorf("got %q after undo, want %q", got, want)
+	}
+}
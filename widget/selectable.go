@@ -2,13 +2,11 @@ package widget
 
 import (
 	"image"
-	"io"
 	"math"
 	"strings"
 
 	"gioui.org/font"
 	"gioui.org/gesture"
-	"gioui.org/io/clipboard"
 	"gioui.org/io/event"
 	"gioui.org/io/key"
 	"gioui.org/io/pointer"
@@ -20,13 +18,13 @@ import (
 	"gioui.org/unit"
 )
 
-// stringSource is an immutable textSource with a fixed string
+// stringSource is an immutable TextSource with a fixed string
 // value.
 type stringSource struct {
 	reader *strings.Reader
 }
 
-var _ textSource = stringSource{}
+var _ TextSource = stringSource{}
 
 func newStringSource(str string) stringSource {
 	return stringSource{
@@ -67,8 +65,18 @@ type Selectable struct {
 	// LineHeightScale applies a scaling factor to the LineHeight. If zero, a
 	// sensible default will be used.
 	LineHeightScale float32
-	initialized     bool
-	source          stringSource
+	// Decoration is the set of lines drawn along the text.
+	Decoration text.Decoration
+	// LetterSpacing is added between the characters of the text.
+	LetterSpacing unit.Sp
+	// WordSpacing is added to the spaces between words.
+	WordSpacing unit.Sp
+	// TabWidth is the interval between tab stops. If zero, tabs are as
+	// wide as their glyph.
+	TabWidth unit.Sp
+
+	initialized bool
+	source      stringSource
 	// scratch is a buffer reused to efficiently read text out of the
 	// textView.
 	scratch   []byte
@@ -77,6 +85,9 @@ type Selectable struct {
 	focused   bool
 	dragging  bool
 	dragger   gesture.Drag
+	// primaryStale is set when the selection changed since it was last
+	// written to the primary selection.
+	primaryStale bool
 
 	clicker gesture.Click
 }
@@ -133,6 +144,12 @@ func (l *Selectable) SetCaret(start, end int) {
 	l.text.SetCaret(start, end)
 }
 
+// ScrollToCaret scrolls the caret into view.
+func (l *Selectable) ScrollToCaret() {
+	l.initialize()
+	l.text.ScrollToCaret()
+}
+
 // SelectedText returns the currently selected text (if any) from the editor.
 func (l *Selectable) SelectedText() string {
 	l.initialize()
@@ -189,18 +206,28 @@ func (l *Selectable) Layout(gtx layout.Context, lt *text.Shaper, font font.Font,
 	l.text.MaxLines = l.MaxLines
 	l.text.Truncator = l.Truncator
 	l.text.WrapPolicy = l.WrapPolicy
-	l.text.Layout(gtx, lt, font, size)
-	dims := l.text.Dimensions()
-	defer clip.Rect(image.Rectangle{Max: dims.Size}).Push(gtx.Ops).Pop()
-	pointer.CursorText.Add(gtx.Ops)
-	event.Op(gtx.Ops, l)
-
-	l.clicker.Add(gtx.Ops)
-	l.dragger.Add(gtx.Ops)
-
-	l.paintSelection(gtx, selectionMaterial)
-	l.paintText(gtx, textMaterial)
-	return dims
+	l.text.Decoration = l.Decoration
+	l.text.LetterSpacing = l.LetterSpacing
+	l.text.WordSpacing = l.WordSpacing
+	l.text.TabWidth = l.TabWidth
+	return layoutText(gtx, func(gtx layout.Context) layout.Dimensions {
+		l.text.Layout(gtx, lt, font, size)
+		dims := l.text.Dimensions()
+		defer clip.Rect(image.Rectangle{Max: dims.Size}).Push(gtx.Ops).Pop()
+		if gtx.Locale.Direction.Axis() == system.Vertical {
+			pointer.CursorVerticalText.Add(gtx.Ops)
+		} else {
+			pointer.CursorText.Add(gtx.Ops)
+		}
+		event.Op(gtx.Ops, l)
+
+		l.clicker.Add(gtx.Ops)
+		l.dragger.Add(gtx.Ops)
+
+		l.paintSelection(gtx, selectionMaterial)
+		l.paintText(gtx, textMaterial)
+		return dims
+	})
 }
 
 func (l *Selectable) handleEvents(gtx layout.Context) (selectionChanged bool) {
@@ -208,6 +235,16 @@ func (l *Selectable) handleEvents(gtx layout.Context) (selectionChanged bool) {
 	defer func() {
 		if newStart, newLen := min(l.text.Selection()), l.text.SelectionLen(); oldStart != newStart || oldLen != newLen {
 			selectionChanged = true
+			l.primaryStale = true
+		}
+		// Write the selection to the primary selection once a drag
+		// completes.
+		if l.primaryStale && !l.dragging {
+			l.primaryStale = false
+			l.scratch = l.text.SelectedText(l.scratch)
+			if text := string(l.scratch); text != "" {
+				writeClipboard(gtx, text, true)
+			}
 		}
 	}()
 	l.processPointer(gtx)
@@ -342,7 +379,7 @@ func (e *Selectable) command(gtx layout.Context, k key.Event) {
 		case "C", "X":
 			e.scratch = e.text.SelectedText(e.scratch)
 			if text := string(e.scratch); text != "" {
-				gtx.Execute(clipboard.WriteCmd{Type: "application/text", Data: io.NopCloser(strings.NewReader(text))})
+				writeClipboard(gtx, text, false)
 			}
 		// Select all
 		case "A":
@@ -350,7 +387,22 @@ func (e *Selectable) command(gtx layout.Context, k key.Event) {
 		}
 		return
 	}
-	switch k.Name {
+	name := k.Name
+	if gtx.Locale.Direction.Axis() == system.Vertical {
+		// Text flows down the columns, and the columns progress from
+		// right to left.
+		switch name {
+		case key.NameUpArrow:
+			name = key.NameLeftArrow
+		case key.NameDownArrow:
+			name = key.NameRightArrow
+		case key.NameLeftArrow:
+			name = key.NameDownArrow
+		case key.NameRightArrow:
+			name = key.NameUpArrow
+		}
+	}
+	switch name {
 	case key.NameUpArrow:
 		e.text.MoveLines(-1, selAct)
 	case key.NameDownArrow:
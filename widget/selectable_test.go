@@ -5,10 +5,13 @@ import (
 	"image"
 	"testing"
 
+	"gioui.org/f32"
 	"gioui.org/font"
 	"gioui.org/font/gofont"
 	"gioui.org/io/input"
 	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/io/system"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/text"
@@ -93,7 +96,7 @@ func TestSelectableConfigurations(t *testing.T) {
 	font := font.Font{}
 	sentence := "\n\n\n\n\n\n\n\n\n\n\n\nthe quick brown fox jumps over the lazy dog"
 
-	for _, alignment := range []text.Alignment{text.Start, text.Middle, text.End} {
+	for _, alignment := range []text.Alignment{text.Start, text.Middle, text.End, text.Justify} {
 		for _, zeroMin := range []bool{true, false} {
 			t.Run(fmt.Sprintf("Alignment: %v ZeroMinConstraint: %v", alignment, zeroMin), func(t *testing.T) {
 				defer func() {
@@ -119,3 +122,65 @@ func TestSelectableConfigurations(t *testing.T) {
 		}
 	}
 }
+
+// TestSelectableVertical verifies that vertical text is laid out in columns
+// from right to left, and that pointer and key input follow the columns.
+func TestSelectableVertical(t *testing.T) {
+	r := new(input.Router)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Locale:      system.Locale{Language: "ja", Direction: system.TTB},
+		Source:      r.Source(),
+		Constraints: layout.Exact(image.Pt(200, 50)),
+	}
+	gtx.Constraints.Min = image.Point{}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	fontSize := unit.Sp(10)
+	s := new(Selectable)
+	s.SetText("aaaa aaaa bbbb bbbb")
+	dims := s.Layout(gtx, cache, font.Font{}, fontSize, op.CallOp{}, op.CallOp{})
+	r.Frame(gtx.Ops)
+	if dims.Size.Y > 50 || dims.Size.X >= dims.Size.Y {
+		t.Fatalf("vertical text has size %v, want narrow columns at most 50 high", dims.Size)
+	}
+	// Press the top of the leftmost column.
+	r.Queue(
+		pointer.Event{
+			Kind:     pointer.Press,
+			Buttons:  pointer.ButtonPrimary,
+			Source:   pointer.Mouse,
+			Position: f32.Pt(1, 1),
+		},
+		pointer.Event{
+			Kind:     pointer.Release,
+			Buttons:  pointer.ButtonPrimary,
+			Source:   pointer.Mouse,
+			Position: f32.Pt(1, 1),
+		},
+	)
+	s.Layout(gtx, cache, font.Font{}, fontSize, op.CallOp{}, op.CallOp{})
+	r.Frame(gtx.Ops)
+	lastLine, _ := s.Selection()
+	if lastLine < 10 {
+		t.Fatalf("pressing the leftmost column moved the caret to %d, want the last column", lastLine)
+	}
+	// The right arrow moves to the previous column, and the down arrow
+	// along the column.
+	for _, k := range []key.Name{key.NameRightArrow, key.NameDownArrow} {
+		before, _ := s.Selection()
+		r.Queue(key.Event{State: key.Press, Name: k})
+		s.Layout(gtx, cache, font.Font{}, fontSize, op.CallOp{}, op.CallOp{})
+		r.Frame(gtx.Ops)
+		after, _ := s.Selection()
+		switch k {
+		case key.NameRightArrow:
+			if after >= before {
+				t.Errorf("right arrow moved the caret from %d to %d, want the previous column", before, after)
+			}
+		case key.NameDownArrow:
+			if after != before+1 {
+				t.Errorf("down arrow moved the caret from %d to %d, want %d", before, after, before+1)
+			}
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+
+	"gioui.org/gesture"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/unit"
+)
+
+// Split lays out two widgets side by side along an axis, separated by a
+// divider the user can drag to distribute the space between them.
+//
+// The divider can be moved with the arrow keys, and double-clicking it or
+// pressing Return collapses the first widget. Ratio and Collapsed describe
+// the complete state of the split and may be saved and restored between
+// sessions.
+type Split struct {
+	// Axis is the axis along which the widgets are laid out.
+	Axis layout.Axis
+	// Ratio is the position of the divider, in the [-1; 1] range. 0 is
+	// the center, -1 leaves no space for the first widget and 1 leaves no
+	// space for the second widget.
+	Ratio float32
+	// Collapsed hides the first widget. Ratio is kept for restoring the
+	// divider position.
+	Collapsed bool
+	// Bar is the thickness of the divider. If zero, a default of 8dp is
+	// used.
+	Bar unit.Dp
+	// First and Second limit the sizes of the widgets.
+	First, Second SplitLimit
+
+	drag  gesture.Drag
+	click gesture.Click
+	// grab is the position of the pointer on the divider during a drag.
+	grab float32
+	// first and avail are the first widget size and the space available
+	// to both widgets during the most recent Layout.
+	first, avail int
+	focused      bool
+	// limits are the limits of the first widget size, in pixels.
+	limits struct{ min, max int }
+}
+
+// SplitLimit constrains the size of a widget of a Split.
+type SplitLimit struct {
+	// Min is the minimum size.
+	Min unit.Dp
+	// Max is the maximum size. Zero means no limit.
+	Max unit.Dp
+}
+
+const (
+	defaultSplitBar = 8
+	// splitStep is the distance the divider moves for each arrow key press.
+	splitStep = 16
+)
+
+// Dragging reports whether the divider is being dragged.
+func (s *Split) Dragging() bool {
+	return s.drag.Dragging()
+}
+
+// Focused reports whether the divider has the keyboard focus.
+func (s *Split) Focused() bool {
+	return s.focused
+}
+
+// Update the state of the split and report whether Ratio or Collapsed
+// was changed by the user.
+func (s *Split) Update(gtx layout.Context) bool {
+	changed := false
+	for {
+		e, ok := s.click.Update(gtx.Source)
+		if !ok {
+			break
+		}
+		switch e.Kind {
+		case gesture.KindPress:
+			if e.Source == pointer.Mouse {
+				gtx.Execute(key.FocusCmd{Tag: s})
+			}
+		case gesture.KindClick:
+			if e.NumClicks == 2 {
+				s.Collapsed = !s.Collapsed
+				changed = true
+			}
+		}
+	}
+	for {
+		e, ok := s.drag.Update(gtx.Metric, gtx.Source, gesture.Axis(s.Axis))
+		if !ok {
+			break
+		}
+		pos := s.Axis.FConvert(e.Position).X
+		switch e.Kind {
+		case pointer.Press:
+			s.grab = pos
+		case pointer.Drag:
+			// The position is relative to the divider as of the most
+			// recent Layout.
+			first := s.first + int(pos-s.grab+.5)
+			if s.Collapsed {
+				first = int(pos - s.grab + .5)
+			}
+			s.Collapsed = false
+			s.setFirst(first)
+			changed = true
+		}
+	}
+	for {
+		ev, ok := gtx.Event(
+			key.FocusFilter{Target: s},
+			key.Filter{Focus: s, Name: key.NameLeftArrow},
+			key.Filter{Focus: s, Name: key.NameRightArrow},
+			key.Filter{Focus: s, Name: key.NameUpArrow},
+			key.Filter{Focus: s, Name: key.NameDownArrow},
+			key.Filter{Focus: s, Name: key.NameHome},
+			key.Filter{Focus: s, Name: key.NameEnd},
+			key.Filter{Focus: s, Name: key.NameReturn},
+			key.Filter{Focus: s, Name: key.NameEnter},
+		)
+		if !ok {
+			break
+		}
+		switch e := ev.(type) {
+		case key.FocusEvent:
+			s.focused = e.Focus
+		case key.Event:
+			if e.State != key.Press {
+				break
+			}
+			first := s.first
+			if s.Collapsed {
+				first = 0
+			}
+			step := gtx.Dp(splitStep)
+			switch e.Name {
+			case key.NameReturn, key.NameEnter:
+				s.Collapsed = !s.Collapsed
+				changed = true
+				continue
+			case key.NameLeftArrow, key.NameUpArrow:
+				first -= step
+			case key.NameRightArrow, key.NameDownArrow:
+				first += step
+			case key.NameHome:
+				first = s.limits.min
+			case key.NameEnd:
+				first = s.limits.max
+			}
+			s.Collapsed = false
+			s.setFirst(first)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// setFirst updates Ratio to give the first widget size first.
+func (s *Split) setFirst(first int) {
+	if s.avail <= 0 {
+		return
+	}
+	first = max(s.limits.min, min(s.limits.max, first))
+	s.Ratio = float32(first)*2/float32(s.avail) - 1
+}
+
+// Layout the widgets along with the divider. The divider widget, if not nil,
+// is laid out with exact constraints and can be used to draw the divider.
+func (s *Split) Layout(gtx layout.Context, first, second, divider layout.Widget) layout.Dimensions {
+	s.Update(gtx)
+	size := gtx.Constraints.Max
+	bar := gtx.Dp(s.Bar)
+	if s.Bar == 0 {
+		bar = gtx.Dp(defaultSplitBar)
+	}
+	total, cross := s.Axis.Convert(size).X, s.Axis.Convert(size).Y
+	s.avail = max(0, total-bar)
+	s.updateLimits(gtx)
+
+	firstSize := int((s.Ratio+1)/2*float32(s.avail) + .5)
+	firstSize = max(s.limits.min, min(s.limits.max, firstSize))
+	s.first = firstSize
+	if s.Collapsed {
+		firstSize = 0
+	}
+	secondSize := s.avail - firstSize
+
+	if firstSize > 0 {
+		gtx := gtx
+		gtx.Constraints = layout.Exact(s.Axis.Convert(image.Pt(firstSize, cross)))
+		rect := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+		first(gtx)
+		rect.Pop()
+	}
+
+	barSize := s.Axis.Convert(image.Pt(bar, cross))
+	off := op.Offset(s.Axis.Convert(image.Pt(firstSize, 0))).Push(gtx.Ops)
+	area := clip.Rect{Max: barSize}.Push(gtx.Ops)
+	if s.Axis == layout.Horizontal {
+		pointer.CursorColResize.Add(gtx.Ops)
+	} else {
+		pointer.CursorRowResize.Add(gtx.Ops)
+	}
+	s.drag.Add(gtx.Ops)
+	s.click.Add(gtx.Ops)
+	event.Op(gtx.Ops, s)
+	if divider != nil {
+		gtx := gtx
+		gtx.Constraints = layout.Exact(barSize)
+		divider(gtx)
+	}
+	area.Pop()
+	off.Pop()
+
+	if secondSize > 0 {
+		gtx := gtx
+		gtx.Constraints = layout.Exact(s.Axis.Convert(image.Pt(secondSize, cross)))
+		off := op.Offset(s.Axis.Convert(image.Pt(firstSize+bar, 0))).Push(gtx.Ops)
+		rect := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+		second(gtx)
+		rect.Pop()
+		off.Pop()
+	}
+	return layout.Dimensions{Size: size}
+}
+
+// updateLimits computes the range of sizes of the first widget allowed by
+// the limits of both widgets.
+func (s *Split) updateLimits(gtx layout.Context) {
+	lo, hi := gtx.Dp(s.First.Min), s.avail
+	if s.First.Max != 0 {
+		hi = min(hi, gtx.Dp(s.First.Max))
+	}
+	hi = min(hi, s.avail-gtx.Dp(s.Second.Min))
+	if s.Second.Max != 0 {
+		lo = max(lo, s.avail-gtx.Dp(s.Second.Max))
+	}
+	// Satisfy the limits of the first widget in case of conflict.
+	lo = max(0, min(s.avail, lo))
+	hi = max(lo, min(s.avail, hi))
+	s.limits.min, s.limits.max = lo, hi
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget_test
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/widget"
+)
+
+func TestSplit(t *testing.T) {
+	var (
+		r     input.Router
+		split = widget.Split{
+			Bar:    10,
+			First:  widget.SplitLimit{Min: 50},
+			Second: widget.SplitLimit{Max: 300},
+		}
+		sizes [2]image.Point
+	)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Source:      r.Source(),
+		Constraints: layout.Exact(image.Pt(410, 100)),
+	}
+	frame := func() {
+		gtx.Reset()
+		split.Layout(gtx,
+			func(gtx layout.Context) layout.Dimensions {
+				sizes[0] = gtx.Constraints.Min
+				return layout.Dimensions{Size: sizes[0]}
+			},
+			func(gtx layout.Context) layout.Dimensions {
+				sizes[1] = gtx.Constraints.Min
+				return layout.Dimensions{Size: sizes[1]}
+			},
+			nil,
+		)
+		r.Frame(gtx.Ops)
+	}
+	frame()
+	if sizes[0].X != 200 || sizes[1].X != 200 {
+		t.Fatalf("centered split sizes %v, want 200 and 200", sizes)
+	}
+	// Drag the divider 50 pixels to the right.
+	r.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Pt(205, 50)},
+		pointer.Event{Kind: pointer.Move, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Pt(255, 50)},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: f32.Pt(255, 50)},
+	)
+	if !split.Update(gtx) {
+		t.Error("drag didn't change the split")
+	}
+	frame()
+	if sizes[0].X != 250 || sizes[1].X != 150 {
+		t.Errorf("dragged split sizes %v, want 250 and 150", sizes)
+	}
+	if !gtx.Focused(&split) {
+		t.Error("press didn't focus the divider")
+	}
+	// The second widget is at most 300 pixels wide.
+	r.Queue(key.Event{Name: key.NameHome, State: key.Press})
+	frame()
+	if sizes[0].X != 100 {
+		t.Errorf("home moved first widget to %d, want 100", sizes[0].X)
+	}
+	r.Queue(key.Event{Name: key.NameRightArrow, State: key.Press})
+	frame()
+	if sizes[0].X != 116 {
+		t.Errorf("right arrow moved first widget to %d, want 116", sizes[0].X)
+	}
+	// Double-click collapses the first widget, and restores it.
+	for range 2 {
+		x := float32(sizes[0].X + 5)
+		r.Queue(
+			pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Pt(x, 50)},
+			pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: f32.Pt(x, 50)},
+			pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Pt(x, 50)},
+			pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: f32.Pt(x, 50)},
+		)
+		prev := sizes[0].X
+		frame()
+		if split.Collapsed != (prev != 0) {
+			t.Errorf("double-click collapsed = %v", split.Collapsed)
+		}
+	}
+	if sizes[0].X != 116 {
+		t.Errorf("restored first widget size %d, want 116", sizes[0].X)
+	}
+}
@@ -0,0 +1,264 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"time"
+
+	"gioui.org/gesture"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/io/semantic"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+)
+
+// Tabs holds the state of a horizontal strip of tabs, of which at most one
+// is selected. The strip scrolls when the tabs don't fit the available
+// space.
+type Tabs struct {
+	// Selected is the index of the selected tab.
+	Selected int
+	// List scrolls the strip. Its Axis is set by Layout.
+	List layout.List
+	// Closable enables closing the selected tab with the Delete key.
+	Closable bool
+
+	tabs    []tabState
+	focused bool
+	// scroll is set when the selected tab should be scrolled into view.
+	scroll bool
+	// anim tracks the movement of the selection indicator.
+	anim struct {
+		from  int
+		start time.Time
+	}
+}
+
+type tabState struct {
+	click gesture.Click
+	close Clickable
+	// x and width locate the tab in the strip, if it was laid out.
+	x, width int
+	visible  bool
+}
+
+// TabEvent describes a user interaction with a tab.
+type TabEvent struct {
+	Kind TabEventKind
+	// Index is the index of the tab.
+	Index int
+}
+
+// TabEventKind is the kind of a TabEvent.
+type TabEventKind uint8
+
+const (
+	// TabSelected is reported when a tab becomes the selected tab.
+	TabSelected TabEventKind = iota
+	// TabClosed is reported when the user requests a tab to be closed.
+	// Removing the tab is the responsibility of the program.
+	TabClosed
+)
+
+// tabAnimation is the duration of the indicator movement.
+const tabAnimation = 150 * time.Millisecond
+
+// CloseButton returns the clickable for closing the tab at index. It is
+// only valid after the first call to Layout.
+func (t *Tabs) CloseButton(index int) *Clickable {
+	return &t.tabs[index].close
+}
+
+// Hovered reports whether a pointer is over the tab at index.
+func (t *Tabs) Hovered(index int) bool {
+	return index < len(t.tabs) && t.tabs[index].click.Hovered()
+}
+
+// Focused reports whether the strip has the keyboard focus.
+func (t *Tabs) Focused() bool {
+	return t.focused
+}
+
+// Update the state of the tabs and report the next user interaction, if
+// any. The Ctrl+Tab and Ctrl+Shift+Tab shortcuts select the next and
+// previous tab regardless of the keyboard focus.
+func (t *Tabs) Update(gtx layout.Context) (TabEvent, bool) {
+	n := len(t.tabs)
+	if n == 0 {
+		return TabEvent{}, false
+	}
+	for i := range t.tabs {
+		tab := &t.tabs[i]
+		if tab.close.Clicked(gtx) {
+			return TabEvent{Kind: TabClosed, Index: i}, true
+		}
+		for {
+			e, ok := tab.click.Update(gtx.Source)
+			if !ok {
+				break
+			}
+			switch e.Kind {
+			case gesture.KindPress:
+				if e.Source == pointer.Mouse {
+					gtx.Execute(key.FocusCmd{Tag: t})
+				}
+			case gesture.KindClick:
+				if t.selectTab(gtx, i) {
+					return TabEvent{Kind: TabSelected, Index: i}, true
+				}
+			}
+		}
+	}
+	filters := []event.Filter{
+		key.FocusFilter{Target: t},
+		key.Filter{Focus: t, Name: key.NameLeftArrow},
+		key.Filter{Focus: t, Name: key.NameRightArrow},
+		key.Filter{Focus: t, Name: key.NameHome},
+		key.Filter{Focus: t, Name: key.NameEnd},
+		key.Filter{Name: key.NameTab, Required: key.ModCtrl, Optional: key.ModShift},
+	}
+	if t.Closable {
+		filters = append(filters, key.Filter{Focus: t, Name: key.NameDeleteForward})
+	}
+	for {
+		ev, ok := gtx.Event(filters...)
+		if !ok {
+			break
+		}
+		switch e := ev.(type) {
+		case key.FocusEvent:
+			t.focused = e.Focus
+		case key.Event:
+			if e.State != key.Press {
+				break
+			}
+			i := t.Selected
+			switch e.Name {
+			case key.NameLeftArrow:
+				i--
+			case key.NameRightArrow:
+				i++
+			case key.NameHome:
+				i = 0
+			case key.NameEnd:
+				i = n - 1
+			case key.NameTab:
+				if e.Modifiers.Contain(key.ModShift) {
+					i = (i - 1 + n) % n
+				} else {
+					i = (i + 1) % n
+				}
+			case key.NameDeleteForward:
+				return TabEvent{Kind: TabClosed, Index: t.Selected}, true
+			}
+			i = max(0, min(n-1, i))
+			if t.selectTab(gtx, i) {
+				t.scroll = true
+				return TabEvent{Kind: TabSelected, Index: i}, true
+			}
+		}
+	}
+	return TabEvent{}, false
+}
+
+// selectTab makes the tab at index i the selected tab and reports whether
+// the selection changed.
+func (t *Tabs) selectTab(gtx layout.Context, i int) bool {
+	if i == t.Selected {
+		return false
+	}
+	t.anim.from = t.Selected
+	t.anim.start = gtx.Now
+	t.Selected = i
+	return true
+}
+
+// Indicator returns the area of the selection indicator, in the coordinates
+// of the strip. The indicator moves from the previously selected tab to
+// the selected tab after the selection changes. Indicator reports false if
+// the selected tab is not visible. It is only valid after Layout.
+func (t *Tabs) Indicator(gtx layout.Context) (image.Rectangle, bool) {
+	bounds := func(i int) (image.Rectangle, bool) {
+		if i < 0 || i >= len(t.tabs) || !t.tabs[i].visible {
+			return image.Rectangle{}, false
+		}
+		tab := t.tabs[i]
+		return image.Rect(tab.x, 0, tab.x+tab.width, 0), true
+	}
+	to, ok := bounds(t.Selected)
+	if !ok {
+		return image.Rectangle{}, false
+	}
+	elapsed := gtx.Now.Sub(t.anim.start)
+	from, ok := bounds(t.anim.from)
+	if !ok || elapsed >= tabAnimation || elapsed < 0 {
+		return to, true
+	}
+	gtx.Execute(op.InvalidateCmd{})
+	// Ease out the movement.
+	p := float32(elapsed) / float32(tabAnimation)
+	p = 1 - (1-p)*(1-p)
+	lerp := func(a, b int) int {
+		return a + int(float32(b-a)*p+.5)
+	}
+	return image.Rect(lerp(from.Min.X, to.Min.X), 0, lerp(from.Max.X, to.Max.X), 0), true
+}
+
+// Layout n tabs in a horizontal, scrollable strip, where each tab is
+// defined by the callback w.
+func (t *Tabs) Layout(gtx layout.Context, n int, w layout.ListElement) layout.Dimensions {
+	if len(t.tabs) != n {
+		tabs := make([]tabState, n)
+		copy(tabs, t.tabs)
+		t.tabs = tabs
+	}
+	t.Update(gtx)
+	if n > 0 {
+		t.Selected = max(0, min(n-1, t.Selected))
+	}
+	if t.scroll {
+		t.scroll = false
+		pos := t.List.Position
+		if last := pos.First + pos.Count - 1; t.Selected < pos.First || t.Selected > last ||
+			(t.Selected == last && pos.OffsetLast < 0) || (t.Selected == pos.First && pos.Offset > 0) {
+			t.List.ScrollTo(t.Selected)
+		}
+	}
+	for i := range t.tabs {
+		t.tabs[i].visible = false
+	}
+	t.List.Axis = layout.Horizontal
+	macro := op.Record(gtx.Ops)
+	dims := t.List.Layout(gtx, n, func(gtx layout.Context, index int) layout.Dimensions {
+		tab := &t.tabs[index]
+		macro := op.Record(gtx.Ops)
+		dims := w(gtx, index)
+		call := macro.Stop()
+		tab.width = dims.Size.X
+		defer clip.Rect{Max: dims.Size}.Push(gtx.Ops).Pop()
+		semantic.Tab.Add(gtx.Ops)
+		semantic.SelectedOp(index == t.Selected).Add(gtx.Ops)
+		semantic.EnabledOp(gtx.Enabled()).Add(gtx.Ops)
+		tab.click.Add(gtx.Ops)
+		call.Add(gtx.Ops)
+		return dims
+	})
+	call := macro.Stop()
+	// Locate the visible tabs.
+	pos := t.List.Position
+	x := -pos.Offset
+	for i := pos.First; i < pos.First+pos.Count && i < n; i++ {
+		t.tabs[i].x = x
+		t.tabs[i].visible = true
+		x += t.tabs[i].width
+	}
+	defer clip.Rect{Max: dims.Size}.Push(gtx.Ops).Pop()
+	semantic.TabList.Add(gtx.Ops)
+	event.Op(gtx.Ops, t)
+	call.Add(gtx.Ops)
+	return dims
+}
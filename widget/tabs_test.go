@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget_test
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"gioui.org/f32"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/io/semantic"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/widget"
+)
+
+func TestTabs(t *testing.T) {
+	var (
+		r    input.Router
+		tabs widget.Tabs
+	)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Source:      r.Source(),
+		Constraints: layout.Exact(image.Pt(200, 40)),
+	}
+	frame := func() {
+		gtx.Reset()
+		tabs.Layout(gtx, 5, func(gtx layout.Context, index int) layout.Dimensions {
+			return layout.Dimensions{Size: image.Pt(60, 40)}
+		})
+		r.Frame(gtx.Ops)
+	}
+	frame()
+	r.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Pt(70, 10)},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: f32.Pt(70, 10)},
+	)
+	if e, ok := tabs.Update(gtx); !ok || e != (widget.TabEvent{Kind: widget.TabSelected, Index: 1}) {
+		t.Errorf("click reported %v, %v; want tab 1 selected", e, ok)
+	}
+	frame()
+	if !gtx.Focused(&tabs) {
+		t.Error("click didn't focus the tabs")
+	}
+	r.Queue(key.Event{Name: key.NameRightArrow, State: key.Press})
+	if e, ok := tabs.Update(gtx); !ok || e.Index != 2 || tabs.Selected != 2 {
+		t.Errorf("right arrow reported %v, %v; want tab 2 selected", e, ok)
+	}
+	r.Queue(key.Event{Name: key.NameEnd, State: key.Press})
+	tabs.Update(gtx)
+	frame()
+	if tabs.Selected != 4 {
+		t.Errorf("end selected %d, want 4", tabs.Selected)
+	}
+	if pos := tabs.List.Position; pos.First+pos.Count != 5 {
+		t.Errorf("last tab not scrolled into view: %+v", pos)
+	}
+	r.Queue(key.Event{Name: key.NameDeleteForward, State: key.Press})
+	if e, ok := tabs.Update(gtx); ok {
+		t.Errorf("delete reported %v for tabs that are not closable", e)
+	}
+	tabs.Closable = true
+	frame()
+	r.Queue(key.Event{Name: key.NameDeleteForward, State: key.Press})
+	if e, ok := tabs.Update(gtx); !ok || e != (widget.TabEvent{Kind: widget.TabClosed, Index: 4}) {
+		t.Errorf("delete reported %v, %v; want tab 4 closed", e, ok)
+	}
+	// Ctrl+Tab applies regardless of focus.
+	gtx.Execute(key.FocusCmd{})
+	frame()
+	r.Queue(key.Event{Name: key.NameTab, Modifiers: key.ModCtrl, State: key.Press})
+	if e, ok := tabs.Update(gtx); !ok || e.Index != 0 {
+		t.Errorf("ctrl+tab reported %v, %v; want tab 0 selected", e, ok)
+	}
+	r.Queue(key.Event{Name: key.NameTab, Modifiers: key.ModCtrl | key.ModShift, State: key.Press})
+	if e, ok := tabs.Update(gtx); !ok || e.Index != 4 {
+		t.Errorf("ctrl+shift+tab reported %v, %v; want tab 4 selected", e, ok)
+	}
+}
+
+func TestTabsIndicator(t *testing.T) {
+	var (
+		r    input.Router
+		tabs widget.Tabs
+	)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Source:      r.Source(),
+		Constraints: layout.Exact(image.Pt(400, 40)),
+		Now:         time.Unix(0, 0),
+	}
+	frame := func() {
+		gtx.Reset()
+		tabs.Layout(gtx, 3, func(gtx layout.Context, index int) layout.Dimensions {
+			return layout.Dimensions{Size: image.Pt(100, 40)}
+		})
+		r.Frame(gtx.Ops)
+	}
+	frame()
+	if got, ok := tabs.Indicator(gtx); !ok || got.Min.X != 0 || got.Max.X != 100 {
+		t.Errorf("indicator at %v, %v; want (0, 100)", got, ok)
+	}
+	gtx.Execute(key.FocusCmd{Tag: &tabs})
+	frame()
+	r.Queue(key.Event{Name: key.NameEnd, State: key.Press})
+	frame()
+	if got, ok := tabs.Indicator(gtx); !ok || got.Min.X != 0 || got.Max.X != 100 {
+		t.Errorf("indicator at %v, %v at the start of the animation; want (0, 100)", got, ok)
+	}
+	gtx.Now = gtx.Now.Add(50 * time.Millisecond)
+	if got, ok := tabs.Indicator(gtx); !ok || got.Min.X <= 0 || got.Min.X >= 200 {
+		t.Errorf("indicator at %v, %v during the animation; want between tabs", got, ok)
+	}
+	gtx.Now = gtx.Now.Add(time.Second)
+	if got, ok := tabs.Indicator(gtx); !ok || got.Min.X != 200 || got.Max.X != 300 {
+		t.Errorf("indicator at %v, %v after the animation; want (200, 300)", got, ok)
+	}
+
+	tree := r.AppendSemantics(nil)
+	var selected []bool
+	for _, n := range tree {
+		if n.Desc.Class == semantic.Tab {
+			selected = append(selected, n.Desc.Selected)
+		}
+	}
+	if len(selected) != 3 || selected[0] || selected[1] || !selected[2] {
+		t.Errorf("semantic tab selection %v, want [false false true]", selected)
+	}
+}
@@ -2,6 +2,7 @@ package widget
 
 import (
 	"bufio"
+	"bytes"
 	"image"
 	"io"
 	"math"
@@ -21,11 +22,15 @@ import (
 	"golang.org/x/image/math/fixed"
 )
 
-// textSource provides text data for use in widgets. If the underlying data type
+// TextSource provides text data for use in widgets. If the underlying data type
 // can fail due to I/O errors, it is the responsibility of that type to provide
 // its own mechanism to surface and handle those errors. They will not always
 // be returned by widgets using these functions.
-type textSource interface {
+//
+// Widgets read the data in small pieces at arbitrary offsets, so sources of
+// large texts should implement ReadAt efficiently, such as with a rope or a
+// piece table.
+type TextSource interface {
 	io.ReaderAt
 	// Size returns the total length of the data in bytes.
 	Size() int64
@@ -61,6 +66,15 @@ type textView struct {
 	Truncator string
 	// WrapPolicy configures how displayed text will be broken into lines.
 	WrapPolicy text.WrapPolicy
+	// Decoration is the set of lines drawn along the text.
+	Decoration text.Decoration
+	// LetterSpacing is added between the characters of the text.
+	LetterSpacing unit.Sp
+	// WordSpacing is added to the spaces between words.
+	WordSpacing unit.Sp
+	// TabWidth is the interval between tab stops. If zero, tabs are as
+	// wide as their glyph.
+	TabWidth unit.Sp
 	// DisableSpaceTrim configures whether trailing whitespace on a line will have its
 	// width zeroed. Set to true for editors, but false for non-editable text.
 	DisableSpaceTrim bool
@@ -69,10 +83,14 @@ type textView struct {
 	// are accessed by Len, Text, and SetText.
 	Mask rune
 
-	params     text.Parameters
-	shaper     *text.Shaper
+	params text.Parameters
+	shaper *text.Shaper
+	// styles are the sorted, non-overlapping styled ranges of the text.
+	styles []StyledRange
+	// spans are the paint styles of the spans of styled text.
+	spans      []SpanStyle
 	seekCursor int64
-	rr         textSource
+	rr         TextSource
 	maskReader maskReader
 	// graphemes tracks the indices of grapheme cluster boundaries within rr.
 	graphemes []int
@@ -81,29 +99,50 @@ type textView struct {
 	lastMask        rune
 	viewSize        image.Point
 	valid           bool
-	regions         []Region
-	dims            layout.Dimensions
+	// lazy is set if only the paragraphs around the viewport are laid out,
+	// as tracked by paras.
+	lazy    bool
+	paras   paragraphIndex
+	regions []Region
+	dims    layout.Dimensions
 
 	// offIndex is an index of rune index to byte offsets.
 	offIndex []offEntry
 
 	index glyphIndex
 
-	caret struct {
-		// xoff is the offset to the current position when moving between lines.
-		xoff fixed.Int26_6
-		// start is the current caret position in runes, and also the start position of
-		// selected text. end is the end position of selected text. If start
-		// == end, then there's no selection. Note that it's possible (and
-		// common) that the caret (start) is after the end, e.g. after
-		// Shift-DownArrow.
-		start int
-		end   int
-	}
+	caret textCaret
+	// carets are the additional carets. Methods that move or read the caret
+	// affect only the caret; eachCaret applies them to all carets.
+	carets []textCaret
 
 	scrollOff image.Point
 }
 
+// textCaret is a caret and its selection.
+type textCaret struct {
+	// xoff is the offset to the current position when moving between lines.
+	xoff fixed.Int26_6
+	// start is the current caret position in runes, and also the start position of
+	// selected text. end is the end position of selected text. If start
+	// == end, then there's no selection. Note that it's possible (and
+	// common) that the caret (start) is after the end, e.g. after
+	// Shift-DownArrow.
+	start int
+	end   int
+}
+
+// overlaps reports whether the selections of c and o overlap, or whether
+// one of them is a caret within or next to the other.
+func (c textCaret) overlaps(o textCaret) bool {
+	cs, ce := min(c.start, c.end), max(c.start, c.end)
+	os, oe := min(o.start, o.end), max(o.start, o.end)
+	if cs == ce || os == oe {
+		return cs <= oe && os <= ce
+	}
+	return cs < oe && os < ce
+}
+
 func (e *textView) Changed() bool {
 	return e.rr.Changed()
 }
@@ -122,8 +161,9 @@ func (e *textView) FullDimensions() layout.Dimensions {
 
 // SetSource initializes the underlying data source for the Text. This
 // must be done before invoking any other methods on Text.
-func (e *textView) SetSource(source textSource) {
+func (e *textView) SetSource(source TextSource) {
 	e.rr = source
+	e.paras.scanned = false
 	e.invalidate()
 	e.seekCursor = 0
 }
@@ -164,17 +204,20 @@ func (e *textView) makeValid() {
 
 func (e *textView) closestToRune(runeIdx int) combinedPos {
 	e.makeValid()
+	e.showRune(runeIdx)
 	pos, _ := e.index.closestToRune(runeIdx)
 	return pos
 }
 
 func (e *textView) closestToLineCol(line, col int) combinedPos {
 	e.makeValid()
+	e.showLine(line)
 	return e.index.closestToLineCol(screenPos{line: line, col: col})
 }
 
 func (e *textView) closestToXY(x fixed.Int26_6, y int) combinedPos {
 	e.makeValid()
+	e.showY(y)
 	return e.index.closestToXY(x, y)
 }
 
@@ -292,6 +335,18 @@ func (e *textView) Layout(gtx layout.Context, lt *text.Shaper, font font.Font, s
 		e.params.DisableSpaceTrim = e.DisableSpaceTrim
 		e.invalidate()
 	}
+	if ls := fixed.I(gtx.Sp(e.LetterSpacing)); ls != e.params.LetterSpacing {
+		e.params.LetterSpacing = ls
+		e.invalidate()
+	}
+	if ws := fixed.I(gtx.Sp(e.WordSpacing)); ws != e.params.WordSpacing {
+		e.params.WordSpacing = ws
+		e.invalidate()
+	}
+	if tw := fixed.I(gtx.Sp(e.TabWidth)); tw != e.params.TabWidth {
+		e.params.TabWidth = tw
+		e.invalidate()
+	}
 
 	e.makeValid()
 
@@ -305,29 +360,39 @@ func (e *textView) Layout(gtx layout.Context, lt *text.Shaper, font font.Font, s
 // PaintSelection clips and paints the visible text selection rectangles using
 // the provided material to fill the rectangles.
 func (e *textView) PaintSelection(gtx layout.Context, material op.CallOp) {
+	e.showViewport()
 	localViewport := image.Rectangle{Max: e.viewSize}
 	docViewport := image.Rectangle{Max: e.viewSize}.Add(e.scrollOff)
 	defer clip.Rect(localViewport).Push(gtx.Ops).Pop()
-	e.regions = e.index.locate(docViewport, e.caret.start, e.caret.end, e.regions)
-	for _, region := range e.regions {
-		area := clip.Rect(region.Bounds).Push(gtx.Ops)
-		material.Add(gtx.Ops)
-		paint.PaintOp{}.Add(gtx.Ops)
-		area.Pop()
+	for i := -1; i < len(e.carets); i++ {
+		c := e.caret
+		if i >= 0 {
+			c = e.carets[i]
+		}
+		e.regions = e.index.locate(docViewport, c.start, c.end, e.regions)
+		for _, region := range e.regions {
+			area := clip.Rect(region.Bounds).Push(gtx.Ops)
+			material.Add(gtx.Ops)
+			paint.PaintOp{}.Add(gtx.Ops)
+			area.Pop()
+		}
 	}
 }
 
 // PaintText clips and paints the visible text glyph outlines using the provided
 // material to fill the glyphs.
 func (e *textView) PaintText(gtx layout.Context, material op.CallOp) {
+	e.showViewport()
 	m := op.Record(gtx.Ops)
 	viewport := image.Rectangle{
 		Min: e.scrollOff,
 		Max: e.viewSize.Add(e.scrollOff),
 	}
 	it := textIterator{
-		viewport: viewport,
-		material: material,
+		viewport:   viewport,
+		material:   material,
+		spans:      e.spans,
+		decoration: e.Decoration,
 	}
 
 	startGlyph := 0
@@ -366,9 +431,22 @@ func (e *textView) caretWidth(gtx layout.Context) int {
 // PaintCaret clips and paints the caret rectangle, adding material immediately
 // before painting to set the appropriate paint material.
 func (e *textView) PaintCaret(gtx layout.Context, material op.CallOp) {
-	carWidth2 := e.caretWidth(gtx)
 	caretPos, carAsc, carDesc := e.CaretInfo()
+	e.paintCaret(gtx, material, caretPos, carAsc, carDesc)
+	for _, c := range e.carets {
+		// Skip the carets outside the laid out text.
+		pos, _ := e.index.closestToRune(c.start)
+		if pos.runes != c.start {
+			continue
+		}
+		p := image.Pt(pos.x.Round(), pos.y).Sub(e.scrollOff)
+		e.paintCaret(gtx, material, p, pos.ascent.Ceil(), pos.descent.Ceil())
+	}
+}
 
+// paintCaret paints a caret at caretPos with the given ascent and descent.
+func (e *textView) paintCaret(gtx layout.Context, material op.CallOp, caretPos image.Point, carAsc, carDesc int) {
+	carWidth2 := e.caretWidth(gtx)
 	carRect := image.Rectangle{
 		Min: caretPos.Sub(image.Pt(carWidth2, carAsc)),
 		Max: caretPos.Add(image.Pt(carWidth2, carDesc)),
@@ -405,6 +483,9 @@ func (e *textView) ByteOffset(runeOffset int) int64 {
 // Len is the length of the editor contents, in runes.
 func (e *textView) Len() int {
 	e.makeValid()
+	if e.lazy {
+		return e.paras.runes
+	}
 	return e.closestToRune(math.MaxInt).runes
 }
 
@@ -483,6 +564,11 @@ func (e *textView) Truncated() bool {
 }
 
 func (e *textView) layoutText(lt *text.Shaper) {
+	e.lazy = lt != nil && !e.SingleLine && e.MaxLines == 0 && e.rr.Size() >= lazyLayoutSize
+	if e.lazy {
+		e.layoutWindow(-1)
+		return
+	}
 	e.Seek(0, io.SeekStart)
 	var r io.Reader = e
 	if e.Mask != 0 {
@@ -492,7 +578,12 @@ func (e *textView) layoutText(lt *text.Shaper) {
 	e.index.reset()
 	it := textIterator{viewport: image.Rectangle{Max: image.Point{X: math.MaxInt, Y: math.MaxInt}}}
 	if lt != nil {
-		lt.Layout(e.params, r)
+		e.spans = e.spans[:0]
+		if len(e.styles) > 0 {
+			lt.LayoutSpans(e.params, e.styledSpans(r, 0))
+		} else {
+			lt.Layout(e.params, r)
+		}
 		for {
 			g, ok := lt.NextGlyph()
 			if !it.processGlyph(g, ok) {
@@ -522,6 +613,51 @@ func (e *textView) layoutText(lt *text.Shaper) {
 	e.dims = dims
 }
 
+// styledSpans reads the text from r, which starts at rune offset start, and
+// splits it into the spans of its styles. The paint styles of the spans are
+// stored in e.spans.
+func (e *textView) styledSpans(r io.Reader, start int) []text.Span {
+	b, _ := io.ReadAll(r)
+	txt := string(b)
+	var spans []text.Span
+	// add appends the span of the runes up to the rune offset end with
+	// style s.
+	pos, off := 0, 0
+	add := func(end int, s TextStyle) {
+		start := off
+		for ; pos < end && off < len(txt); pos++ {
+			_, n := utf8.DecodeRuneInString(txt[off:])
+			off += n
+		}
+		if start == off {
+			return
+		}
+		f := e.params.Font
+		if s.Weight != 0 {
+			f.Weight = s.Weight
+		}
+		if s.Style != 0 {
+			f.Style = s.Style
+		}
+		spans = append(spans, text.Span{Text: txt[start:off], Font: f})
+		e.spans = append(e.spans, SpanStyle{
+			Material:   s.Material,
+			Background: s.Background,
+			Decoration: s.Decoration,
+		})
+	}
+	for _, st := range e.styles {
+		add(st.Start-start, TextStyle{})
+		add(st.End-start, st.Style)
+	}
+	add(math.MaxInt, TextStyle{})
+	if len(spans) == 0 {
+		spans = append(spans, text.Span{Font: e.params.Font})
+		e.spans = append(e.spans, SpanStyle{})
+	}
+	return spans
+}
+
 // CaretPos returns the line & column numbers of the caret.
 func (e *textView) CaretPos() (line, col int) {
 	pos := e.closestToRune(e.caret.start)
@@ -557,6 +693,13 @@ func (e *textView) indexRune(r int) offEntry {
 func (e *textView) runeOffset(r int) int {
 	const runesPerIndexEntry = 50
 	entry := e.indexRune(r)
+	if e.lazy {
+		// Start from the paragraph of r if it is closer.
+		_, off := e.paras.find(func(o paragraphOffset) bool { return o.runes > r })
+		if off.runes > entry.runes {
+			entry = offEntry{runes: off.runes, bytes: int(off.bytes)}
+		}
+	}
 	lastEntry := e.offIndex[len(e.offIndex)-1].runes
 	for entry.runes < r {
 		if entry.runes > lastEntry && entry.runes%runesPerIndexEntry == runesPerIndexEntry-1 {
@@ -569,6 +712,46 @@ func (e *textView) runeOffset(r int) int {
 	return entry.bytes
 }
 
+// indexRunes returns the rune offset of the first occurrence of sel at or
+// after the rune offset r, or -1. The text is searched in chunks, so large
+// texts aren't copied.
+func (e *textView) indexRunes(sel []byte, r int) int {
+	off := e.ByteOffset(r)
+	size := e.rr.Size()
+	buf := make([]byte, max(32*1024, 2*len(sel)))
+	// n is the number of bytes in buf, which starts at off.
+	n := 0
+	for {
+		m, err := e.rr.ReadAt(buf[n:], off+int64(n))
+		n += m
+		if i := bytes.Index(buf[:n], sel); i != -1 {
+			return r + utf8.RuneCount(buf[:i])
+		}
+		if err != nil || off+int64(n) >= size {
+			return -1
+		}
+		// Keep the bytes that may start an occurrence, from a rune
+		// boundary.
+		cut := max(n-len(sel)+1, 0)
+		for cut > 0 && !utf8.RuneStart(buf[cut]) {
+			cut--
+		}
+		r += utf8.RuneCount(buf[:cut])
+		off += int64(cut)
+		n = copy(buf, buf[cut:n])
+	}
+}
+
+// SetStyles sets the styles of the text. The ranges must be sorted and
+// must not overlap.
+func (e *textView) SetStyles(styles []StyledRange) {
+	if slices.Equal(e.styles, styles) {
+		return
+	}
+	e.styles = append(e.styles[:0], styles...)
+	e.invalidate()
+}
+
 func (e *textView) invalidate() {
 	e.offIndex = e.offIndex[:0]
 	e.valid = false
@@ -587,6 +770,10 @@ func (e *textView) Replace(start, end int, s string) int {
 	sc := utf8.RuneCountInString(s)
 	newEnd := startPos.runes + sc
 
+	if e.paras.scanned {
+		endOff := e.runeOffset(endPos.runes)
+		e.paras.replace(startPos.runes, endPos.runes, int64(startOff), int64(endOff), s)
+	}
 	e.rr.ReplaceRunes(int64(startOff), int64(replaceSize), s)
 	adjust := func(pos int) int {
 		switch {
@@ -600,6 +787,11 @@ func (e *textView) Replace(start, end int, s string) int {
 	}
 	e.caret.start = adjust(e.caret.start)
 	e.caret.end = adjust(e.caret.end)
+	for i := range e.carets {
+		c := &e.carets[i]
+		c.start = adjust(c.start)
+		c.end = adjust(c.end)
+	}
 	e.invalidate()
 	return sc
 }
@@ -619,6 +811,8 @@ func (e *textView) MovePages(pages int, selAct selectionAction) {
 // moveByGraphemes returns the rune index resulting from moving the
 // specified number of grapheme clusters from startRuneidx.
 func (e *textView) moveByGraphemes(startRuneidx, graphemes int) int {
+	// Segment the paragraphs around startRuneidx.
+	e.closestToRune(startRuneidx)
 	if len(e.graphemes) == 0 {
 		return startRuneidx
 	}
@@ -793,7 +987,7 @@ func (e *textView) SelectedText(buf []byte) []byte {
 	buf = buf[:end-start]
 	n, _ := e.rr.ReadAt(buf, int64(start))
 	// There is no way to reasonably handle a read error here. We rely upon
-	// implementations of textSource to provide other ways to signal errors
+	// implementations of TextSource to provide other ways to signal errors
 	// if the user cares about that, and here we use whatever data we were
 	// able to read.
 	return buf[:n]
@@ -811,6 +1005,97 @@ func (e *textView) ClearSelection() {
 	e.caret.end = e.caret.start
 }
 
+// Carets returns the number of carets.
+func (e *textView) Carets() int {
+	return 1 + len(e.carets)
+}
+
+// AddCaret adds a caret at the position and selection of the caret, for
+// the caret to move elsewhere.
+func (e *textView) AddCaret() {
+	e.carets = append(e.carets, e.caret)
+}
+
+// ClearCarets removes the additional carets.
+func (e *textView) ClearCarets() {
+	e.carets = e.carets[:0]
+}
+
+// eachCaret calls f with each caret in turn as the caret, from the last
+// caret in the text to the first. Carets that end up overlapping are
+// merged.
+func (e *textView) eachCaret(f func()) {
+	if len(e.carets) == 0 {
+		f()
+		return
+	}
+	e.carets = append(e.carets, e.caret)
+	primary := len(e.carets) - 1
+	order := make([]int, len(e.carets))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		ca, cb := e.carets[a], e.carets[b]
+		return min(cb.start, cb.end) - min(ca.start, ca.end)
+	})
+	for _, i := range order {
+		e.caret, e.carets[i] = e.carets[i], e.caret
+		f()
+		e.caret, e.carets[i] = e.carets[i], e.caret
+	}
+	e.caret = e.carets[primary]
+	e.carets = slices.Delete(e.carets, primary, primary+1)
+	e.mergeCarets()
+}
+
+// mergeCarets removes the additional carets that overlap the caret or
+// another caret.
+func (e *textView) mergeCarets() {
+	kept := e.carets[:0]
+	for _, c := range e.carets {
+		if c.overlaps(e.caret) || slices.ContainsFunc(kept, c.overlaps) {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	e.carets = kept
+}
+
+// Selections returns the selections of all carets in text order.
+func (e *textView) Selections() []textCaret {
+	sels := append([]textCaret{e.caret}, e.carets...)
+	slices.SortFunc(sels, func(a, b textCaret) int {
+		return min(a.start, a.end) - min(b.start, b.end)
+	})
+	return sels
+}
+
+// SelectColumn selects the rectangle of text between the document
+// coordinates a and b, with a caret for every line. The caret is placed on
+// the line of b.
+func (e *textView) SelectColumn(a, b image.Point) {
+	first := e.closestToXY(fixed.I(a.X), a.Y).lineCol.line
+	last := e.closestToXY(fixed.I(b.X), b.Y).lineCol.line
+	step := 1
+	if last < first {
+		step = -1
+	}
+	e.carets = e.carets[:0]
+	for line := first; ; line += step {
+		y := e.closestToLineCol(line, 0).y
+		start := e.closestToXYGraphemes(fixed.I(a.X), y)
+		end := e.closestToXYGraphemes(fixed.I(b.X), y)
+		if line != first {
+			e.AddCaret()
+		}
+		e.caret = textCaret{start: end.runes, end: start.runes}
+		if line == last {
+			break
+		}
+	}
+}
+
 // WriteTo implements io.WriterTo.
 func (e *textView) WriteTo(w io.Writer) (int64, error) {
 	e.Seek(0, io.SeekStart)
@@ -844,9 +1129,26 @@ func (e *textView) ReadAt(p []byte, offset int64) (int, error) {
 
 // Regions returns visible regions covering the rune range [start,end).
 func (e *textView) Regions(start, end int, regions []Region) []Region {
+	e.showViewport()
 	viewport := image.Rectangle{
 		Min: e.scrollOff,
 		Max: e.viewSize.Add(e.scrollOff),
 	}
 	return e.index.locate(viewport, start, end, regions)
 }
+
+// Lines returns the visible lines of text. If the lines parameter is
+// non-nil, Lines will use it to return results instead of allocating,
+// provided that there is enough capacity.
+func (e *textView) Lines(lines []LineInfo) []LineInfo {
+	e.showViewport()
+	viewport := image.Rectangle{
+		Min: e.scrollOff,
+		Max: e.viewSize.Add(e.scrollOff),
+	}
+	line, runes := 0, 0
+	if e.lazy {
+		line, runes = e.paras.start, e.paras.first.runes
+	}
+	return e.index.logicalLines(viewport, line, runes, lines)
+}
@@ -0,0 +1,263 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"fmt"
+	"image"
+
+	"gioui.org/gesture"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/io/semantic"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+)
+
+// TimePicker is for selecting a time of day. The hour, minute and, for
+// locales using a 12-hour clock, the period fields are adjusted with the
+// arrow keys or by typing digits.
+type TimePicker struct {
+	// Hour is the hour of the day, in the [0; 23] range.
+	Hour int
+	// Minute is in the [0; 59] range.
+	Minute int
+	// MinuteStep is the amount the arrow keys change Minute. Zero means 1.
+	MinuteStep int
+
+	field   TimeField
+	focused bool
+	h12     bool
+	// typed is the number of digits typed into the focused field.
+	typed  int
+	clicks [3]gesture.Click
+}
+
+// TimeField identifies a field of a TimePicker.
+type TimeField uint8
+
+const (
+	TimeHour TimeField = iota
+	TimeMinute
+	// TimePeriod is the AM/PM field of 12-hour clocks.
+	TimePeriod
+)
+
+// TimeCell describes a field of a TimePicker for display.
+type TimeCell struct {
+	Field TimeField
+	// Text is the value of the field.
+	Text string
+	// Focused is set for the field receiving the keyboard input.
+	Focused bool
+}
+
+// Focused reports whether the picker has the keyboard focus.
+func (p *TimePicker) Focused() bool {
+	return p.focused
+}
+
+// Update the state of the picker and report whether the time was changed
+// by the user.
+func (p *TimePicker) Update(gtx layout.Context) bool {
+	p.h12 = Uses12Hour(gtx.Locale)
+	changed := false
+	for i := range p.clicks {
+		for {
+			e, ok := p.clicks[i].Update(gtx.Source)
+			if !ok {
+				break
+			}
+			if e.Kind == gesture.KindPress {
+				p.field = TimeField(i)
+				p.typed = 0
+				if e.Source == pointer.Mouse {
+					gtx.Execute(key.FocusCmd{Tag: p})
+				}
+			}
+		}
+	}
+	filters := []event.Filter{
+		key.FocusFilter{Target: p},
+		key.Filter{Focus: p, Name: key.NameLeftArrow},
+		key.Filter{Focus: p, Name: key.NameRightArrow},
+		key.Filter{Focus: p, Name: key.NameUpArrow},
+		key.Filter{Focus: p, Name: key.NameDownArrow},
+		key.Filter{Focus: p, Name: "A"},
+		key.Filter{Focus: p, Name: "P"},
+	}
+	for d := '0'; d <= '9'; d++ {
+		filters = append(filters, key.Filter{Focus: p, Name: key.Name(d)})
+	}
+	for {
+		ev, ok := gtx.Event(filters...)
+		if !ok {
+			break
+		}
+		switch e := ev.(type) {
+		case key.FocusEvent:
+			p.focused = e.Focus
+			p.typed = 0
+		case key.Event:
+			if e.State != key.Press {
+				break
+			}
+			last := TimeMinute
+			if p.h12 {
+				last = TimePeriod
+			}
+			switch n := e.Name; n {
+			case key.NameLeftArrow:
+				if p.field > TimeHour {
+					p.field--
+				}
+				p.typed = 0
+			case key.NameRightArrow:
+				if p.field < last {
+					p.field++
+				}
+				p.typed = 0
+			case key.NameUpArrow:
+				p.step(+1)
+				changed = true
+			case key.NameDownArrow:
+				p.step(-1)
+				changed = true
+			case "A":
+				if p.h12 {
+					p.Hour %= 12
+					changed = true
+				}
+			case "P":
+				if p.h12 {
+					p.Hour = p.Hour%12 + 12
+					changed = true
+				}
+			default:
+				changed = p.typeDigit(int(n[0]-'0')) || changed
+				if p.field > last {
+					p.field = last
+				}
+			}
+		}
+	}
+	if !p.h12 && p.field == TimePeriod {
+		p.field = TimeMinute
+	}
+	return changed
+}
+
+// step changes the focused field by dir steps.
+func (p *TimePicker) step(dir int) {
+	p.typed = 0
+	switch p.field {
+	case TimeHour:
+		p.Hour = (p.Hour + dir + 24) % 24
+	case TimeMinute:
+		s := max(1, p.MinuteStep)
+		// Snap to the step before stepping.
+		m := p.Minute - p.Minute%s
+		if m == p.Minute || dir > 0 {
+			m += dir * s
+		}
+		p.Minute = (m + 60) % 60
+	case TimePeriod:
+		p.Hour = (p.Hour + 12) % 24
+	}
+}
+
+// typeDigit enters digit d into the focused field and reports whether the
+// time changed.
+func (p *TimePicker) typeDigit(d int) bool {
+	f := p.field
+	if f == TimePeriod {
+		return false
+	}
+	limit, v := 59, p.Minute
+	if f == TimeHour {
+		limit, v = 23, p.Hour
+		if p.h12 {
+			limit, v = 12, p.Hour%12
+		}
+	}
+	if p.typed > 0 && v*10+d <= limit {
+		v = v*10 + d
+		p.typed++
+	} else {
+		v = d
+		p.typed = 1
+	}
+	// Move to the next field when no more digits fit.
+	if p.typed == 2 || v*10 > limit {
+		p.typed = 0
+		p.field++
+	}
+	switch {
+	case f == TimeMinute:
+		p.Minute = v
+	case p.h12:
+		// Keep the period of the day.
+		p.Hour = v%12 + p.Hour/12*12
+	default:
+		p.Hour = v
+	}
+	return true
+}
+
+// cell describes field f.
+func (p *TimePicker) cell(f TimeField) TimeCell {
+	c := TimeCell{Field: f, Focused: p.focused && f == p.field}
+	switch f {
+	case TimeHour:
+		h := p.Hour
+		if p.h12 {
+			h %= 12
+			if h == 0 {
+				h = 12
+			}
+		}
+		c.Text = fmt.Sprintf("%02d", h)
+	case TimeMinute:
+		c.Text = fmt.Sprintf("%02d", p.Minute)
+	case TimePeriod:
+		c.Text = "AM"
+		if p.Hour >= 12 {
+			c.Text = "PM"
+		}
+	}
+	return c
+}
+
+// Layout the fields of the picker in a row.
+func (p *TimePicker) Layout(gtx layout.Context, field func(gtx layout.Context, c TimeCell) layout.Dimensions) layout.Dimensions {
+	p.Update(gtx)
+	n := 2
+	if p.h12 {
+		n = 3
+	}
+	gtx.Constraints.Min = image.Point{}
+	macro := op.Record(gtx.Ops)
+	x, height := 0, 0
+	for i := range n {
+		rec := op.Record(gtx.Ops)
+		dims := field(gtx, p.cell(TimeField(i)))
+		call := rec.Stop()
+		off := op.Offset(image.Pt(x, 0)).Push(gtx.Ops)
+		cl := clip.Rect{Max: dims.Size}.Push(gtx.Ops)
+		p.clicks[i].Add(gtx.Ops)
+		call.Add(gtx.Ops)
+		cl.Pop()
+		off.Pop()
+		x += dims.Size.X
+		height = max(height, dims.Size.Y)
+	}
+	call := macro.Stop()
+	size := image.Pt(x, height)
+	defer clip.Rect{Max: size}.Push(gtx.Ops).Pop()
+	event.Op(gtx.Ops, p)
+	semantic.DescriptionOp(fmt.Sprintf("%02d:%02d", p.Hour, p.Minute)).Add(gtx.Ops)
+	call.Add(gtx.Ops)
+	return layout.Dimensions{Size: size}
+}